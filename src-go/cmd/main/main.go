@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"net"
@@ -16,6 +17,7 @@ import (
 
 	"github.com/Rayzorblade23/MightyPie-Revamped/pkg/processmonitor"
 	"github.com/Rayzorblade23/MightyPie-Revamped/src/adapters/buttonManagerAdapter"
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/adapters/httpApiAdapter"
 	"github.com/Rayzorblade23/MightyPie-Revamped/src/adapters/mouseInputAdapter"
 	"github.com/Rayzorblade23/MightyPie-Revamped/src/adapters/natsAdapter"
 	"github.com/Rayzorblade23/MightyPie-Revamped/src/adapters/pieButtonExecutionAdapter"
@@ -25,6 +27,7 @@ import (
 	"github.com/Rayzorblade23/MightyPie-Revamped/src/adapters/shortcutSetterAdapter"
 	"github.com/Rayzorblade23/MightyPie-Revamped/src/adapters/windowManagementAdapter"
 	"github.com/Rayzorblade23/MightyPie-Revamped/src/core"
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core/jsonUtils"
 	"github.com/Rayzorblade23/MightyPie-Revamped/src/core/logger"
 	"github.com/nats-io/nats-server/v2/server"
 )
@@ -43,7 +46,14 @@ var (
 		"shortcutSetter":    flag.Bool("shortcutSetter", false, "Run as shortcut setter worker"),
 		"piemenuConfigManager": flag.Bool("piemenuConfigManager", false, "Run as pie menu config manager worker"),
 		"windowManager":     flag.Bool("windowManager", false, "Run as window management worker"),
+		"httpApi":           flag.Bool("httpApi", false, "Run as local HTTP API worker"),
 	}
+
+	// One-shot tools
+	lintConfigFlag      = flag.Bool("lintConfig", false, "Lint the pie menu config (or the file given as argument) and exit")
+	simulateWindowsFlag = flag.Bool("simulateWindows", false, "Replay a window trace (second argument) against a pie menu config (first argument) and exit")
+	simulateOutFlag     = flag.String("simulateOut", "", "With -simulateWindows, write the button config after each step to this directory")
+	simulateFullFlag    = flag.Bool("simulateFull", false, "With -simulateWindows, print the full button config after each step instead of a diff")
 )
 
 func main() {
@@ -54,6 +64,13 @@ func main() {
 	log := logger.New("Main")
 	logger.ReplaceStdLog("Main")
 
+	if *lintConfigFlag {
+		os.Exit(runConfigLint(flag.Arg(0)))
+	}
+	if *simulateWindowsFlag {
+		os.Exit(runWindowSimulation(flag.Arg(0), flag.Arg(1)))
+	}
+
 	// Check if we should run as a specific worker
 	for workerName, flagValue := range workerFlags {
 		if *flagValue {
@@ -97,6 +114,10 @@ func main() {
 		"piemenuConfigManager",
 		"windowManager",
 	}
+	// The HTTP API is optional and only runs when a token is configured
+	if httpApiAdapter.Enabled() {
+		workers = append(workers, "httpApi")
+	}
 
 	var wg sync.WaitGroup
 	// Prepare all commands before starting them to avoid race conditions.
@@ -349,6 +370,97 @@ func cleanupAllProcesses(log *logger.Logger) {
 	}
 }
 
+// runConfigLint prints lint findings for the config at path (the AppData config if empty).
+// It returns the process exit code: 1 if any errors were found, 2 if the file could not be read.
+func runConfigLint(path string) int {
+	report, err := piemenuConfigManager.LintConfigFile(path, windowManagementAdapter.FetchExecutableApplicationMap())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to lint config: %v\n", err)
+		return 2
+	}
+	for _, f := range report.Findings {
+		location := f.Location
+		if location == "" {
+			location = "-"
+		}
+		fmt.Printf("%-7s %-20s %s\n", f.Severity, location, f.Message)
+	}
+	fmt.Printf("%d error(s), %d warning(s)\n", report.Errors, report.Warnings)
+	if report.Errors > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runWindowSimulation replays the window trace at tracePath against the buttons of the pie menu
+// config at configPath and prints what changed after each step.
+// It returns the process exit code: 2 if an input could not be read or an output written.
+func runWindowSimulation(configPath, tracePath string) int {
+	if configPath == "" || tracePath == "" {
+		fmt.Fprintln(os.Stderr, "Usage: -simulateWindows [-simulateOut dir] [-simulateFull] <piemenuConfig.json> <trace.jsonl>")
+		return 2
+	}
+	cfg, err := piemenuConfigManager.ReadConfigFromFile(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read config: %v\n", err)
+		return 2
+	}
+	var buttons buttonManagerAdapter.ConfigData
+	if err := jsonUtils.Copy(cfg.Buttons, &buttons); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to convert config buttons: %v\n", err)
+		return 2
+	}
+	traceFile, err := os.Open(tracePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open trace: %v\n", err)
+		return 2
+	}
+	defer traceFile.Close()
+	steps, err := buttonManagerAdapter.ReadTrace(traceFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read trace: %v\n", err)
+		return 2
+	}
+	if *simulateOutFlag != "" {
+		if err := os.MkdirAll(*simulateOutFlag, 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create output directory: %v\n", err)
+			return 2
+		}
+	}
+
+	err = buttonManagerAdapter.Simulate(buttons, steps, func(step buttonManagerAdapter.SimulationStep) error {
+		fmt.Printf("=== Step %d/%d: %s", step.Index, len(steps), step.Step.Type)
+		if step.Step.Type == buttonManagerAdapter.TraceStepWindows {
+			fmt.Printf(" (%d windows)", len(step.Step.Windows))
+		}
+		if !step.Step.Time.IsZero() {
+			fmt.Printf(" at %s", step.Step.Time.Format(time.RFC3339Nano))
+		}
+		fmt.Println()
+
+		data, err := json.MarshalIndent(step.Config, "", "  ")
+		if err != nil {
+			return err
+		}
+		if *simulateFullFlag {
+			fmt.Println(string(data))
+		} else if len(step.Diff) == 0 {
+			fmt.Println("(no changes)")
+		} else {
+			fmt.Println(strings.Join(step.Diff, "\n"))
+		}
+		if *simulateOutFlag != "" {
+			return os.WriteFile(filepath.Join(*simulateOutFlag, fmt.Sprintf("step_%03d.json", step.Index)), data, 0644)
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Simulation failed: %v\n", err)
+		return 2
+	}
+	return 0
+}
+
 // runWorker runs the specified worker type
 func runWorker(workerType string) {
 	// Preserve camelCase by only uppercasing the first rune
@@ -413,6 +525,7 @@ func runWorker(workerType string) {
 	switch workerType {
 	case "buttonManager":
 		buttonManager := buttonManagerAdapter.New(natsAdapter)
+		processmonitor.RegisterShutdownCallback(buttonManager.Flush)
 		buttonManager.Run()
 	case "mouseInputHandler":
 		mouseInputAdapter := mouseInputAdapter.New(natsAdapter)
@@ -438,6 +551,12 @@ func runWorker(workerType string) {
 			log.Fatal("Failed to create WindowManagementAdapter: %v", err)
 		}
 		windowManagement.Run()
+	case "httpApi":
+		httpApi, err := httpApiAdapter.New(natsAdapter)
+		if err != nil {
+			log.Fatal("Failed to create HttpApiAdapter: %v", err)
+		}
+		httpApi.Run()
 	default:
 		panic("Unknown worker type: " + workerType)
 	}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/exec"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -34,7 +35,9 @@ func monitorProcessLoop(pid int) {
 	}
 }
 
-// RegisterShutdownCallback registers a function to be called when shutdown is triggered
+// RegisterShutdownCallback registers a function to be called when shutdown is triggered.
+// Callbacks run in reverse order of registration, like deferred calls, so cleanup registered
+// after the callback that exits the process still runs.
 func RegisterShutdownCallback(callback func()) {
 	shutdownMutex.Lock()
 	defer shutdownMutex.Unlock()
@@ -53,7 +56,7 @@ func TriggerShutdown() {
 	shutdownCalled = true
 
 	log.Info("Triggering shutdown callbacks")
-	for _, callback := range callbacks {
+	for _, callback := range slices.Backward(callbacks) {
 		callback()
 	}
 }
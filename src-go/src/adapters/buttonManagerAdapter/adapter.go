@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"maps"
 	"os"
+	"slices"
 	"sync"
 
 	"github.com/Rayzorblade23/MightyPie-Revamped/src/adapters/natsAdapter"
@@ -15,16 +16,31 @@ import (
 // Package-level logger instance
 var log = logger.New("ButtonManager")
 
-var (
+// buttonState is the live button config and the window list it was assigned from. Each
+// adapter owns one, so an offline simulation never touches the worker's state.
+type buttonState struct {
+	mu sync.RWMutex
 	// Assumes ConfigData is map[string]MenuConfig (MenuID -> PageID -> PageConfiguration)
-	buttonConfig          ConfigData
-	windowsList           core.WindowsUpdate
-	separatedButtonsCache SeparatedButtonsCache
-	mu                    sync.RWMutex
-)
+	config    ConfigData
+	windows   core.WindowsUpdate
+	separated SeparatedButtonsCache
+	// revision is bumped on every store.
+	revision int
+}
 
 type ButtonManagerAdapter struct {
 	natsAdapter *natsAdapter.NatsAdapter
+	state       *buttonState
+	assignment  *assignmentState
+	sticky      *stickyAssignments
+	rules       *placementRules
+	trace       *traceRecorder
+	baseline    windowBaseline
+}
+
+// settingValue is the part of a settings entry the button manager reads.
+type settingValue struct {
+	Value json.RawMessage `json:"value"`
 }
 
 // New creates and initializes the ButtonManagerAdapter
@@ -34,6 +50,11 @@ func New(natsAdapter *natsAdapter.NatsAdapter) *ButtonManagerAdapter {
 	}
 	a := &ButtonManagerAdapter{
 		natsAdapter: natsAdapter,
+		state:       &buttonState{},
+		assignment:  newAssignmentState(),
+		sticky:      loadStickyAssignments(),
+		rules:       newPlacementRules(),
+		trace:       newTraceRecorder(),
 	}
 
 	windowUpdateSubject := os.Getenv("PUBLIC_NATSSUBJECT_WINDOWMANAGER_UPDATE")
@@ -44,7 +65,7 @@ func New(natsAdapter *natsAdapter.NatsAdapter) *ButtonManagerAdapter {
 
 	// Do NOT read/write the on-disk config here. The file is owned by PieMenuConfigManager.
 	// Initialize with an empty in-memory config and wait for full-config updates from PieMenuConfigManager.
-	updateButtonConfig(make(ConfigData))
+	a.state.store(make(ConfigData))
 	log.Info("Waiting for full config from PieMenuConfigManager...")
 
 	// Subscribe to full-config backend updates and extract buttons for this adapter
@@ -64,9 +85,12 @@ func New(natsAdapter *natsAdapter.NatsAdapter) *ButtonManagerAdapter {
 			}
 
 			// Update in-memory state and publish buttons to live subject
-			updateButtonConfig(payload.Buttons)
+			a.trace.record(TraceStep{Type: TraceStepConfig, Buttons: payload.Buttons})
+			a.state.store(payload.Buttons)
 			a.natsAdapter.PublishMessage(liveButtonsSubject, payload.Buttons)
-			a.natsAdapter.PublishMessage(windowUpdateSubject, windowsList)
+			// A new config replaces every button, so delta consumers get a snapshot instead
+			a.publishLiveSnapshot()
+			a.natsAdapter.PublishMessage(windowUpdateSubject, a.state.currentWindows())
 			log.Info("Processed full backend update and republished buttons to '%s'", liveButtonsSubject)
 		})
 	}
@@ -80,60 +104,151 @@ func New(natsAdapter *natsAdapter.NatsAdapter) *ButtonManagerAdapter {
 		}
 
 		// PrintWindowList(currentWindows)
-		updateWindowsList(currentWindows)
-
-		currentConfigSnapshot := GetButtonConfig() // Get clean snapshot
-
-		// Process updates
-		processedConfig, err := a.processWindowUpdate(currentConfigSnapshot, currentWindows)
-		if err != nil {
-			log.Error("Failed to process window update for button config: %v", err)
-			return
-		}
+		a.trace.record(TraceStep{Type: TraceStepWindows, Windows: currentWindows})
 
 		// Publish ONLY if changes were detected
-		if processedConfig != nil {
-			// Update global state first
-			updateButtonConfig(processedConfig)
-			log.Info("Button configuration updated (due to window event) and will be published.")
+		if update := a.applyWindowUpdate(currentWindows); update != nil {
+			log.Info("Button configuration updated (due to window event): %d button(s) changed, revision %d.", len(update.Changes), update.Revision)
 			// Publish the updated configuration
-			a.natsAdapter.PublishMessage(liveButtonsSubject, processedConfig)
+			a.publishLiveUpdate(update)
 			// PrintConfig(processedConfig, true)
 		}
 	})
 
 	// Gap-filling/compaction subscription
 	a.natsAdapter.SubscribeToSubject(fillGapsSubject, func(msg *nats.Msg) {
-		mu.Lock()
-		currentConfig := buttonConfig
-		mu.Unlock()
-		gapFilledConfig, cleared := FillWindowAssignmentGaps(currentConfig)
-		if cleared > 0 {
-			updateButtonConfig(gapFilledConfig)
-			a.natsAdapter.PublishMessage(liveButtonsSubject, gapFilledConfig)
+		a.trace.record(TraceStep{Type: TraceStepFillGaps})
+		if update := a.applyFillGaps(); update != nil {
+			a.publishLiveUpdate(update)
 			log.Info("Gap-filling/compaction performed and update published (no processWindowUpdate).")
 		} else {
 			log.Info("Gap-filling triggered but no gaps were found.")
 		}
 	})
 
+	a.subscribeFocusHistory()
+	a.subscribeSettings()
+	a.watchPlacementRules()
+	a.handleGetRequests()
+	a.serveLiveSnapshots()
+
 	return a
 }
 
-// updateButtonConfig safely updates the global buttonConfig variable and rebuilds the cache.
-func updateButtonConfig(config ConfigData) {
-	mu.Lock()
-	buttonConfig = config
-	separatedButtonsCache = buildSeparatedButtonsCache(config)
-	mu.Unlock()
+// applyWindowUpdate runs the assignment pipeline for a window update and stores the result.
+// It returns nil if nothing changed.
+func (a *ButtonManagerAdapter) applyWindowUpdate(currentWindows core.WindowsUpdate) *liveUpdate {
+	a.state.setWindows(currentWindows)
+	a.assignment.recordWindows(slices.Collect(maps.Keys(currentWindows)))
+
+	snapshot, err := a.state.snapshot() // Get clean snapshot
+	if err != nil {
+		log.Error("Failed to snapshot button config: %v", err)
+		return nil
+	}
+
+	// Where the result is the same, an update only touches the buttons of the windows it changed
+	var processedConfig ConfigData
+	handled := false
+	if previous, ok := a.baseline.previous(snapshot.Revision); ok {
+		processedConfig, handled = a.applyWindowChanges(snapshot.Data, previous, currentWindows)
+	}
+	if !handled {
+		processedConfig, err = a.processWindowUpdate(snapshot.Data, currentWindows)
+		if err != nil {
+			log.Error("Failed to process window update for button config: %v", err)
+			return nil
+		}
+	}
+
+	var update *liveUpdate
+	if processedConfig != nil {
+		// Update global state first
+		if update = a.state.commit(processedConfig); update != nil {
+			a.sticky.record(processedConfig, currentWindows)
+		}
+	}
+	revision := snapshot.Revision
+	if update != nil {
+		revision = update.Revision
+	}
+	a.baseline.remember(currentWindows, revision)
+	return update
+}
+
+// applyFillGaps compacts the window assignments of the current config and stores the result.
+// It returns nil if no assignment moved.
+func (a *ButtonManagerAdapter) applyFillGaps() *liveUpdate {
+	// FillWindowAssignmentGaps edits the config it is given, so hand it a copy
+	gapFilledConfig, moved := FillWindowAssignmentGaps(a.state.configCopy())
+	if moved == 0 {
+		return nil
+	}
+	return a.state.commit(gapFilledConfig)
+}
+
+// subscribeSettings applies the button manager's settings: window order and trace recording.
+func (a *ButtonManagerAdapter) subscribeSettings() {
+	settingsSubject := os.Getenv("PUBLIC_NATSSUBJECT_SETTINGS_UPDATE")
+	err := a.natsAdapter.SubscribeJetStreamPull(settingsSubject, "buttonManager_reader", func(msg *nats.Msg) {
+		var settings map[string]settingValue
+		if err := json.Unmarshal(msg.Data, &settings); err != nil {
+			log.Error("Failed to decode settings update: %v", err)
+			return
+		}
+		a.assignment.applyStrategySettings(settings)
+
+		var recordTrace bool
+		if entry, ok := settings["recordWindowTrace"]; ok {
+			_ = json.Unmarshal(entry.Value, &recordTrace)
+		}
+		a.trace.setEnabled(recordTrace)
+	})
+	if err != nil {
+		log.Error("Failed to subscribe to settings updates: %v", err)
+	}
+}
+
+// handleGetRequests answers `get` requests with the live button config and its revision.
+func (a *ButtonManagerAdapter) handleGetRequests() {
+	natsAdapter.HandleRequest(a.natsAdapter, os.Getenv("PUBLIC_NATSSUBJECT_LIVEBUTTONCONFIG_GET"),
+		func(struct{}) (natsAdapter.Snapshot[ConfigData], error) {
+			return a.state.snapshot()
+		})
+}
+
+// snapshot returns a deep copy of the live button config together with its revision.
+func (s *buttonState) snapshot() (natsAdapter.Snapshot[ConfigData], error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	config, err := deepCopyConfig(s.config)
+	if err != nil {
+		return natsAdapter.Snapshot[ConfigData]{}, err
+	}
+	return natsAdapter.Snapshot[ConfigData]{Revision: s.revision, Data: config}, nil
+}
+
+// store replaces the button config and rebuilds the cache. It returns the new revision.
+func (s *buttonState) store(config ConfigData) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.storeLocked(config)
 }
 
-// getSeparatedButtons safely retrieves separated buttons for a specific menu and page.
-func getSeparatedButtons(menuID, pageID string) *SeparatedButtons {
-	mu.RLock()
-	defer mu.RUnlock()
+// storeLocked replaces the button config; the caller holds s.mu.
+func (s *buttonState) storeLocked(config ConfigData) int {
+	s.config = config
+	s.revision++
+	s.separated = buildSeparatedButtonsCache(config)
+	return s.revision
+}
+
+// separatedButtons safely retrieves separated buttons for a specific menu and page.
+func (s *buttonState) separatedButtons(menuID, pageID string) *SeparatedButtons {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	if menuCache, ok := separatedButtonsCache[menuID]; ok {
+	if menuCache, ok := s.separated[menuID]; ok {
 		if pageCache, ok := menuCache[pageID]; ok {
 			return pageCache
 		}
@@ -141,12 +256,25 @@ func getSeparatedButtons(menuID, pageID string) *SeparatedButtons {
 	return nil
 }
 
-// updateWindowsList safely updates the global windowsList variable.
-func updateWindowsList(newList core.WindowsUpdate) {
-	mu.Lock()
-	windowsList = make(core.WindowsUpdate, len(newList))
-	maps.Copy(windowsList, newList)
-	mu.Unlock()
+// setWindows safely replaces the window list.
+func (s *buttonState) setWindows(newList core.WindowsUpdate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.windows = make(core.WindowsUpdate, len(newList))
+	maps.Copy(s.windows, newList)
+}
+
+// currentWindows returns the last window list. It is replaced, never modified, so callers may read it.
+func (s *buttonState) currentWindows() core.WindowsUpdate {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.windows
+}
+
+// Flush writes state that is saved in the background, e.g. the remembered window assignments.
+// The worker calls it on shutdown.
+func (a *ButtonManagerAdapter) Flush() {
+	a.sticky.flush()
 }
 
 // Run keeps the adapter alive (if needed, e.g., for non-NATS goroutines)
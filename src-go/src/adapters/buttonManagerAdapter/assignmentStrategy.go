@@ -0,0 +1,261 @@
+package buttonManagerAdapter
+
+import (
+	"cmp"
+	"encoding/json"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+)
+
+// WindowAssignmentStrategy decides which free windows fill the empty ShowAnyWindow slots of a menu.
+// Order sorts windows in place; the first window goes to the lowest page and button.
+type WindowAssignmentStrategy interface {
+	Order(windows []availableWindowInfo, history windowHistory)
+}
+
+// compareStrategy implements WindowAssignmentStrategy with a comparison function.
+// Ties are broken by handle so the result never depends on map iteration order.
+type compareStrategy func(a, b availableWindowInfo, history windowHistory) int
+
+func (c compareStrategy) Order(windows []availableWindowInfo, history windowHistory) {
+	slices.SortStableFunc(windows, func(a, b availableWindowInfo) int {
+		if n := c(a, b, history); n != 0 {
+			return n
+		}
+		return cmp.Compare(a.Handle, b.Handle)
+	})
+}
+
+// Names of the built-in strategies, as offered by the windowAssignmentStrategy setting.
+const (
+	StrategyHandleOrder   = "Handle Order"
+	StrategyCreationOrder = "Creation Order"
+	StrategyAlphabetical  = "Alphabetical"
+	StrategyRecentFocus   = "Most Recently Focused"
+	StrategyGroupedByApp  = "Grouped by App"
+)
+
+// assignmentStrategies holds the built-in strategies by name.
+var assignmentStrategies = map[string]WindowAssignmentStrategy{
+	// Raw window handle order; the historical behaviour.
+	StrategyHandleOrder: compareStrategy(func(a, b availableWindowInfo, _ windowHistory) int { return 0 }),
+
+	// Windows in the order the button manager first saw them. Windows present at startup
+	// share the first sighting and fall back to handle order.
+	StrategyCreationOrder: compareStrategy(func(a, b availableWindowInfo, h windowHistory) int {
+		return cmp.Compare(h.firstSeen[a.Handle], h.firstSeen[b.Handle])
+	}),
+
+	// By app name, then title, case-insensitively.
+	StrategyAlphabetical: compareStrategy(func(a, b availableWindowInfo, _ windowHistory) int {
+		if n := strings.Compare(strings.ToLower(appKey(a)), strings.ToLower(appKey(b))); n != 0 {
+			return n
+		}
+		return strings.Compare(strings.ToLower(a.Info.Title), strings.ToLower(b.Info.Title))
+	}),
+
+	// Most recently focused first; windows never focused follow in creation order.
+	StrategyRecentFocus: compareStrategy(func(a, b availableWindowInfo, h windowHistory) int {
+		if n := cmp.Compare(h.lastFocused[b.Handle], h.lastFocused[a.Handle]); n != 0 {
+			return n
+		}
+		return cmp.Compare(h.firstSeen[a.Handle], h.firstSeen[b.Handle])
+	}),
+
+	// Windows of one app next to each other. Apps are ordered by their oldest window,
+	// windows within an app by creation order.
+	StrategyGroupedByApp: groupedByAppStrategy{},
+}
+
+type groupedByAppStrategy struct{}
+
+func (groupedByAppStrategy) Order(windows []availableWindowInfo, history windowHistory) {
+	oldest := make(map[string]int)
+	for _, w := range windows {
+		seen := history.firstSeen[w.Handle]
+		if first, ok := oldest[appKey(w)]; !ok || seen < first {
+			oldest[appKey(w)] = seen
+		}
+	}
+	compareStrategy(func(a, b availableWindowInfo, h windowHistory) int {
+		if n := cmp.Compare(oldest[appKey(a)], oldest[appKey(b)]); n != 0 {
+			return n
+		}
+		if n := strings.Compare(appKey(a), appKey(b)); n != 0 {
+			return n
+		}
+		return cmp.Compare(h.firstSeen[a.Handle], h.firstSeen[b.Handle])
+	}).Order(windows, history)
+}
+
+// appKey identifies the app a window belongs to.
+func appKey(w availableWindowInfo) string {
+	if w.Info.AppName != "" {
+		return w.Info.AppName
+	}
+	return w.Info.ExeName
+}
+
+// windowHistory records when windows were first seen and last focused, as increasing sequence numbers.
+// Zero means never.
+type windowHistory struct {
+	firstSeen   map[int]int
+	lastFocused map[int]int
+}
+
+// assignmentState is the window history and the window order settings of one adapter.
+type assignmentState struct {
+	mu              sync.Mutex
+	history         windowHistory
+	seq             int
+	initialized     bool
+	strategyDefault string
+	strategyPerMenu map[string]string
+}
+
+func newAssignmentState() *assignmentState {
+	return &assignmentState{
+		history:         windowHistory{firstSeen: map[int]int{}, lastFocused: map[int]int{}},
+		strategyDefault: StrategyHandleOrder,
+		strategyPerMenu: map[string]string{},
+	}
+}
+
+// recordWindows notes newly seen windows and forgets closed ones.
+// All windows of the first update count as seen at the same time.
+func (s *assignmentState) recordWindows(handles []int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := make(map[int]bool, len(handles))
+	slices.Sort(handles)
+	s.seq++
+	for _, handle := range handles {
+		current[handle] = true
+		if _, ok := s.history.firstSeen[handle]; !ok {
+			s.history.firstSeen[handle] = s.seq
+			if s.initialized {
+				// Several new windows in one update are ordered by handle.
+				s.seq++
+			}
+		}
+	}
+	s.initialized = true
+	for handle := range s.history.firstSeen {
+		if !current[handle] {
+			delete(s.history.firstSeen, handle)
+			delete(s.history.lastFocused, handle)
+		}
+	}
+}
+
+// recordFocus notes that a window received the focus.
+func (s *assignmentState) recordFocus(handle int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	s.history.lastFocused[handle] = s.seq
+}
+
+// historySnapshot returns a copy of the window history for use outside the lock.
+func (s *assignmentState) historySnapshot() windowHistory {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return windowHistory{
+		firstSeen:   maps.Clone(s.history.firstSeen),
+		lastFocused: maps.Clone(s.history.lastFocused),
+	}
+}
+
+// strategyForMenu returns the assignment strategy configured for menuID.
+func (s *assignmentState) strategyForMenu(menuID string) WindowAssignmentStrategy {
+	s.mu.Lock()
+	name, ok := s.strategyPerMenu[menuID]
+	if !ok {
+		name = s.strategyDefault
+	}
+	s.mu.Unlock()
+	return assignmentStrategies[name]
+}
+
+// lookupStrategy resolves a strategy name case-insensitively.
+func lookupStrategy(name string) (string, bool) {
+	for known := range assignmentStrategies {
+		if strings.EqualFold(known, strings.TrimSpace(name)) {
+			return known, true
+		}
+	}
+	return "", false
+}
+
+// parseStrategyOverrides parses "menuID=strategy" pairs separated by commas.
+// Invalid pairs are logged and skipped.
+func parseStrategyOverrides(value string) map[string]string {
+	overrides := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		menuID, name, found := strings.Cut(pair, "=")
+		menuID = strings.TrimSpace(menuID)
+		if _, err := strconv.Atoi(menuID); !found || err != nil {
+			log.Warn("Ignoring window order override '%s': expected menuID=order", strings.TrimSpace(pair))
+			continue
+		}
+		strategy, ok := lookupStrategy(name)
+		if !ok {
+			log.Warn("Ignoring window order override '%s': unknown order '%s'", strings.TrimSpace(pair), strings.TrimSpace(name))
+			continue
+		}
+		overrides[menuID] = strategy
+	}
+	return overrides
+}
+
+// subscribeFocusHistory tracks which windows were focused last.
+func (a *ButtonManagerAdapter) subscribeFocusHistory() {
+	a.natsAdapter.SubscribeToSubject(os.Getenv("PUBLIC_NATSSUBJECT_FOCUSEDAPP_UPDATE"), func(msg *nats.Msg) {
+		var payload struct {
+			WindowHandle int `json:"windowHandle"`
+		}
+		if err := json.Unmarshal(msg.Data, &payload); err != nil {
+			log.Error("Failed to decode focused app update: %v", err)
+			return
+		}
+		if payload.WindowHandle != 0 {
+			a.assignment.recordFocus(payload.WindowHandle)
+		}
+	})
+}
+
+// applyStrategySettings takes over the windowAssignmentStrategy settings.
+func (s *assignmentState) applyStrategySettings(settings map[string]settingValue) {
+	var defaultName, overrides string
+	if entry, ok := settings["windowAssignmentStrategy"]; ok {
+		_ = json.Unmarshal(entry.Value, &defaultName)
+	}
+	if entry, ok := settings["windowAssignmentStrategyPerMenu"]; ok {
+		_ = json.Unmarshal(entry.Value, &overrides)
+	}
+
+	strategy, ok := lookupStrategy(defaultName)
+	if !ok {
+		if defaultName != "" {
+			log.Warn("Unknown window order '%s'; using '%s'", defaultName, StrategyHandleOrder)
+		}
+		strategy = StrategyHandleOrder
+	}
+	perMenu := parseStrategyOverrides(overrides)
+
+	s.mu.Lock()
+	s.strategyDefault = strategy
+	s.strategyPerMenu = perMenu
+	s.mu.Unlock()
+	log.Info("Window order: '%s' (%d per-menu override(s))", strategy, len(perMenu))
+}
@@ -1,11 +1,11 @@
 package buttonManagerAdapter
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
-	"slices"
 
 	"github.com/Rayzorblade23/MightyPie-Revamped/src/core"
 	"github.com/Rayzorblade23/MightyPie-Revamped/src/core/jsonUtils"
@@ -15,24 +15,24 @@ const (
 	backupFilePrefix = "piemenuConfig_BACKUP"
 )
 
-// GetButtonConfig returns a deep copy of the current button configuration.
-func GetButtonConfig() ConfigData {
-	mu.RLock()
-	configToCopy := buttonConfig
+// configCopy returns a deep copy of the current button configuration.
+func (s *buttonState) configCopy() ConfigData {
+	s.mu.RLock()
+	configToCopy := s.config
 	sourceLen := len(configToCopy)
-	mu.RUnlock()
+	s.mu.RUnlock()
 
 	copiedConfig, err := deepCopyConfig(configToCopy)
 	if err != nil {
-		log.Error("GetButtonConfig - deepCopyConfig returned an error: %v. Returning empty config.", err)
+		log.Error("configCopy - deepCopyConfig returned an error: %v. Returning empty config.", err)
 		return make(ConfigData)
 	}
 	if copiedConfig == nil { // Should not happen with current deepCopyConfig logic
-		log.Error("GetButtonConfig - deepCopyConfig returned nil unexpectedly. Returning empty config.")
+		log.Error("configCopy - deepCopyConfig returned nil unexpectedly. Returning empty config.")
 		return make(ConfigData)
 	}
 	if len(copiedConfig) == 0 && sourceLen > 0 {
-		log.Warn("GetButtonConfig - deepCopyConfig resulted in an EMPTY map, but source was NOT empty (len %d)! Decode likely failed inside deepCopyConfig.", sourceLen)
+		log.Warn("configCopy - deepCopyConfig resulted in an EMPTY map, but source was NOT empty (len %d)! Decode likely failed inside deepCopyConfig.", sourceLen)
 		return make(ConfigData)
 	}
 
@@ -94,15 +94,29 @@ func mustMarshalProperties(v any) json.RawMessage {
 }
 
 // deepCopyConfig performs a deep copy of the configuration.
+// Maps and property bytes are copied directly instead of round-tripping through JSON;
+// a nil menu or page stays nil.
 func deepCopyConfig(src ConfigData) (ConfigData, error) {
-	if src == nil {
-		return make(ConfigData), nil
-	}
-
-	dst := make(ConfigData)
-	if err := jsonUtils.Copy(src, &dst); err != nil {
-		log.Error("deepCopyConfig - failed to copy config: %v", err)
-		return nil, err
+	dst := make(ConfigData, len(src))
+	for menuID, menuConfig := range src {
+		if menuConfig == nil {
+			dst[menuID] = nil
+			continue
+		}
+		menuCopy := make(MenuConfig, len(menuConfig))
+		for pageID, pageConfig := range menuConfig {
+			if pageConfig == nil {
+				menuCopy[pageID] = nil
+				continue
+			}
+			pageCopy := make(PageConfig, len(pageConfig))
+			for buttonID, button := range pageConfig {
+				button.Properties = bytes.Clone(button.Properties)
+				pageCopy[buttonID] = button
+			}
+			menuCopy[pageID] = pageCopy
+		}
+		dst[menuID] = menuCopy
 	}
 	return dst, nil
 }
@@ -239,15 +253,7 @@ func validateAndRepairConfig(config ConfigData) bool {
 						buttonID, pageID, menuID)
 					
 					// Create a default ShowAnyWindow button
-					pageConfig[buttonID] = Button{
-						ButtonType: "show_any_window",
-						Properties: mustMarshalProperties(core.ShowAnyWindowProperties{
-							ButtonTextUpper: "",
-							ButtonTextLower: "",
-							IconPath:        "",
-							WindowHandle:    InvalidHandle,
-						}),
-					}
+					pageConfig[buttonID] = defaultButton()
 					configChanged = true
 					continue
 				}
@@ -259,15 +265,7 @@ func validateAndRepairConfig(config ConfigData) bool {
 						button.ButtonType, buttonID, pageID, menuID)
 					
 					// Reset to a default ShowAnyWindow button
-					pageConfig[buttonID] = Button{
-						ButtonType: "show_any_window",
-						Properties: mustMarshalProperties(core.ShowAnyWindowProperties{
-							ButtonTextUpper: "",
-							ButtonTextLower: "",
-							IconPath:        "",
-							WindowHandle:    InvalidHandle,
-						}),
-					}
+					pageConfig[buttonID] = defaultButton()
 					configChanged = true
 					continue
 				}
@@ -278,15 +276,7 @@ func validateAndRepairConfig(config ConfigData) bool {
 						buttonID, pageID, menuID, button.ButtonType)
 					
 					// Reset to a default ShowAnyWindow button
-					pageConfig[buttonID] = Button{
-						ButtonType: "show_any_window",
-						Properties: mustMarshalProperties(core.ShowAnyWindowProperties{
-							ButtonTextUpper: "",
-							ButtonTextLower: "",
-							IconPath:        "",
-							WindowHandle:    InvalidHandle,
-						}),
-					}
+					pageConfig[buttonID] = defaultButton()
 					configChanged = true
 				}
 			}
@@ -296,88 +286,23 @@ func validateAndRepairConfig(config ConfigData) bool {
 	return configChanged
 }
 
-// validateButtonType checks if the button type is one of the valid types
-func validateButtonType(buttonType string) bool {
-	validTypes := []string{
-		"show_program_window",
-		"show_any_window",
-		"call_function",
-		"launch_program",
-		"open_page_in_menu",
-		"open_resource",
-		"disabled",
-	}
+// defaultButton returns the ShowAnyWindow button that replaces missing or invalid buttons.
+func defaultButton() Button {
+	info, _ := core.LookupButtonType(core.ButtonTypeShowAnyWindow)
+	return Button{ButtonType: string(info.Type), Properties: info.DefaultProperties()}
+}
 
-	return slices.Contains(validTypes, buttonType)
+// validateButtonType checks if the button type is registered
+func validateButtonType(buttonType string) bool {
+	_, ok := core.LookupButtonType(core.ButtonType(buttonType))
+	return ok
 }
 
-// validateButtonProperties checks if the button properties match the expected structure for its type
+// validateButtonProperties checks if the button properties are valid for its registered type
 func validateButtonProperties(button Button) bool {
-	// For each button type, try to unmarshal the properties into the expected struct
-	// If it fails, the properties don't match the expected structure
-	switch button.ButtonType {
-	case "show_any_window":
-		var props core.ShowAnyWindowProperties
-		if err := json.Unmarshal(button.Properties, &props); err != nil {
-			log.Warn("Failed to unmarshal ShowAnyWindowProperties: %v", err)
-			return false
-		}
-		return true
-
-	case "show_program_window":
-		var props core.ShowProgramWindowProperties
-		if err := json.Unmarshal(button.Properties, &props); err != nil {
-			log.Warn("Failed to unmarshal ShowProgramWindowProperties: %v", err)
-			return false
-		}
-		return true
-
-	case "call_function":
-		var props core.CallFunctionProperties
-		if err := json.Unmarshal(button.Properties, &props); err != nil {
-			log.Warn("Failed to unmarshal CallFunctionProperties: %v", err)
-			return false
-		}
-		return true
-
-	case "launch_program":
-		var props core.LaunchProgramProperties
-		if err := json.Unmarshal(button.Properties, &props); err != nil {
-			log.Warn("Failed to unmarshal LaunchProgramProperties: %v", err)
-			return false
-		}
-		return true
-
-	case "open_page_in_menu":
-		var props core.OpenSpecificPieMenuPage
-		if err := json.Unmarshal(button.Properties, &props); err != nil {
-			log.Warn("Failed to unmarshal OpenSpecificPieMenuPage: %v", err)
-			return false
-		}
-		return true
-
-	case "open_resource":
-		var props core.OpenResourceProperties
-		if err := json.Unmarshal(button.Properties, &props); err != nil {
-			log.Warn("Failed to unmarshal OpenResourceProperties: %v", err)
-			return false
-		}
-		return true
-
-	case "keyboard_shortcut":
-		var props core.KeyboardShortcut
-		if err := json.Unmarshal(button.Properties, &props); err != nil {
-			log.Warn("Failed to unmarshal KeyboardShortcut: %v", err)
-			return false
-		}
-		return true
-
-	case "disabled":
-		// Disabled buttons don't need specific properties
-		return true
-
-	default:
-		// Unknown button type
+	if err := core.ValidateButton(button.ButtonType, button.Properties); err != nil {
+		log.Warn("%v", err)
 		return false
 	}
+	return true
 }
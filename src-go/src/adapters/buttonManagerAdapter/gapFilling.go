@@ -99,12 +99,17 @@ func FillWindowAssignmentGaps(config ConfigData) (ConfigData, int) {
 					srcBtnToMove := config[srcKey.menuID][srcKey.pageID][srcKey.btnID]
 					gapBtnToFill := config[gapKey.menuID][gapKey.pageID][gapKey.btnID]
 
+					// Only the window moves; click actions belong to the slot
 					switch buttonType {
 					case core.ButtonTypeShowAnyWindow:
 						props, _ := GetButtonProperties[core.ShowAnyWindowProperties](srcBtnToMove)
+						gapProps, _ := GetButtonProperties[core.ShowAnyWindowProperties](gapBtnToFill)
+						props.ButtonActions = gapProps.ButtonActions
 						SetButtonProperties(&gapBtnToFill, props)
 					case core.ButtonTypeShowProgramWindow:
 						props, _ := GetButtonProperties[core.ShowProgramWindowProperties](srcBtnToMove)
+						gapProps, _ := GetButtonProperties[core.ShowProgramWindowProperties](gapBtnToFill)
+						props.ButtonActions = gapProps.ButtonActions
 						SetButtonProperties(&gapBtnToFill, props)
 					}
 					clearButtonWindowProperties(&srcBtnToMove)
@@ -0,0 +1,130 @@
+package buttonManagerAdapter
+
+import (
+	"bytes"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/adapters/natsAdapter"
+)
+
+// liveSnapshotInterval is how often the full live button config is published as a snapshot,
+// so consumers that missed a delta catch up without asking.
+const liveSnapshotInterval = 30 * time.Second
+
+// ButtonChange is one changed button. Button is nil if the button was removed.
+type ButtonChange struct {
+	MenuID   string  `json:"menuId"`
+	PageID   string  `json:"pageId"`
+	ButtonID string  `json:"buttonId"`
+	Button   *Button `json:"button"`
+}
+
+// LiveButtonDelta turns the live button config at BaseRevision into the one at Revision.
+// A consumer whose revision is not BaseRevision has missed an update and must resync.
+type LiveButtonDelta struct {
+	BaseRevision int            `json:"baseRevision"`
+	Revision     int            `json:"revision"`
+	Changes      []ButtonChange `json:"changes"`
+}
+
+// liveUpdate is a stored change to the live button config.
+type liveUpdate struct {
+	Config   ConfigData
+	Changes  []ButtonChange
+	Revision int
+}
+
+// diffButtons lists the buttons that differ between two configs, in menu, page and button order.
+func diffButtons(before, after ConfigData) []ButtonChange {
+	var changes []ButtonChange
+	for menuID, menuConfig := range after {
+		for pageID, pageConfig := range menuConfig {
+			for buttonID, button := range pageConfig {
+				old, existed := before[menuID][pageID][buttonID]
+				if existed && old.ButtonType == button.ButtonType && bytes.Equal(old.Properties, button.Properties) {
+					continue
+				}
+				changes = append(changes, ButtonChange{MenuID: menuID, PageID: pageID, ButtonID: buttonID, Button: &button})
+			}
+		}
+	}
+	for menuID, menuConfig := range before {
+		for pageID, pageConfig := range menuConfig {
+			for buttonID := range pageConfig {
+				if _, exists := after[menuID][pageID][buttonID]; !exists {
+					changes = append(changes, ButtonChange{MenuID: menuID, PageID: pageID, ButtonID: buttonID})
+				}
+			}
+		}
+	}
+
+	slices.SortFunc(changes, func(a, b ButtonChange) int {
+		if n := compareNumericIDs(a.MenuID, b.MenuID); n != 0 {
+			return n
+		}
+		if n := compareNumericIDs(a.PageID, b.PageID); n != 0 {
+			return n
+		}
+		return compareNumericIDs(a.ButtonID, b.ButtonID)
+	})
+	return changes
+}
+
+// commit stores config as the live button config, unless it equals the current one.
+// It returns nil if nothing changed.
+func (s *buttonState) commit(config ConfigData) *liveUpdate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changes := diffButtons(s.config, config)
+	if len(changes) == 0 {
+		return nil
+	}
+	return &liveUpdate{Config: config, Changes: changes, Revision: s.storeLocked(config)}
+}
+
+// publishLiveUpdate publishes a change to the live button config: the full config for
+// existing consumers and the delta for those that keep their own copy.
+func (a *ButtonManagerAdapter) publishLiveUpdate(update *liveUpdate) {
+	a.natsAdapter.PublishMessage(os.Getenv("PUBLIC_NATSSUBJECT_LIVEBUTTONCONFIG"), update.Config)
+	if deltaSubject := os.Getenv("PUBLIC_NATSSUBJECT_LIVEBUTTONCONFIG_DELTA"); deltaSubject != "" {
+		a.natsAdapter.PublishMessage(deltaSubject, LiveButtonDelta{
+			BaseRevision: update.Revision - 1,
+			Revision:     update.Revision,
+			Changes:      update.Changes,
+		})
+	}
+}
+
+// publishLiveSnapshot publishes the full live button config with its revision.
+func (a *ButtonManagerAdapter) publishLiveSnapshot() {
+	subject := os.Getenv("PUBLIC_NATSSUBJECT_LIVEBUTTONCONFIG_SNAPSHOT")
+	if subject == "" {
+		return
+	}
+	snapshot, err := a.state.snapshot()
+	if err != nil {
+		log.Error("Failed to snapshot live button config: %v", err)
+		return
+	}
+	a.natsAdapter.PublishMessage(subject, snapshot)
+}
+
+// serveLiveSnapshots publishes snapshots periodically and on resync requests. A resync is
+// answered with the snapshot and also broadcast, so every consumer realigns at once.
+func (a *ButtonManagerAdapter) serveLiveSnapshots() {
+	natsAdapter.HandleRequest(a.natsAdapter, os.Getenv("PUBLIC_NATSSUBJECT_LIVEBUTTONCONFIG_RESYNC"),
+		func(struct{}) (natsAdapter.Snapshot[ConfigData], error) {
+			a.publishLiveSnapshot()
+			return a.state.snapshot()
+		})
+
+	go func() {
+		ticker := time.NewTicker(liveSnapshotInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			a.publishLiveSnapshot()
+		}
+	}()
+}
@@ -0,0 +1,355 @@
+package buttonManagerAdapter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core"
+)
+
+// Placement rule actions.
+const (
+	// RulePin keeps a window inside its target: it is placed there first and never anywhere else.
+	RulePin = "pin"
+	// RulePrefer places a window in its target first but allows any other slot.
+	RulePrefer = "prefer"
+	// RuleForbid keeps a window out of its target.
+	RuleForbid = "forbid"
+)
+
+// placementRulesPollInterval is how often the rule file is checked for changes.
+const placementRulesPollInterval = 2 * time.Second
+
+// PlacementRulesFile is the user-edited rule file, e.g.:
+//
+//	{"rules": [
+//	  {"name": "Tickets", "match": {"title": "/JIRA-\\d+/"}, "action": "pin", "target": {"menu": 1, "page": 2}},
+//	  {"match": {"app": "Slack"}, "action": "forbid", "target": {"page": 0}, "slots": ["show_any_window"]}
+//	]}
+type PlacementRulesFile struct {
+	Rules []PlacementRule `json:"rules"`
+}
+
+// PlacementRule places the windows it matches relative to its target.
+type PlacementRule struct {
+	Name   string          `json:"name,omitempty"`
+	Match  PlacementMatch  `json:"match"`
+	Action string          `json:"action"`
+	Target PlacementTarget `json:"target"`
+	// Slots limits the rule to these button types (show_any_window, show_program_window). Empty means both.
+	Slots []string `json:"slots,omitempty"`
+}
+
+// PlacementMatch selects windows. All set fields must match; Exe and App ignore case.
+// Title is a regular expression, optionally written as /expr/ or /expr/i.
+type PlacementMatch struct {
+	Exe      string `json:"exe,omitempty"`
+	App      string `json:"app,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Instance *int   `json:"instance,omitempty"`
+}
+
+// PlacementTarget selects slots. Unset fields match any menu, page or button.
+type PlacementTarget struct {
+	Menu   *int `json:"menu,omitempty"`
+	Page   *int `json:"page,omitempty"`
+	Button *int `json:"button,omitempty"`
+}
+
+// PlacementRulesStatus is published after every (re)load of the rule file.
+type PlacementRulesStatus struct {
+	Path   string   `json:"path"`
+	OK     bool     `json:"ok"`
+	Rules  int      `json:"rules"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+type compiledRule struct {
+	PlacementRule
+	title *regexp.Regexp
+}
+
+func (r compiledRule) matchesWindow(info core.WindowInfo) bool {
+	m := r.Match
+	return (m.Exe == "" || strings.EqualFold(m.Exe, info.ExeName)) &&
+		(m.App == "" || strings.EqualFold(m.App, info.AppName)) &&
+		(r.title == nil || r.title.MatchString(info.Title)) &&
+		(m.Instance == nil || *m.Instance == info.Instance)
+}
+
+func (r compiledRule) matchesSlot(slot placementSlot) bool {
+	t := r.Target
+	return (len(r.Slots) == 0 || slices.Contains(r.Slots, string(slot.buttonType))) &&
+		(t.Menu == nil || *t.Menu == slot.menu) &&
+		(t.Page == nil || *t.Page == slot.page) &&
+		(t.Button == nil || *t.Button == slot.button)
+}
+
+// placementSlot is a window button as seen by the rules.
+type placementSlot struct {
+	buttonType core.ButtonType
+	menu       int
+	page       int
+	button     int
+}
+
+// newPlacementSlot parses the IDs of a button; ok is false for non-numeric IDs.
+func newPlacementSlot(buttonType core.ButtonType, menuID, pageID, buttonID string) (placementSlot, bool) {
+	menu, errM := strconv.Atoi(menuID)
+	page, errP := strconv.Atoi(pageID)
+	button, errB := strconv.Atoi(buttonID)
+	return placementSlot{buttonType, menu, page, button}, errM == nil && errP == nil && errB == nil
+}
+
+// placementRules holds the active rules. Invalid rule files are rejected as a whole and the
+// previous rules stay active.
+type placementRules struct {
+	mu      sync.RWMutex
+	path    string
+	rules   []compiledRule
+	modTime time.Time
+	size    int64
+}
+
+// Allows reports whether info may be placed in slot: no forbid rule targets the slot and,
+// if the window is pinned, the slot lies inside one of its pin targets.
+func (p *placementRules) Allows(info core.WindowInfo, slot placementSlot) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	pinned, inPin := false, false
+	for _, r := range p.rules {
+		if !r.matchesWindow(info) {
+			continue
+		}
+		switch r.Action {
+		case RuleForbid:
+			if r.matchesSlot(slot) {
+				return false
+			}
+		case RulePin:
+			pinned = true
+			inPin = inPin || r.matchesSlot(slot)
+		}
+	}
+	return !pinned || inPin
+}
+
+// active reports whether any rules are loaded.
+func (p *placementRules) active() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.rules) > 0
+}
+
+// Prefers reports whether a pin or prefer rule targets slot for info.
+func (p *placementRules) Prefers(info core.WindowInfo, slot placementSlot) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, r := range p.rules {
+		if (r.Action == RulePin || r.Action == RulePrefer) && r.matchesWindow(info) && r.matchesSlot(slot) {
+			return true
+		}
+	}
+	return false
+}
+
+// placementAllowed checks the rules for placing info in the given button.
+func (a *ButtonManagerAdapter) placementAllowed(info core.WindowInfo, buttonType core.ButtonType, menuID, pageID, buttonID string) bool {
+	slot, ok := newPlacementSlot(buttonType, menuID, pageID, buttonID)
+	return !ok || a.rules.Allows(info, slot)
+}
+
+// placementPreferred reports whether a rule asks for info to be placed in the given button.
+func (a *ButtonManagerAdapter) placementPreferred(info core.WindowInfo, buttonType core.ButtonType, menuID, pageID, buttonID string) bool {
+	slot, ok := newPlacementSlot(buttonType, menuID, pageID, buttonID)
+	return ok && a.rules.Prefers(info, slot)
+}
+
+// compilePlacementRules validates a rule file. Every problem is reported, in file order and
+// prefixed with the rule it belongs to.
+func compilePlacementRules(file PlacementRulesFile) ([]compiledRule, []string) {
+	var compiled []compiledRule
+	var problems []string
+	for i, rule := range file.Rules {
+		label := fmt.Sprintf("rule %d", i+1)
+		if rule.Name != "" {
+			label = fmt.Sprintf("rule %d (%q)", i+1, rule.Name)
+		}
+		fail := func(format string, args ...any) {
+			problems = append(problems, label+": "+fmt.Sprintf(format, args...))
+		}
+
+		c := compiledRule{PlacementRule: rule}
+		m := rule.Match
+		if m.Exe == "" && m.App == "" && m.Title == "" && m.Instance == nil {
+			fail("match needs at least one of exe, app, title or instance")
+		}
+		if m.Title != "" {
+			re, err := compileTitlePattern(m.Title)
+			if err != nil {
+				fail("title %q is not a valid regular expression: %v", m.Title, err)
+			}
+			c.title = re
+		}
+		if m.Instance != nil && *m.Instance < 0 {
+			fail("instance must not be negative")
+		}
+
+		switch rule.Action {
+		case RulePin, RulePrefer, RuleForbid:
+		case "":
+			fail("action is missing (expected %s, %s or %s)", RulePin, RulePrefer, RuleForbid)
+		default:
+			fail("unknown action %q (expected %s, %s or %s)", rule.Action, RulePin, RulePrefer, RuleForbid)
+		}
+
+		t := rule.Target
+		if t.Menu == nil && t.Page == nil && t.Button == nil && rule.Action != RuleForbid {
+			fail("%s needs a target with at least one of menu, page or button", rule.Action)
+		}
+		if (t.Menu != nil && *t.Menu < 0) || (t.Page != nil && *t.Page < 0) || (t.Button != nil && *t.Button < 0) {
+			fail("target menu, page and button must not be negative")
+		}
+
+		for _, s := range rule.Slots {
+			if bt := core.ButtonType(s); bt != core.ButtonTypeShowAnyWindow && bt != core.ButtonTypeShowProgramWindow {
+				fail("unknown slot type %q (expected %s or %s)", s, core.ButtonTypeShowAnyWindow, core.ButtonTypeShowProgramWindow)
+			}
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, problems
+}
+
+// compileTitlePattern accepts a bare expression or one wrapped in slashes with an optional i flag.
+func compileTitlePattern(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") {
+		switch {
+		case strings.HasSuffix(pattern, "/i") && len(pattern) >= 3:
+			pattern = "(?i)" + pattern[1:len(pattern)-2]
+		case strings.HasSuffix(pattern, "/"):
+			pattern = pattern[1 : len(pattern)-1]
+		}
+	}
+	return regexp.Compile(pattern)
+}
+
+// newPlacementRules resolves the rule file path. The file itself is loaded by watchPlacementRules.
+func newPlacementRules() *placementRules {
+	p := &placementRules{}
+	rel := os.Getenv("PUBLIC_DIR_WINDOWPLACEMENTRULES")
+	if rel == "" {
+		log.Warn("PUBLIC_DIR_WINDOWPLACEMENTRULES is not set; window placement rules are disabled")
+		return p
+	}
+	appDataDir, err := core.GetAppDataDir()
+	if err != nil {
+		log.Warn("Failed to resolve app data dir for placement rules: %v", err)
+		return p
+	}
+	p.path = filepath.Join(appDataDir, rel)
+	return p
+}
+
+// reload reads the rule file if it changed since the last call. It returns the load status,
+// or nil if the file is unchanged.
+func (p *placementRules) reload() *PlacementRulesStatus {
+	var modTime time.Time
+	var size int64
+	stat, err := os.Stat(p.path)
+	if err == nil {
+		modTime, size = stat.ModTime(), stat.Size()
+	} else if !os.IsNotExist(err) {
+		log.Warn("Failed to stat placement rules '%s': %v", p.path, err)
+		return nil
+	}
+	p.mu.RLock()
+	unchanged := modTime.Equal(p.modTime) && size == p.size
+	p.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	status := &PlacementRulesStatus{Path: p.path}
+	var file PlacementRulesFile
+	if err == nil {
+		data, readErr := os.ReadFile(p.path)
+		if readErr != nil {
+			log.Warn("Failed to read placement rules '%s': %v", p.path, readErr)
+			return nil
+		}
+		if len(bytes.TrimSpace(data)) > 0 {
+			decoder := json.NewDecoder(bytes.NewReader(data))
+			decoder.DisallowUnknownFields()
+			if err := decoder.Decode(&file); err != nil {
+				status.Errors = []string{fmt.Sprintf("invalid rule file: %v", err)}
+			}
+		}
+	}
+
+	var rules []compiledRule
+	if status.Errors == nil {
+		rules, status.Errors = compilePlacementRules(file)
+	}
+
+	p.mu.Lock()
+	p.modTime, p.size = modTime, size
+	if status.Errors == nil {
+		p.rules = rules
+	}
+	status.Rules = len(p.rules)
+	p.mu.Unlock()
+
+	status.OK = status.Errors == nil
+	if status.OK {
+		log.Info("Loaded %d window placement rule(s) from '%s'", len(rules), p.path)
+	} else {
+		for _, problem := range status.Errors {
+			log.Error("Placement rules '%s': %s", p.path, problem)
+		}
+		log.Warn("Keeping the previous %d placement rule(s) until '%s' is fixed", status.Rules, p.path)
+	}
+	return status
+}
+
+// watchPlacementRules loads the rule file and reloads it whenever it changes, publishing the
+// load status and re-running window assignment with the new rules.
+func (a *ButtonManagerAdapter) watchPlacementRules() {
+	if a.rules.path == "" {
+		return
+	}
+	statusSubject := os.Getenv("PUBLIC_NATSSUBJECT_BUTTONMANAGER_PLACEMENT_RULES")
+	windowUpdateSubject := os.Getenv("PUBLIC_NATSSUBJECT_WINDOWMANAGER_UPDATE")
+
+	apply := func(initial bool) {
+		status := a.rules.reload()
+		if status == nil {
+			return
+		}
+		if statusSubject != "" {
+			a.natsAdapter.PublishMessage(statusSubject, status)
+		}
+		if status.OK && !initial {
+			a.baseline.invalidate()
+			a.natsAdapter.PublishMessage(windowUpdateSubject, a.state.currentWindows())
+		}
+	}
+
+	apply(true)
+	go func() {
+		ticker := time.NewTicker(placementRulesPollInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			apply(false)
+		}
+	}()
+}
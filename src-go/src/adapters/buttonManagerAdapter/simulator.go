@@ -0,0 +1,114 @@
+package buttonManagerAdapter
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core"
+)
+
+// SimulationStep is the state after one replayed trace step.
+type SimulationStep struct {
+	Index  int
+	Step   TraceStep
+	Config ConfigData
+	// Diff lists the buttons that changed in this step, see DiffConfigs.
+	Diff []string
+}
+
+// Simulate replays a window trace against initial offline, through the same pipeline the
+// worker runs for NATS messages, and calls visit after every step.
+//
+// The simulation uses the default window order and no placement rules or remembered
+// assignments. A config step replaces the buttons without reassigning windows; recorded
+// traces contain the window update the worker republishes after a config change.
+// Every simulation has its own state, so several can run in one process.
+func Simulate(initial ConfigData, steps []TraceStep, visit func(SimulationStep) error) error {
+	a := newOfflineAdapter()
+	a.state.store(initial)
+
+	for i, step := range steps {
+		before := a.state.configCopy()
+		switch step.Type {
+		case TraceStepWindows:
+			a.applyWindowUpdate(step.Windows)
+		case TraceStepConfig:
+			if len(step.Buttons) > 0 {
+				a.state.store(step.Buttons)
+			}
+		case TraceStepFillGaps:
+			a.applyFillGaps()
+		default:
+			return fmt.Errorf("step %d: unknown step type %q", i+1, step.Type)
+		}
+
+		after := a.state.configCopy()
+		if err := visit(SimulationStep{Index: i + 1, Step: step, Config: after, Diff: DiffConfigs(before, after)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newOfflineAdapter returns an adapter without NATS, placement rules or remembered assignments,
+// for replaying inputs outside the worker.
+func newOfflineAdapter() *ButtonManagerAdapter {
+	return &ButtonManagerAdapter{
+		state:      &buttonState{},
+		assignment: newAssignmentState(),
+		sticky:     &stickyAssignments{slots: map[string]windowFingerprint{}},
+		rules:      &placementRules{},
+	}
+}
+
+// DiffConfigs describes the buttons that differ between two configs, one line per button
+// in menu, page and button order: "- 0:1:3 <before>" and "+ 0:1:3 <after>".
+func DiffConfigs(before, after ConfigData) []string {
+	var lines []string
+	for _, change := range diffButtons(before, after) {
+		key := strings.Join([]string{change.MenuID, change.PageID, change.ButtonID}, ":")
+		if old, existed := before[change.MenuID][change.PageID][change.ButtonID]; existed {
+			lines = append(lines, fmt.Sprintf("- %s %s", key, describeButton(old)))
+		}
+		if change.Button != nil {
+			lines = append(lines, fmt.Sprintf("+ %s %s", key, describeButton(*change.Button)))
+		}
+	}
+	return lines
+}
+
+// compareNumericIDs orders menu, page and button IDs numerically, falling back to string order.
+func compareNumericIDs(a, b string) int {
+	ia, errA := strconv.Atoi(a)
+	ib, errB := strconv.Atoi(b)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+	return cmp.Compare(ia, ib)
+}
+
+// describeButton summarizes a button on one line; window buttons show their assignment.
+func describeButton(button Button) string {
+	switch core.ButtonType(button.ButtonType) {
+	case core.ButtonTypeShowAnyWindow:
+		props, err := GetButtonProperties[core.ShowAnyWindowProperties](button)
+		if err == nil {
+			return describeWindowSlot(button.ButtonType, props.WindowHandle, props.ButtonTextUpper, props.ButtonTextLower)
+		}
+	case core.ButtonTypeShowProgramWindow:
+		props, err := GetButtonProperties[core.ShowProgramWindowProperties](button)
+		if err == nil {
+			return describeWindowSlot(button.ButtonType, props.WindowHandle, props.ButtonTextUpper, props.ButtonTextLower)
+		}
+	}
+	return fmt.Sprintf("%s %s", button.ButtonType, string(button.Properties))
+}
+
+func describeWindowSlot(buttonType string, handle int, title, app string) string {
+	if handle == InvalidHandle {
+		return fmt.Sprintf("%s (empty) %q", buttonType, app)
+	}
+	return fmt.Sprintf("%s hwnd=%d %q %q", buttonType, handle, title, app)
+}
@@ -0,0 +1,164 @@
+package buttonManagerAdapter
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testTrace shows a window in a ShowAnyWindow slot and closes it again. The second line is a
+// hand-written bare window update.
+const testTrace = `{"type":"config","buttons":{"0":{"0":{"0":{"button_type":"show_any_window","properties":{"window_handle":-1}},"1":{"button_type":"disabled","properties":{}}}}}}
+
+{"101":{"Title":"Notes","ExeName":"notepad.exe","AppName":"Notepad"}}
+{"type":"windows","time":"2024-01-05T09:07:03Z","windows":{}}
+{"type":"fill_gaps"}
+`
+
+// testTraceDiffs are the DiffConfigs lines of each step of testTrace, replayed from an empty config.
+var testTraceDiffs = [][]string{
+	{`+ 0:0:0 show_any_window (empty) ""`, `+ 0:0:1 disabled {}`},
+	{`- 0:0:0 show_any_window (empty) ""`, `+ 0:0:0 show_any_window hwnd=101 "Notes" "Notepad"`},
+	{`- 0:0:0 show_any_window hwnd=101 "Notes" "Notepad"`, `+ 0:0:0 show_any_window (empty) ""`},
+	nil,
+}
+
+func TestReadTrace(t *testing.T) {
+	steps, err := ReadTrace(strings.NewReader(testTrace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantTypes := []string{TraceStepConfig, TraceStepWindows, TraceStepWindows, TraceStepFillGaps}
+	gotTypes := make([]string, len(steps))
+	for i, step := range steps {
+		gotTypes[i] = step.Type
+	}
+	if !slices.Equal(gotTypes, wantTypes) {
+		t.Fatalf("step types = %v, want %v", gotTypes, wantTypes)
+	}
+	if info, ok := steps[1].Windows[101]; !ok || info.AppName != "Notepad" {
+		t.Errorf("bare window update read as %+v, want window 101 of Notepad", steps[1].Windows)
+	}
+	if steps[2].Time.IsZero() {
+		t.Error("time of a recorded step was not read")
+	}
+}
+
+func TestReadTraceErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		trace   string
+		wantErr string
+	}{
+		{"unknown step type", "{\"type\":\"fill_gaps\"}\n{\"type\":\"resize\"}", `line 2: unknown step type "resize"`},
+		{"not json", "\n{\"101\":", "line 2:"},
+		{"bare object that is no window update", `{"windows":"none"}`, "line 1: not a trace step or window update"},
+		{"step with wrong field types", `{"type":"config","buttons":[]}`, "line 1:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadTrace(strings.NewReader(tt.trace))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ReadTrace error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	steps, err := ReadTrace(strings.NewReader(testTrace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var diffs [][]string
+	err = Simulate(ConfigData{}, steps, func(step SimulationStep) error {
+		diffs = append(diffs, step.Diff)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertTraceDiffs(t, diffs)
+
+	err = Simulate(ConfigData{}, []TraceStep{{Type: "resize"}}, func(SimulationStep) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), `step 1: unknown step type "resize"`) {
+		t.Errorf("Simulate error = %v, want the unknown step type", err)
+	}
+}
+
+func TestSimulationsAreIndependent(t *testing.T) {
+	steps, err := ReadTrace(strings.NewReader(testTrace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A second simulation replays the whole trace while the first one is halfway through
+	var diffs [][]string
+	err = Simulate(ConfigData{}, steps, func(step SimulationStep) error {
+		diffs = append(diffs, step.Diff)
+		if step.Index != 2 {
+			return nil
+		}
+		var nested [][]string
+		if err := Simulate(ConfigData{}, steps, func(step SimulationStep) error {
+			nested = append(nested, step.Diff)
+			return nil
+		}); err != nil {
+			return err
+		}
+		assertTraceDiffs(t, nested)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertTraceDiffs(t, diffs)
+}
+
+func assertTraceDiffs(t *testing.T, diffs [][]string) {
+	t.Helper()
+	if len(diffs) != len(testTraceDiffs) {
+		t.Fatalf("got %d steps, want %d", len(diffs), len(testTraceDiffs))
+	}
+	for i, want := range testTraceDiffs {
+		if !slices.Equal(diffs[i], want) {
+			t.Errorf("step %d diff = %q, want %q", i+1, diffs[i], want)
+		}
+	}
+}
+
+func TestDiffConfigs(t *testing.T) {
+	button := func(buttonType, properties string) Button {
+		return Button{ButtonType: buttonType, Properties: json.RawMessage(properties)}
+	}
+	disabled := button("disabled", "{}")
+	tests := []struct {
+		name   string
+		before ConfigData
+		after  ConfigData
+		want   []string
+	}{
+		{"equal", ConfigData{"0": {"0": {"0": disabled}}}, ConfigData{"0": {"0": {"0": disabled}}}, nil},
+		{"added", ConfigData{}, ConfigData{"0": {"0": {"0": disabled}}}, []string{"+ 0:0:0 disabled {}"}},
+		{"removed", ConfigData{"0": {"0": {"0": disabled}}}, ConfigData{"0": {"0": {}}}, []string{"- 0:0:0 disabled {}"}},
+		{
+			"changed program slot",
+			ConfigData{"1": {"0": {"2": button("show_program_window", `{"window_handle":-1,"button_text_lower":"Notepad"}`)}}},
+			ConfigData{"1": {"0": {"2": button("show_program_window", `{"window_handle":7,"button_text_upper":"a.txt","button_text_lower":"Notepad"}`)}}},
+			[]string{`- 1:0:2 show_program_window (empty) "Notepad"`, `+ 1:0:2 show_program_window hwnd=7 "a.txt" "Notepad"`},
+		},
+		{
+			"numeric order",
+			ConfigData{},
+			ConfigData{"10": {"0": {"0": disabled}}, "2": {"10": {"0": disabled}, "9": {"0": disabled}}},
+			[]string{"+ 2:9:0 disabled {}", "+ 2:10:0 disabled {}", "+ 10:0:0 disabled {}"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiffConfigs(tt.before, tt.after); !slices.Equal(got, tt.want) {
+				t.Errorf("DiffConfigs = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
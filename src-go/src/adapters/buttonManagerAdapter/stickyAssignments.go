@@ -0,0 +1,251 @@
+package buttonManagerAdapter
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core"
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core/jsonUtils"
+)
+
+// windowFingerprint identifies a window across restarts, when its handle is no longer valid.
+type windowFingerprint struct {
+	ExeName  string `json:"exeName"`
+	AppName  string `json:"appName"`
+	Title    string `json:"title"` // normalized, see normalizeTitle
+	Instance int    `json:"instance"`
+	// Program is the ButtonTextLower of a ShowProgramWindow slot; the fingerprint is only
+	// restored while the slot still shows the same program.
+	Program string `json:"program,omitempty"`
+}
+
+// stickyFlushDelay is how long recorded fingerprints wait before they are written. Window updates
+// come in bursts, e.g. while apps start at login, and each would otherwise cost a durable write.
+const stickyFlushDelay = 2 * time.Second
+
+// stickyAssignments remembers which window each ShowAnyWindow and ShowProgramWindow slot showed,
+// keyed by "menuID:pageID:buttonID", and persists it beside the pie menu config.
+// A cleared slot keeps its last fingerprint so windows closed during shutdown still come back.
+type stickyAssignments struct {
+	mu             sync.Mutex
+	path           string
+	slots          map[string]windowFingerprint
+	restorePending bool
+
+	// dirty is set while slots has changes that are not written yet; flushTimer writes them.
+	dirty      bool
+	flushTimer *time.Timer
+	// writeMu keeps flushes in order, so an older snapshot never overwrites a newer one.
+	writeMu sync.Mutex
+}
+
+// loadStickyAssignments reads the fingerprint store. A missing or unreadable file starts empty.
+func loadStickyAssignments() *stickyAssignments {
+	s := &stickyAssignments{slots: map[string]windowFingerprint{}, restorePending: true}
+
+	rel := os.Getenv("PUBLIC_DIR_WINDOWFINGERPRINTS")
+	if rel == "" {
+		log.Warn("PUBLIC_DIR_WINDOWFINGERPRINTS is not set; window assignments will not survive restarts")
+		return s
+	}
+	appDataDir, err := core.GetAppDataDir()
+	if err != nil {
+		log.Warn("Failed to resolve app data dir for window fingerprints: %v", err)
+		return s
+	}
+	s.path = filepath.Join(appDataDir, rel)
+
+	var slots map[string]windowFingerprint
+	if err := jsonUtils.ReadFromFile(s.path, &slots); err != nil {
+		log.Warn("Ignoring unreadable window fingerprints '%s': %v", s.path, err)
+		return s
+	}
+	if slots != nil {
+		s.slots = slots
+	}
+	log.Info("Loaded %d window fingerprint(s) from '%s'", len(s.slots), s.path)
+	return s
+}
+
+// normalizeTitle makes titles comparable across sessions: case, invisible format
+// characters and runs of whitespace are ignored.
+func normalizeTitle(title string) string {
+	return strings.Join(strings.Fields(removeFormatChars(strings.ToLower(title))), " ")
+}
+
+func fingerprintOf(info core.WindowInfo) windowFingerprint {
+	return windowFingerprint{
+		ExeName:  info.ExeName,
+		AppName:  info.AppName,
+		Title:    normalizeTitle(info.Title),
+		Instance: info.Instance,
+	}
+}
+
+// slotWindow returns the window handle and program of a window slot, or ok=false for other button types.
+func slotWindow(button Button) (handle int, program string, ok bool) {
+	switch core.ButtonType(button.ButtonType) {
+	case core.ButtonTypeShowAnyWindow:
+		props, err := GetButtonProperties[core.ShowAnyWindowProperties](button)
+		return props.WindowHandle, "", err == nil
+	case core.ButtonTypeShowProgramWindow:
+		props, err := GetButtonProperties[core.ShowProgramWindowProperties](button)
+		return props.WindowHandle, props.ButtonTextLower, err == nil
+	}
+	return InvalidHandle, "", false
+}
+
+// takeRestore reports whether the restore is still due and marks it done.
+func (s *stickyAssignments) takeRestore() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := s.restorePending
+	s.restorePending = false
+	return pending
+}
+
+// restore puts windows matching a stored fingerprint back into their slot. Matching runs in
+// passes from strict to loose so an exact match is never taken by a weaker one:
+// the full fingerprint, then ignoring the instance number, then ignoring the title.
+func (s *stickyAssignments) restore(config ConfigData, availableWindows core.WindowsUpdate, processedButtons map[string]bool) {
+	s.mu.Lock()
+	slots := maps.Clone(s.slots)
+	s.mu.Unlock()
+	if len(slots) == 0 {
+		return
+	}
+
+	passes := []func(a, b windowFingerprint) bool{
+		func(a, b windowFingerprint) bool { return a == b },
+		func(a, b windowFingerprint) bool {
+			return a.ExeName == b.ExeName && a.AppName == b.AppName && a.Title == b.Title
+		},
+		func(a, b windowFingerprint) bool {
+			return a.ExeName == b.ExeName && a.AppName == b.AppName && a.Instance == b.Instance
+		},
+	}
+
+	keys := slices.Sorted(maps.Keys(slots))
+	handles := slices.Sorted(maps.Keys(availableWindows))
+	restored := 0
+	for _, matches := range passes {
+		for _, key := range keys {
+			if processedButtons[key] {
+				continue
+			}
+			parts := strings.Split(key, ":")
+			if len(parts) != 3 {
+				continue
+			}
+			pageConfig := config[parts[0]][parts[1]]
+			buttonID := parts[2]
+			button, exists := pageConfig[buttonID]
+			if !exists {
+				continue
+			}
+			_, program, ok := slotWindow(button)
+			if !ok || program != slots[key].Program {
+				continue
+			}
+
+			for _, handle := range handles {
+				info, available := availableWindows[handle]
+				if !available {
+					continue
+				}
+				fp := fingerprintOf(info)
+				fp.Program = program
+				if !matches(slots[key], fp) {
+					continue
+				}
+				original := button
+				if err := updateButtonWithWindowInfo(&button, info, handle); err != nil {
+					log.Error("[%s] Failed to restore window assignment: %v", key, err)
+					break
+				}
+				if !reflect.DeepEqual(original, button) {
+					pageConfig[buttonID] = button
+				}
+				delete(availableWindows, handle)
+				processedButtons[key] = true
+				restored++
+				break
+			}
+		}
+	}
+	log.Info("Restored %d of %d remembered window assignment(s)", restored, len(slots))
+}
+
+// record stores the fingerprints of all assigned window slots in config and schedules a write
+// of the store if anything changed. A window that moved to another slot is forgotten in the old one.
+func (s *stickyAssignments) record(config ConfigData, windows core.WindowsUpdate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed := false
+	for menuID, menuConfig := range config {
+		for pageID, pageConfig := range menuConfig {
+			for buttonID, button := range pageConfig {
+				handle, program, ok := slotWindow(button)
+				if !ok || handle == InvalidHandle {
+					continue
+				}
+				info, exists := windows[handle]
+				if !exists {
+					continue
+				}
+				fp := fingerprintOf(info)
+				fp.Program = program
+				key := fmt.Sprintf("%s:%s:%s", menuID, pageID, buttonID)
+				if s.slots[key] == fp {
+					continue
+				}
+				for other, stored := range s.slots {
+					if stored == fp {
+						delete(s.slots, other)
+					}
+				}
+				s.slots[key] = fp
+				changed = true
+			}
+		}
+	}
+
+	if !changed || s.path == "" {
+		return
+	}
+	s.dirty = true
+	if s.flushTimer == nil {
+		s.flushTimer = time.AfterFunc(stickyFlushDelay, s.flush)
+	}
+}
+
+// flush writes the store if it has unwritten changes. It runs after stickyFlushDelay and on shutdown.
+func (s *stickyAssignments) flush() {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.mu.Lock()
+	if s.flushTimer != nil {
+		s.flushTimer.Stop()
+		s.flushTimer = nil
+	}
+	if !s.dirty {
+		s.mu.Unlock()
+		return
+	}
+	s.dirty = false
+	slots := maps.Clone(s.slots)
+	s.mu.Unlock()
+
+	if err := jsonUtils.WriteToFile(s.path, slots); err != nil {
+		log.Error("Failed to write window fingerprints to '%s': %v", s.path, err)
+	}
+}
@@ -43,15 +43,11 @@ type availableWindowInfo struct {
 	Info   core.WindowInfo
 }
 
-// SeparatedButtons holds buttons separated by type for a single page
+// SeparatedButtons holds the window buttons of a single page, separated by type.
+// Buttons of types that don't take part in window assignment are left out.
 type SeparatedButtons struct {
-	ShowProgram      map[string]*Button
-	ShowAny          map[string]*Button
-	LaunchProgram    map[string]*Button
-	FunctionCall     map[string]*Button
-	OpenPageInMenu   map[string]*Button
-	OpenResource     map[string]*Button
-	KeyboardShortcut map[string]*Button
+	ShowProgram map[string]*Button
+	ShowAny     map[string]*Button
 }
 
 // SeparatedButtonsCache holds separated buttons for all menus and pages
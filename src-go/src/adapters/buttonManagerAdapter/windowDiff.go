@@ -0,0 +1,286 @@
+package buttonManagerAdapter
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core"
+)
+
+// windowDelta classifies a window update against the previous one.
+type windowDelta struct {
+	Added    []int
+	Removed  []int
+	Retitled []int // same window; only the title or icon changed
+	Changed  []int // same handle, but the exe, app or instance changed
+}
+
+func (d windowDelta) empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Retitled) == 0 && len(d.Changed) == 0
+}
+
+func diffWindows(prev, next core.WindowsUpdate) windowDelta {
+	var d windowDelta
+	for handle, info := range next {
+		old, existed := prev[handle]
+		switch {
+		case !existed:
+			d.Added = append(d.Added, handle)
+		case old.ExeName != info.ExeName || old.AppName != info.AppName || old.Instance != info.Instance:
+			d.Changed = append(d.Changed, handle)
+		case old.Title != info.Title || old.IconPath != info.IconPath:
+			d.Retitled = append(d.Retitled, handle)
+		}
+	}
+	for handle := range prev {
+		if _, exists := next[handle]; !exists {
+			d.Removed = append(d.Removed, handle)
+		}
+	}
+	slices.Sort(d.Added)
+	slices.Sort(d.Removed)
+	return d
+}
+
+// buttonRef locates a button in a ConfigData.
+type buttonRef struct {
+	MenuID, PageID, ButtonID string
+}
+
+// compareButtonRefs orders buttons by menu, page and button, like the assignment passes.
+func compareButtonRefs(a, b buttonRef) int {
+	if n := compareNumericIDs(a.MenuID, b.MenuID); n != 0 {
+		return n
+	}
+	if n := compareNumericIDs(a.PageID, b.PageID); n != 0 {
+		return n
+	}
+	return compareNumericIDs(a.ButtonID, b.ButtonID)
+}
+
+// windowButtonIndex locates the window buttons of a config by the window they show
+// and, for empty slots, by the window they are waiting for.
+type windowButtonIndex struct {
+	byHandle map[int][]buttonRef
+	// programs holds the program of every ShowProgramWindow button.
+	programs map[buttonRef]string
+	// emptyPrograms holds the empty ShowProgramWindow slots by program, emptyAny the empty
+	// ShowAnyWindow slots. Neither is sorted; see compareButtonRefs.
+	emptyPrograms map[string][]buttonRef
+	emptyAny      []buttonRef
+}
+
+func indexWindowButtons(config ConfigData) windowButtonIndex {
+	index := windowButtonIndex{
+		byHandle:      make(map[int][]buttonRef),
+		programs:      make(map[buttonRef]string),
+		emptyPrograms: make(map[string][]buttonRef),
+	}
+	for menuID, menuConfig := range config {
+		for pageID, pageConfig := range menuConfig {
+			for buttonID, button := range pageConfig {
+				handle, program, ok := slotWindow(button)
+				if !ok {
+					continue
+				}
+				ref := buttonRef{menuID, pageID, buttonID}
+				if core.ButtonType(button.ButtonType) == core.ButtonTypeShowProgramWindow {
+					index.programs[ref] = program
+				}
+				if handle != InvalidHandle {
+					index.byHandle[handle] = append(index.byHandle[handle], ref)
+				} else {
+					index.addEmpty(ref)
+				}
+			}
+		}
+	}
+	return index
+}
+
+func (x *windowButtonIndex) isProgram(ref buttonRef) bool {
+	_, ok := x.programs[ref]
+	return ok
+}
+
+// addEmpty files ref under the empty slots of its type.
+func (x *windowButtonIndex) addEmpty(ref buttonRef) {
+	if program, ok := x.programs[ref]; ok {
+		x.emptyPrograms[program] = append(x.emptyPrograms[program], ref)
+	} else {
+		x.emptyAny = append(x.emptyAny, ref)
+	}
+}
+
+// windowBaseline is the window list the live button config was last computed from.
+// It is only valid while the config is still at the recorded revision.
+type windowBaseline struct {
+	mu       sync.Mutex
+	windows  core.WindowsUpdate
+	revision int
+	valid    bool
+}
+
+// previous returns the baseline windows if the config is still at revision.
+func (b *windowBaseline) previous(revision int) (core.WindowsUpdate, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.windows, b.valid && b.revision == revision
+}
+
+func (b *windowBaseline) remember(windows core.WindowsUpdate, revision int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.windows, b.revision, b.valid = windows, revision, true
+}
+
+// invalidate forces the next window update through the full pipeline,
+// e.g. after the placement rules changed.
+func (b *windowBaseline) invalidate() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.valid = false
+}
+
+// applyWindowChanges updates config in place for a window update, touching just the buttons the
+// changed windows affect. config must be the full pipeline's result for prev; updates that could
+// move a window the full pipeline would reassign are left to it.
+// It returns ok=false if the update needs the full pipeline, and a nil config if no window changed.
+func (a *ButtonManagerAdapter) applyWindowChanges(config ConfigData, prev, next core.WindowsUpdate) (ConfigData, bool) {
+	delta := diffWindows(prev, next)
+	if len(delta.Changed) > 0 {
+		return nil, false
+	}
+	if delta.empty() {
+		return nil, true
+	}
+	// Rules may forbid, pin or prefer windows anywhere in the config
+	if a.rules.active() {
+		return nil, false
+	}
+	// Edge PWAs are matched to buttons by title and reassigned on every update
+	for _, handle := range slices.Concat(delta.Retitled, delta.Added) {
+		if isEdgeWindow(next[handle]) {
+			return nil, false
+		}
+	}
+	for _, handle := range delta.Removed {
+		if isEdgeWindow(prev[handle]) {
+			return nil, false
+		}
+	}
+
+	index := indexWindowButtons(config)
+	// The full pipeline clears and refills program slots showing a window of another app
+	for handle, refs := range index.byHandle {
+		info, exists := next[handle]
+		for _, ref := range refs {
+			if program, ok := index.programs[ref]; ok && exists && info.AppName != program {
+				return nil, false
+			}
+		}
+	}
+
+	for _, handle := range delta.Retitled {
+		for _, ref := range index.byHandle[handle] {
+			if !updateWindowButton(config, ref, func(button *Button) error {
+				return updateButtonWithWindowInfo(button, next[handle], handle)
+			}) {
+				return nil, false
+			}
+		}
+	}
+
+	freedPrograms := make(map[string]bool)
+	for _, handle := range delta.Removed {
+		for _, ref := range index.byHandle[handle] {
+			if !updateWindowButton(config, ref, clearButtonWindowProperties) {
+				return nil, false
+			}
+			if program, ok := index.programs[ref]; ok {
+				freedPrograms[program] = true
+			}
+			index.addEmpty(ref)
+		}
+		delete(index.byHandle, handle)
+	}
+
+	// Windows no button shows compete for the empty slots, as in the full pipeline
+	available := make(core.WindowsUpdate)
+	for handle, info := range next {
+		shownBy := index.byHandle[handle]
+		if len(shownBy) == 0 {
+			available[handle] = info
+		}
+		if len(freedPrograms) == 0 || slices.ContainsFunc(shownBy, index.isProgram) {
+			continue
+		}
+		// A freed program slot also takes its program's windows from ShowAnyWindow slots,
+		// and Edge windows by title
+		if isEdgeWindow(info) || (len(shownBy) > 0 && freedPrograms[info.AppName]) {
+			return nil, false
+		}
+	}
+
+	// Program slots first, each taking the lowest handle of its program
+	byProgram := make(map[string][]int)
+	for _, handle := range slices.Sorted(maps.Keys(available)) {
+		if info := available[handle]; !isEdgeWindow(info) {
+			byProgram[info.AppName] = append(byProgram[info.AppName], handle)
+		}
+	}
+	for program, handles := range byProgram {
+		slots := slices.SortedFunc(slices.Values(index.emptyPrograms[program]), compareButtonRefs)
+		for i, ref := range slots[:min(len(slots), len(handles))] {
+			handle := handles[i]
+			if !updateWindowButton(config, ref, func(button *Button) error {
+				return updateButtonWithWindowInfo(button, available[handle], handle)
+			}) {
+				return nil, false
+			}
+			delete(available, handle)
+		}
+	}
+
+	if len(available) == 0 {
+		return config, true
+	}
+
+	// Then the empty ShowAnyWindow slots, through the same pass as the full pipeline
+	var slots []availableSlotInfo
+	for _, ref := range slices.SortedFunc(slices.Values(index.emptyAny), compareButtonRefs) {
+		if slot, ok := newAvailableSlot(ref); ok {
+			slots = append(slots, slot)
+		}
+	}
+	// Without rules every slot takes the next window; the slots after them are empty already
+	a.fillWindowSlots(slots[:min(len(slots), len(available))], available, make(map[string]bool), config)
+	return config, true
+}
+
+// newAvailableSlot describes ref for the ShowAnyWindow pass, which only fills numeric IDs.
+func newAvailableSlot(ref buttonRef) (availableSlotInfo, bool) {
+	menuIdx, errM := strconv.Atoi(ref.MenuID)
+	pageIdx, errP := strconv.Atoi(ref.PageID)
+	buttonIdx, errB := strconv.Atoi(ref.ButtonID)
+	if errM != nil || errP != nil || errB != nil {
+		return availableSlotInfo{}, false
+	}
+	return availableSlotInfo{
+		MenuID: ref.MenuID, PageID: ref.PageID, ButtonID: ref.ButtonID,
+		MenuIdx: menuIdx, PageIdx: pageIdx, ButtonIdx: buttonIdx,
+	}, true
+}
+
+// updateWindowButton applies update to the button at ref. It logs and returns false on failure.
+func updateWindowButton(config ConfigData, ref buttonRef, update func(*Button) error) bool {
+	button := config[ref.MenuID][ref.PageID][ref.ButtonID]
+	if err := update(&button); err != nil {
+		log.Error("[%s:%s:%s] Failed to apply window change: %v", ref.MenuID, ref.PageID, ref.ButtonID, err)
+		return false
+	}
+	config[ref.MenuID][ref.PageID][ref.ButtonID] = button
+	return true
+}
@@ -0,0 +1,219 @@
+package buttonManagerAdapter
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core"
+)
+
+const (
+	benchMenus          = 50
+	benchPagesPerMenu   = 10
+	benchButtonsPerPage = 8
+	benchWindows        = 200
+	benchApps           = 20
+)
+
+// benchSetup builds a large config whose window buttons already show benchWindows windows,
+// stored in a as the live config, and returns it with its window list. Every app has more
+// program slots than windows, so all windows are in program slots and every ShowAnyWindow
+// slot is empty.
+func benchSetup(tb testing.TB) (*ButtonManagerAdapter, ConfigData, core.WindowsUpdate) {
+	tb.Helper()
+	anyWindow := benchButton(tb, core.ButtonTypeShowAnyWindow, core.ShowAnyWindowProperties{WindowHandle: InvalidHandle})
+	disabled := benchButton(tb, core.ButtonTypeDisabled, struct{}{})
+
+	config := make(ConfigData, benchMenus)
+	for menu := range benchMenus {
+		menuConfig := make(MenuConfig, benchPagesPerMenu)
+		for page := range benchPagesPerMenu {
+			pageConfig := make(PageConfig, benchButtonsPerPage)
+			for button := range benchButtonsPerPage {
+				switch button % 4 {
+				case 0, 1:
+					pageConfig[strconv.Itoa(button)] = anyWindow
+				case 2:
+					app := (menu*benchPagesPerMenu + page) % benchApps
+					pageConfig[strconv.Itoa(button)] = benchButton(tb, core.ButtonTypeShowProgramWindow,
+						core.ShowProgramWindowProperties{ButtonTextLower: benchAppName(app), WindowHandle: InvalidHandle})
+				default:
+					pageConfig[strconv.Itoa(button)] = disabled
+				}
+			}
+			menuConfig[strconv.Itoa(page)] = pageConfig
+		}
+		config[strconv.Itoa(menu)] = menuConfig
+	}
+
+	windows := make(core.WindowsUpdate, benchWindows)
+	for i := range benchWindows {
+		windows[1000+i] = benchWindow(i%benchApps, i/benchApps)
+	}
+
+	// Store the config first: the pipeline reads the window buttons from the live config's cache
+	a := newOfflineAdapter()
+	a.state.store(config)
+	if a.applyWindowUpdate(windows) == nil {
+		tb.Fatal("initial assignment changed nothing")
+	}
+	assigned := a.state.configCopy()
+	if refs := indexWindowButtons(assigned).byHandle[1000]; len(refs) != 1 {
+		tb.Fatalf("window 1000 is shown by %v, want one button", refs)
+	}
+	return a, assigned, windows
+}
+
+func benchButton(tb testing.TB, buttonType core.ButtonType, properties any) Button {
+	tb.Helper()
+	raw, err := json.Marshal(properties)
+	if err != nil {
+		tb.Fatalf("failed to encode properties: %v", err)
+	}
+	return Button{ButtonType: string(buttonType), Properties: raw}
+}
+
+func benchAppName(app int) string {
+	return fmt.Sprintf("App %d", app)
+}
+
+func benchWindow(app, instance int) core.WindowInfo {
+	return core.WindowInfo{
+		Title:    fmt.Sprintf("Document %d", app+instance*benchApps),
+		ExeName:  fmt.Sprintf("app%d.exe", app),
+		AppName:  benchAppName(app),
+		Instance: instance,
+	}
+}
+
+// benchUpdates are the window updates the benchmarks and TestWindowChangesMatchFull apply
+// to the window list of benchSetup.
+var benchUpdates = []struct {
+	name   string
+	modify func(windows core.WindowsUpdate)
+}{
+	{"retitled", func(windows core.WindowsUpdate) {
+		info := windows[1000]
+		info.Title = "Document 0 (edited)"
+		windows[1000] = info
+	}},
+	{"added to a program slot", func(windows core.WindowsUpdate) {
+		windows[5000] = benchWindow(3, benchWindows/benchApps)
+	}},
+	{"added without a program slot", func(windows core.WindowsUpdate) {
+		windows[5000] = core.WindowInfo{Title: "Untitled", ExeName: "other.exe", AppName: "Other"}
+		windows[5001] = core.WindowInfo{Title: "Untitled 2", ExeName: "other.exe", AppName: "Other", Instance: 1}
+	}},
+	{"removed", func(windows core.WindowsUpdate) {
+		delete(windows, 1000)
+	}},
+	{"removed and added", func(windows core.WindowsUpdate) {
+		delete(windows, 1000)
+		windows[5000] = benchWindow(0, benchWindows/benchApps)
+	}},
+}
+
+func TestWindowChangesMatchFull(t *testing.T) {
+	for _, tt := range benchUpdates {
+		t.Run(tt.name, func(t *testing.T) {
+			a, config, windows := benchSetup(t)
+			next := maps.Clone(windows)
+			tt.modify(next)
+			a.assignment.recordWindows(slices.Collect(maps.Keys(next)))
+
+			full, err := a.processWindowUpdate(config, next)
+			if err != nil || full == nil {
+				t.Fatalf("full pipeline returned %v, %v; want a changed config", full, err)
+			}
+			snapshot, err := deepCopyConfig(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			incremental, handled := a.applyWindowChanges(snapshot, windows, next)
+			if !handled {
+				t.Fatal("update was not handled incrementally")
+			}
+			if diff := DiffConfigs(full, incremental); len(diff) > 0 {
+				t.Errorf("incremental result differs from the full pipeline:\n%s", strings.Join(diff, "\n"))
+			}
+		})
+	}
+}
+
+func TestWindowChangesFallBack(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(a *ButtonManagerAdapter, config ConfigData, windows core.WindowsUpdate)
+	}{
+		{"window changed app", func(_ *ButtonManagerAdapter, _ ConfigData, windows core.WindowsUpdate) {
+			info := windows[1000]
+			info.AppName = benchAppName(1)
+			windows[1000] = info
+		}},
+		{"edge window added", func(_ *ButtonManagerAdapter, _ ConfigData, windows core.WindowsUpdate) {
+			windows[5000] = core.WindowInfo{Title: "New tab", ExeName: "msedge.exe", AppName: "Microsoft Edge"}
+		}},
+		{"freed program slot wanted by a ShowAnyWindow window", func(_ *ButtonManagerAdapter, config ConfigData, windows core.WindowsUpdate) {
+			// Another window of the closed window's app sits in a ShowAnyWindow slot
+			button := config["0"]["0"]["0"]
+			if err := updateButtonWithWindowInfo(&button, benchWindow(0, 99), 5000); err != nil {
+				panic(err)
+			}
+			config["0"]["0"]["0"] = button
+			delete(windows, 1000)
+			windows[5000] = benchWindow(0, 99)
+		}},
+		{"placement rules", func(a *ButtonManagerAdapter, _ ConfigData, windows core.WindowsUpdate) {
+			a.rules.rules = []compiledRule{{}}
+			delete(windows, 1000)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, config, windows := benchSetup(t)
+			next := maps.Clone(windows)
+			tt.modify(a, config, next)
+			if _, handled := a.applyWindowChanges(config, windows, next); handled {
+				t.Fatal("update was handled incrementally, want the full pipeline")
+			}
+		})
+	}
+}
+
+// The benchmarks copy the config first, like the snapshot applyWindowUpdate starts from.
+
+func BenchmarkWindowUpdate(b *testing.B) {
+	for _, tt := range benchUpdates {
+		a, config, windows := benchSetup(b)
+		next := maps.Clone(windows)
+		tt.modify(next)
+
+		b.Run(tt.name+"/full", func(b *testing.B) {
+			for b.Loop() {
+				snapshot, err := deepCopyConfig(config)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := a.processWindowUpdate(snapshot, next); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(tt.name+"/incremental", func(b *testing.B) {
+			for b.Loop() {
+				snapshot, err := deepCopyConfig(config)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, handled := a.applyWindowChanges(snapshot, windows, next); !handled {
+					b.Fatal("update was not handled incrementally")
+				}
+			}
+		})
+	}
+}
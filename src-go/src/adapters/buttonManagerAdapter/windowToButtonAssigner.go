@@ -2,7 +2,9 @@ package buttonManagerAdapter
 
 import (
 	"fmt"
+	"maps"
 	"reflect"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -46,7 +48,8 @@ func (a *ButtonManagerAdapter) processExistingShowProgramHandles(
 		buttonModified := false
 		if props.WindowHandle > 0 {
 			if winInfo, exists := availableWindows[props.WindowHandle]; exists {
-				if winInfo.AppName == props.ButtonTextLower {
+				if winInfo.AppName == props.ButtonTextLower &&
+					a.placementAllowed(winInfo, core.ButtonTypeShowProgramWindow, menuID, pageID, btnID) {
 					originalButton := buttonCopy
 					if err := updateButtonWithWindowInfo(&buttonCopy, winInfo, props.WindowHandle); err != nil {
 						log.Error("[%s] Failed update ShowProgram button: %v", buttonKey, err)
@@ -57,6 +60,7 @@ func (a *ButtonManagerAdapter) processExistingShowProgramHandles(
 					processedButtons[buttonKey] = true
 				} else {
 					originalButton := buttonCopy
+					// Wrong program, or a placement rule no longer allows the window here
 					if err := clearButtonWindowProperties(&buttonCopy); err != nil {
 						log.Error("[%s] Failed clear ShowProgram on mismatch: %v", buttonKey, err)
 					} else if !reflect.DeepEqual(originalButton, buttonCopy) {
@@ -107,7 +111,8 @@ func (a *ButtonManagerAdapter) processExistingShowAnyHandles(
 
 		buttonModified := false
 		if props.WindowHandle != InvalidHandle {
-			if winInfo, exists := availableWindows[props.WindowHandle]; exists {
+			winInfo, exists := availableWindows[props.WindowHandle]
+			if exists && a.placementAllowed(winInfo, core.ButtonTypeShowAnyWindow, menuID, pageID, btnID) {
 				originalButton := buttonCopy
 				if err := updateButtonWithWindowInfo(&buttonCopy, winInfo, props.WindowHandle); err != nil {
 					log.Error("[%s] Failed update ShowAny button: %v", buttonKey, err)
@@ -150,11 +155,26 @@ func (a *ButtonManagerAdapter) assignMatchingProgramWindows(
 
 	windowsConsumed := make(map[int]bool)
 
-	for pID, mConfig := range fullUpdatedConfig {
+	// Index the windows once: by app name for regular programs, plus every Edge window,
+	// since Edge PWAs match buttons by title rather than app name.
+	windowsByApp := make(map[string][]int)
+	var edgeWindows []int
+	for _, handle := range slices.Sorted(maps.Keys(availableWindows)) {
+		if info := availableWindows[handle]; isEdgeWindow(info) {
+			edgeWindows = append(edgeWindows, handle)
+		} else {
+			windowsByApp[info.AppName] = append(windowsByApp[info.AppName], handle)
+		}
+	}
+
+	// Menus and pages in order too, so the same window always lands in the same slot
+	for _, pID := range slices.SortedFunc(maps.Keys(fullUpdatedConfig), compareNumericIDs) {
+		mConfig := fullUpdatedConfig[pID]
 		if mConfig == nil {
 			continue
 		}
-		for mID, bMap := range mConfig {
+		for _, mID := range slices.SortedFunc(maps.Keys(mConfig), compareNumericIDs) {
+			bMap := mConfig[mID]
 			if bMap == nil {
 				continue
 			}
@@ -190,17 +210,32 @@ func (a *ButtonManagerAdapter) assignMatchingProgramWindows(
 
 					isEdgeButton := strings.Contains(strings.ToLower(props.ButtonTextLower), "edge")
 
-					for handle, winInfo := range availableWindows {
-						if windowsConsumed[handle] {
+					// Windows a placement rule prefers for this button are considered first
+					preferred := make(map[int]bool)
+					var candidates, others []int
+					for _, handle := range slices.Concat(windowsByApp[props.ButtonTextLower], edgeWindows) {
+						if a.placementPreferred(availableWindows[handle], core.ButtonTypeShowProgramWindow, pID, mID, bID) {
+							preferred[handle] = true
+							candidates = append(candidates, handle)
+						} else {
+							others = append(others, handle)
+						}
+					}
+					candidates = append(candidates, others...)
+
+					for _, handle := range candidates {
+						winInfo := availableWindows[handle]
+						if windowsConsumed[handle] ||
+							!a.placementAllowed(winInfo, core.ButtonTypeShowProgramWindow, pID, mID, bID) {
 							continue
 						}
-						isEdgeWindow := winInfo.ExeName == "msedge.exe" || winInfo.AppName == "Microsoft Edge"
+						isEdge := isEdgeWindow(winInfo)
 						// Before sanitizing/removing app name, decide if it's a PWA.
 						rawTitleLower := strings.ToLower(winInfo.Title)
 						rawTitleLower = removeFormatChars(rawTitleLower) // removes Cf chars like zero-width space
 						// optionally normalize whitespace further:
 						rawTitleLower = strings.Join(strings.Fields(rawTitleLower), " ")
-						isEdgePWA := isEdgeWindow && !strings.Contains(rawTitleLower, "microsoft edge")
+						isEdgePWA := isEdge && !strings.Contains(rawTitleLower, "microsoft edge")
 
 						// Exception: certain Edge PWA windows (e.g., Disney+) include a '|' separator and app name.
 						// If we detect both, treat it as a PWA regardless of the generic title heuristic.
@@ -208,7 +243,7 @@ func (a *ButtonManagerAdapter) assignMatchingProgramWindows(
 							isEdgePWA = true
 						}
 
-						if isEdgeWindow {
+						if isEdge {
 							// Match Edge PWA by checking if button text appears anywhere in the window title
 							titleLower := removeFormatChars(strings.ToLower(winInfo.Title))
 							appLower := removeFormatChars(strings.ToLower(winInfo.AppName))
@@ -223,6 +258,9 @@ func (a *ButtonManagerAdapter) assignMatchingProgramWindows(
 								foundHandle = handle
 								foundWinInfo = winInfo
 							}
+							if foundHandle == handle && preferred[handle] {
+								break
+							}
 
 						} else {
 							if winInfo.AppName == props.ButtonTextLower {
@@ -261,6 +299,11 @@ func (a *ButtonManagerAdapter) assignMatchingProgramWindows(
 	}
 }
 
+// isEdgeWindow reports whether a window belongs to Microsoft Edge, including its PWAs.
+func isEdgeWindow(info core.WindowInfo) bool {
+	return info.ExeName == "msedge.exe" || info.AppName == "Microsoft Edge"
+}
+
 // assignRemainingWindows (Cleaned)
 func (a *ButtonManagerAdapter) assignRemainingWindows(
 	availableWindows core.WindowsUpdate,
@@ -325,60 +368,96 @@ func (a *ButtonManagerAdapter) assignRemainingWindows(
 		}
 		return availableSlots[i].ButtonIdx < availableSlots[j].ButtonIdx
 	})
+	a.fillWindowSlots(availableSlots, availableWindows, processedButtons, fullUpdatedConfig)
+}
 
-	var windowsToAssign []availableWindowInfo
+// fillWindowSlots assigns availableWindows to the empty ShowAnyWindow slots, which are sorted by
+// menu, page and button, and clears the slots left over.
+func (a *ButtonManagerAdapter) fillWindowSlots(
+	availableSlots []availableSlotInfo,
+	availableWindows core.WindowsUpdate,
+	processedButtons map[string]bool,
+	fullUpdatedConfig ConfigData,
+) {
+	var windowPool []availableWindowInfo
 	for handle, info := range availableWindows {
-		windowsToAssign = append(windowsToAssign, availableWindowInfo{Handle: handle, Info: info})
+		windowPool = append(windowPool, availableWindowInfo{Handle: handle, Info: info})
 	}
-	sort.Slice(windowsToAssign, func(i, j int) bool { return windowsToAssign[i].Handle < windowsToAssign[j].Handle })
-
-	assignedCount := 0
-	windowsConsumed := make(map[int]bool)
-
-	for i := 0; i < len(availableSlots) && assignedCount < len(windowsToAssign); i++ {
-		slot := availableSlots[i]
-		window := windowsToAssign[assignedCount]
-		slotButtonKey := fmt.Sprintf("%s:%s:%s", slot.MenuID, slot.PageID, slot.ButtonID)
-
-		targetButtonMap := fullUpdatedConfig[slot.MenuID][slot.PageID]
-		buttonToModify := targetButtonMap[slot.ButtonID]
-		originalButton := buttonToModify
-
-		err := updateButtonWithWindowInfo(&buttonToModify, window.Info, window.Handle)
-		if err != nil {
-			log.Error("[%s] Failed update button with assigned window: %v", slotButtonKey, err)
-			continue
-		}
 
-		if !reflect.DeepEqual(originalButton, buttonToModify) {
-			targetButtonMap[slot.ButtonID] = buttonToModify
+	// Menus are filled in order, each with its own strategy, from the windows the previous
+	// menus left over. Within a menu, windows go to pages and buttons in ascending order.
+	history := a.assignment.historySnapshot()
+	var unfilledSlots []availableSlotInfo
+	for start := 0; start < len(availableSlots); {
+		end := start
+		for end < len(availableSlots) && availableSlots[end].MenuID == availableSlots[start].MenuID {
+			end++
 		}
+		menuSlots := availableSlots[start:end]
+		start = end
 
-		windowsConsumed[window.Handle] = true
-		processedButtons[slotButtonKey] = true
-		assignedCount++
-	}
-
-	for handle := range windowsConsumed {
-		delete(availableWindows, handle)
-	}
+		a.assignment.strategyForMenu(menuSlots[0].MenuID).Order(windowPool, history)
 
-	// Clear remaining slots
-	if assignedCount < len(availableSlots) {
-		for i := assignedCount; i < len(availableSlots); i++ {
-			slot := availableSlots[i]
+		for _, slot := range menuSlots {
+			pick := a.pickWindowForSlot(windowPool, slot)
+			if pick < 0 {
+				unfilledSlots = append(unfilledSlots, slot)
+				continue
+			}
+			window := windowPool[pick]
 			slotButtonKey := fmt.Sprintf("%s:%s:%s", slot.MenuID, slot.PageID, slot.ButtonID)
+
 			targetButtonMap := fullUpdatedConfig[slot.MenuID][slot.PageID]
 			buttonToModify := targetButtonMap[slot.ButtonID]
 			originalButton := buttonToModify
-			err := clearButtonWindowProperties(&buttonToModify)
+
+			err := updateButtonWithWindowInfo(&buttonToModify, window.Info, window.Handle)
 			if err != nil {
-				log.Error("[%s] Failed to clear remaining empty slot: %v", slotButtonKey, err)
-			} else if !reflect.DeepEqual(originalButton, buttonToModify) {
+				log.Error("[%s] Failed update button with assigned window: %v", slotButtonKey, err)
+				continue
+			}
+
+			if !reflect.DeepEqual(originalButton, buttonToModify) {
 				targetButtonMap[slot.ButtonID] = buttonToModify
 			}
+
+			delete(availableWindows, window.Handle)
+			processedButtons[slotButtonKey] = true
+			windowPool = slices.Delete(windowPool, pick, pick+1)
+		}
+	}
+
+	// Clear remaining slots
+	for _, slot := range unfilledSlots {
+		slotButtonKey := fmt.Sprintf("%s:%s:%s", slot.MenuID, slot.PageID, slot.ButtonID)
+		targetButtonMap := fullUpdatedConfig[slot.MenuID][slot.PageID]
+		buttonToModify := targetButtonMap[slot.ButtonID]
+		originalButton := buttonToModify
+		err := clearButtonWindowProperties(&buttonToModify)
+		if err != nil {
+			log.Error("[%s] Failed to clear remaining empty slot: %v", slotButtonKey, err)
+		} else if !reflect.DeepEqual(originalButton, buttonToModify) {
+			targetButtonMap[slot.ButtonID] = buttonToModify
+		}
+	}
+}
+
+// pickWindowForSlot returns the index of the window in pool that goes to slot, or -1:
+// the first window a placement rule prefers for the slot, else the first one allowed there.
+func (a *ButtonManagerAdapter) pickWindowForSlot(pool []availableWindowInfo, slot availableSlotInfo) int {
+	fallback := -1
+	for i, window := range pool {
+		if !a.placementAllowed(window.Info, core.ButtonTypeShowAnyWindow, slot.MenuID, slot.PageID, slot.ButtonID) {
+			continue
+		}
+		if a.placementPreferred(window.Info, core.ButtonTypeShowAnyWindow, slot.MenuID, slot.PageID, slot.ButtonID) {
+			return i
+		}
+		if fallback < 0 {
+			fallback = i
 		}
 	}
+	return fallback
 }
 
 // updateButtonWithWindowInfo (Cleaned)
@@ -392,7 +471,7 @@ func updateButtonWithWindowInfo(button *Button, winInfo core.WindowInfo, newHand
 		props.WindowHandle = newHandle
 		props.Instance = winInfo.Instance
 
-		isEdge := winInfo.ExeName == "msedge.exe" || winInfo.AppName == "Microsoft Edge"
+		isEdge := isEdgeWindow(winInfo)
 		if isEdge {
 			// For Edge windows, set ButtonTextUpper to window title but keep ButtonTextLower unchanged
 			props.ButtonTextUpper = winInfo.Title
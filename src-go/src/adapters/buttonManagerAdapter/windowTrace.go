@@ -0,0 +1,133 @@
+package buttonManagerAdapter
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core"
+)
+
+// Trace step types, one per input the assignment pipeline reacts to.
+const (
+	TraceStepWindows  = "windows"   // a window update from the window manager
+	TraceStepConfig   = "config"    // a full button config from PieMenuConfigManager
+	TraceStepFillGaps = "fill_gaps" // a gap-filling request
+)
+
+// TraceStep is one line of a window trace (JSON lines). Lines without a type are read as a
+// bare core.WindowsUpdate, so traces can also be written by hand.
+type TraceStep struct {
+	Time    time.Time          `json:"time,omitzero"`
+	Type    string             `json:"type"`
+	Windows core.WindowsUpdate `json:"windows,omitempty"`
+	Buttons ConfigData         `json:"buttons,omitempty"`
+}
+
+// ReadTrace parses a JSON-lines trace. Empty lines are skipped.
+func ReadTrace(r io.Reader) ([]TraceStep, error) {
+	var steps []TraceStep
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		data := scanner.Bytes()
+		if len(data) == 0 {
+			continue
+		}
+		var probe struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(data, &probe); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		var step TraceStep
+		switch probe.Type {
+		case "":
+			step.Type = TraceStepWindows
+			if err := json.Unmarshal(data, &step.Windows); err != nil {
+				return nil, fmt.Errorf("line %d: not a trace step or window update: %w", line, err)
+			}
+		case TraceStepWindows, TraceStepConfig, TraceStepFillGaps:
+			if err := json.Unmarshal(data, &step); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		default:
+			return nil, fmt.Errorf("line %d: unknown step type %q", line, probe.Type)
+		}
+		steps = append(steps, step)
+	}
+	return steps, scanner.Err()
+}
+
+// traceRecorder appends the button manager's inputs to the trace file while the
+// recordWindowTrace setting is on.
+type traceRecorder struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+func newTraceRecorder() *traceRecorder {
+	t := &traceRecorder{}
+	rel := os.Getenv("PUBLIC_DIR_WINDOWTRACE")
+	if rel == "" {
+		return t
+	}
+	appDataDir, err := core.GetAppDataDir()
+	if err != nil {
+		log.Warn("Failed to resolve app data dir for the window trace: %v", err)
+		return t
+	}
+	t.path = filepath.Join(appDataDir, rel)
+	return t
+}
+
+// setEnabled opens or closes the trace file.
+func (t *traceRecorder) setEnabled(enabled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if enabled == (t.file != nil) {
+		return
+	}
+	if !enabled {
+		t.file.Close()
+		t.file = nil
+		log.Info("Stopped recording window trace")
+		return
+	}
+	if t.path == "" {
+		log.Warn("Cannot record a window trace: PUBLIC_DIR_WINDOWTRACE is not set")
+		return
+	}
+	file, err := os.OpenFile(t.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		log.Error("Failed to open window trace '%s': %v", t.path, err)
+		return
+	}
+	t.file = file
+	log.Info("Recording window trace to '%s'", t.path)
+}
+
+// record appends a step if recording is on.
+func (t *traceRecorder) record(step TraceStep) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.file == nil {
+		return
+	}
+	step.Time = time.Now()
+	data, err := json.Marshal(step)
+	if err != nil {
+		log.Error("Failed to encode window trace step: %v", err)
+		return
+	}
+	if _, err := t.file.Write(append(data, '\n')); err != nil {
+		log.Error("Failed to write window trace: %v", err)
+	}
+}
@@ -1,8 +1,6 @@
 package buttonManagerAdapter
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
 	"maps"
 	"reflect"
@@ -32,6 +30,11 @@ func (a *ButtonManagerAdapter) processWindowUpdate(currentConfig ConfigData, win
 	maps.Copy(availableWindows, windows)
 	processedButtons := make(map[string]bool)
 
+	// After a restart, put remembered windows back into their slots before anything else claims them.
+	if a.sticky.takeRestore() {
+		a.sticky.restore(updatedConfig, availableWindows, processedButtons)
+	}
+
 	// First: run processExistingShowProgramHandles for all pages
 	for menuID, menuConfig := range updatedConfig {
 		if menuConfig == nil {
@@ -42,14 +45,15 @@ func (a *ButtonManagerAdapter) processWindowUpdate(currentConfig ConfigData, win
 				continue
 			}
 			// Use cached separated buttons instead of re-separating
-			separated := getSeparatedButtons(menuID, pageID)
+			separated := a.state.separatedButtons(menuID, pageID)
 			if separated == nil {
 				continue
 			}
 			a.processExistingShowProgramHandles(menuID, pageID, separated.ShowProgram, availableWindows, processedButtons, pageConfig)
 		}
 	}
-	// Then: run assignMatchingProgramWindows and processExistingShowAnyHandles for all pages
+	// Then: match program windows across the whole config once, and keep existing ShowAny handles
+	a.assignMatchingProgramWindows(availableWindows, processedButtons, updatedConfig)
 	for menuID, menuConfig := range updatedConfig {
 		if menuConfig == nil {
 			continue
@@ -59,26 +63,17 @@ func (a *ButtonManagerAdapter) processWindowUpdate(currentConfig ConfigData, win
 				continue
 			}
 			// Use cached separated buttons instead of re-separating
-			separated := getSeparatedButtons(menuID, pageID)
+			separated := a.state.separatedButtons(menuID, pageID)
 			if separated == nil {
 				continue
 			}
-			a.assignMatchingProgramWindows(availableWindows, processedButtons, updatedConfig)
 			a.processExistingShowAnyHandles(menuID, pageID, separated.ShowAny, availableWindows, processedButtons, pageConfig)
 		}
 	}
 
 	a.assignRemainingWindows(availableWindows, processedButtons, updatedConfig)
 
-	jsonSnapshotFinal, errSnapFinal := json.Marshal(currentConfig)
-	jsonUpdatedFinal, errUpdateFinal := json.Marshal(updatedConfig)
-
-	if errSnapFinal != nil || errUpdateFinal != nil {
-		log.Error("Failed to marshal for final comparison (SnapErr: %v, UpdateErr: %v)", errSnapFinal, errUpdateFinal)
-		return nil, fmt.Errorf("final marshal error")
-	}
-
-	if bytes.Equal(jsonSnapshotFinal, jsonUpdatedFinal) {
+	if len(diffButtons(currentConfig, updatedConfig)) == 0 {
 		return nil, nil
 	}
 
@@ -94,13 +89,7 @@ func (a *ButtonManagerAdapter) handleEmptyWindowListAndCompare(currentConfig, up
 	}
 
 	// Compare original with potentially modified config
-	jsonSnapshot, errSnap := json.Marshal(currentConfig)
-	jsonAfterClear, errClear := json.Marshal(updatedConfig)
-	if errSnap != nil || errClear != nil {
-		log.Error("Failed to marshal for empty list comparison (SnapErr: %v, ClearErr: %v)", errSnap, errClear)
-		return nil, fmt.Errorf("marshal error during empty list check")
-	}
-	if bytes.Equal(jsonSnapshot, jsonAfterClear) {
+	if len(diffButtons(currentConfig, updatedConfig)) == 0 {
 		return nil, nil
 	}
 	return updatedConfig, nil
@@ -127,36 +116,27 @@ func buildSeparatedButtonsCache(config ConfigData) SeparatedButtonsCache {
 	return cache
 }
 
-// separateButtonsByType separates buttons by type for a single page
+// separateButtonsByType separates the window buttons of a single page by type
 func separateButtonsByType(pageConfig PageConfig) *SeparatedButtons {
 	separated := &SeparatedButtons{
-		ShowProgram:      make(map[string]*Button),
-		ShowAny:          make(map[string]*Button),
-		LaunchProgram:    make(map[string]*Button),
-		FunctionCall:     make(map[string]*Button),
-		OpenPageInMenu:   make(map[string]*Button),
-		OpenResource:     make(map[string]*Button),
-		KeyboardShortcut: make(map[string]*Button),
+		ShowProgram: make(map[string]*Button),
+		ShowAny:     make(map[string]*Button),
 	}
 
 	for btnID := range pageConfig {
 		buttonPtr := pageConfig[btnID]
 
-		switch core.ButtonType(buttonPtr.ButtonType) {
+		info, ok := core.LookupButtonType(core.ButtonType(buttonPtr.ButtonType))
+		if !ok || !info.WindowAssignment {
+			continue
+		}
+		switch info.Type {
 		case core.ButtonTypeShowProgramWindow:
 			separated.ShowProgram[btnID] = &buttonPtr
 		case core.ButtonTypeShowAnyWindow:
 			separated.ShowAny[btnID] = &buttonPtr
-		case core.ButtonTypeLaunchProgram:
-			separated.LaunchProgram[btnID] = &buttonPtr
-		case core.ButtonTypeCallFunction:
-			separated.FunctionCall[btnID] = &buttonPtr
-		case core.ButtonTypeOpenPageInMenu:
-			separated.OpenPageInMenu[btnID] = &buttonPtr
-		case core.ButtonTypeOpenResource:
-			separated.OpenResource[btnID] = &buttonPtr
-		case core.ButtonTypeKeyboardShortcut:
-			separated.KeyboardShortcut[btnID] = &buttonPtr
+		default:
+			log.Warn("Window button type '%s' has no assignment pass; button %s is left as is", info.Type, btnID)
 		}
 	}
 	return separated
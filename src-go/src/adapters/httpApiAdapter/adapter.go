@@ -0,0 +1,109 @@
+// Package httpApiAdapter exposes a small HTTP/JSON API on localhost for scripts. Every call is
+// translated to the NATS subjects the other workers already serve, so the API has no state of
+// its own. Requests need the token from HTTP_API_TOKEN as a bearer token.
+package httpApiAdapter
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/adapters/natsAdapter"
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core/logger"
+)
+
+// Package-level logger instance
+var log = logger.New("HttpApi")
+
+// defaultPort is used when HTTP_API_PORT is not set.
+const defaultPort = "17420"
+
+// HttpApiAdapter serves the HTTP API.
+type HttpApiAdapter struct {
+	natsAdapter *natsAdapter.NatsAdapter
+	token       string
+	addr        string
+	handler     http.Handler
+}
+
+// Enabled reports whether the API is configured. The coordinator only spawns the worker then.
+func Enabled() bool {
+	return os.Getenv("HTTP_API_TOKEN") != ""
+}
+
+// New creates the API worker from HTTP_API_TOKEN and HTTP_API_PORT.
+func New(natsAdapter *natsAdapter.NatsAdapter) (*HttpApiAdapter, error) {
+	token := os.Getenv("HTTP_API_TOKEN")
+	if token == "" {
+		return nil, errors.New("HTTP_API_TOKEN is not set")
+	}
+	port := os.Getenv("HTTP_API_PORT")
+	if port == "" {
+		port = defaultPort
+	}
+	return NewWithToken(natsAdapter, token, net.JoinHostPort("127.0.0.1", port)), nil
+}
+
+// NewWithToken creates the API worker with an explicit token and listen address. Run is not
+// needed to use Handler, e.g. with httptest.
+func NewWithToken(natsAdapter *natsAdapter.NatsAdapter, token, addr string) *HttpApiAdapter {
+	a := &HttpApiAdapter{
+		natsAdapter: natsAdapter,
+		token:       token,
+		addr:        addr,
+	}
+	a.handler = a.authorize(a.routes())
+	return a
+}
+
+// Handler returns the API's HTTP handler, including the token check.
+func (a *HttpApiAdapter) Handler() http.Handler {
+	return a.handler
+}
+
+// Run serves the API until the process exits.
+func (a *HttpApiAdapter) Run() {
+	server := &http.Server{
+		Addr:              a.addr,
+		Handler:           a.handler,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	log.Info("HTTP API listening on http://%s", a.addr)
+	if err := server.ListenAndServe(); err != nil {
+		log.Fatal("HTTP API stopped: %v", err)
+	}
+}
+
+// authorize rejects requests without the bearer token, and requests addressed to another
+// host name, which is how a web page would reach the API through DNS rebinding.
+func (a *HttpApiAdapter) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isLoopbackHost(r.Host) {
+			writeError(w, http.StatusForbidden, "only localhost requests are accepted")
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "missing or wrong token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isLoopbackHost(hostPort string) bool {
+	host, _, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		host = hostPort
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
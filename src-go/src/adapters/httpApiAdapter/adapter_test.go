@@ -0,0 +1,224 @@
+package httpApiAdapter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/adapters/natsAdapter"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+const testToken = "test-token"
+
+// newTestAPI starts an embedded NATS server and returns the API's handler together with a
+// connection for stubbing the workers it forwards to.
+func newTestAPI(t *testing.T) (http.Handler, *natsAdapter.NatsAdapter) {
+	t.Helper()
+	natsServer, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: server.RANDOM_PORT, NoLog: true, NoSigs: true})
+	if err != nil {
+		t.Fatalf("failed to create NATS server: %v", err)
+	}
+	go natsServer.Start()
+	t.Cleanup(natsServer.Shutdown)
+	if !natsServer.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server did not start")
+	}
+
+	connection, err := nats.Connect(natsServer.ClientURL())
+	if err != nil {
+		t.Fatalf("failed to connect to NATS: %v", err)
+	}
+	t.Cleanup(connection.Close)
+
+	// The subjects come from the environment, which tests don't load; routes binds them on creation
+	natsSubjectPieMenuConfigGet = "test.piemenuconfig.get"
+	natsSubjectPieMenuConfigPatch = "test.piemenuconfig.patch"
+	natsSubjectSettingsPatch = "test.settings.patch"
+
+	nc := &natsAdapter.NatsAdapter{Connection: connection}
+	return NewWithToken(nc, testToken, "127.0.0.1:0").Handler(), nc
+}
+
+// serve sends a request to handler as a local script would, with the token if it isn't empty.
+func serve(handler http.Handler, method, target, token, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Host = "127.0.0.1:17420"
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+// respondToConfigGet answers config requests like the config manager does.
+func respondToConfigGet(nc *natsAdapter.NatsAdapter) {
+	natsAdapter.HandleRequest(nc, natsSubjectPieMenuConfigGet, func(struct{}) (natsAdapter.Snapshot[map[string]any], error) {
+		return natsAdapter.Snapshot[map[string]any]{Revision: 7, Data: map[string]any{"buttons": map[string]any{}}}, nil
+	})
+}
+
+func TestAuthorize(t *testing.T) {
+	handler, nc := newTestAPI(t)
+	respondToConfigGet(nc)
+
+	tests := []struct {
+		name       string
+		host       string
+		authHeader string
+		wantStatus int
+	}{
+		{"no token", "127.0.0.1:17420", "", http.StatusUnauthorized},
+		{"wrong token", "127.0.0.1:17420", "Bearer nope", http.StatusUnauthorized},
+		{"token without bearer scheme", "127.0.0.1:17420", testToken, http.StatusUnauthorized},
+		{"other host", "attacker.example:17420", "Bearer " + testToken, http.StatusForbidden},
+		{"other host without token", "attacker.example", "", http.StatusForbidden},
+		{"localhost", "localhost:17420", "Bearer " + testToken, http.StatusOK},
+		{"ipv6 loopback", "[::1]:17420", "Bearer " + testToken, http.StatusOK},
+		{"ipv4 loopback", "127.0.0.1:17420", "Bearer " + testToken, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/config", nil)
+			r.Host = tt.host
+			if tt.authHeader != "" {
+				r.Header.Set("Authorization", tt.authHeader)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("WWW-Authenticate = %q, want %q", w.Header().Get("WWW-Authenticate"), "Bearer")
+			}
+		})
+	}
+}
+
+func TestForwardGet(t *testing.T) {
+	handler, nc := newTestAPI(t)
+	respondToConfigGet(nc)
+
+	w := serve(handler, http.MethodGet, "/api/config", testToken, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (body %s)", w.Code, http.StatusOK, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	var snapshot natsAdapter.Snapshot[map[string]any]
+	if err := json.Unmarshal(w.Body.Bytes(), &snapshot); err != nil {
+		t.Fatalf("unreadable body %s: %v", w.Body, err)
+	}
+	if _, ok := snapshot.Data["buttons"]; snapshot.Revision != 7 || !ok {
+		t.Fatalf("got %s, want the config manager's snapshot", w.Body)
+	}
+}
+
+func TestForwardWithoutResponder(t *testing.T) {
+	handler, _ := newTestAPI(t)
+
+	w := serve(handler, http.MethodGet, "/api/config", testToken, "")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d (body %s)", w.Code, http.StatusServiceUnavailable, w.Body)
+	}
+}
+
+func TestPatchConfig(t *testing.T) {
+	const patch = `{"baseRevision":3,"patch":[{"op":"remove","path":"/starred"}],"summary":"unstar"}`
+
+	tests := []struct {
+		name       string
+		reply      string
+		wantStatus int
+	}{
+		{"applied", `{"ok":true,"revision":4}`, http.StatusOK},
+		{"conflict", `{"ok":false,"conflict":true,"error":"config changed since revision 3"}`, http.StatusConflict},
+		{"rejected", `{"ok":false,"error":"invalid patch"}`, http.StatusBadRequest},
+		{"unreadable reply", `not json`, http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, nc := newTestAPI(t)
+			received := make(chan string, 1)
+			nc.SubscribeToSubject(natsSubjectPieMenuConfigPatch, func(msg *nats.Msg) {
+				received <- string(msg.Data)
+				_ = msg.Respond([]byte(tt.reply))
+			})
+
+			w := serve(handler, http.MethodPatch, "/api/config", testToken, patch)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body)
+			}
+			if got := <-received; got != patch {
+				t.Errorf("config manager received %s, want the request body unchanged", got)
+			}
+			if tt.wantStatus != http.StatusBadGateway && strings.TrimSpace(w.Body.String()) != tt.reply {
+				t.Errorf("body = %s, want the config manager's reply %s", w.Body, tt.reply)
+			}
+		})
+	}
+}
+
+func TestPatchSettings(t *testing.T) {
+	const values = `{"pauseOnEdgeProximity":true}`
+
+	tests := []struct {
+		name       string
+		reply      string
+		wantStatus int
+	}{
+		{"applied", `{"ok":true,"revision":4,"settings":{"pauseOnEdgeProximity":{"value":true}}}`, http.StatusOK},
+		{"unknown setting", `{"ok":false,"unknown":["pauseOnEdgeProximity"],"revision":3,"error":"unknown settings: pauseOnEdgeProximity"}`, http.StatusNotFound},
+		{"rejected", `{"ok":false,"revision":3,"error":"invalid value"}`, http.StatusBadRequest},
+		{"unreadable reply", `not json`, http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, nc := newTestAPI(t)
+			received := make(chan string, 1)
+			nc.SubscribeToSubject(natsSubjectSettingsPatch, func(msg *nats.Msg) {
+				received <- string(msg.Data)
+				_ = msg.Respond([]byte(tt.reply))
+			})
+
+			w := serve(handler, http.MethodPatch, "/api/settings", testToken, values)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body)
+			}
+			if got := <-received; got != values {
+				t.Errorf("settings manager received %s, want the request body unchanged", got)
+			}
+			if tt.wantStatus != http.StatusBadGateway && strings.TrimSpace(w.Body.String()) != tt.reply {
+				t.Errorf("body = %s, want the settings manager's reply %s", w.Body, tt.reply)
+			}
+		})
+	}
+}
+
+func TestPatchSettingsErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"not an object", `[true]`, http.StatusBadRequest},
+		{"no settings", `{}`, http.StatusBadRequest},
+		{"no settings manager", `{"pauseOnEdgeProximity":true}`, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, _ := newTestAPI(t)
+			w := serve(handler, http.MethodPatch, "/api/settings", testToken, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package httpApiAdapter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/adapters/natsAdapter"
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core"
+	"github.com/nats-io/nats.go"
+)
+
+// NATS Subjects the API translates to.
+var (
+	natsSubjectPieMenuConfigGet     = os.Getenv("PUBLIC_NATSSUBJECT_PIEMENUCONFIG_GET")
+	natsSubjectPieMenuConfigPatch   = os.Getenv("PUBLIC_NATSSUBJECT_PIEMENUCONFIG_PATCH")
+	natsSubjectSettingsGet          = os.Getenv("PUBLIC_NATSSUBJECT_SETTINGS_GET")
+	natsSubjectSettingsPatch        = os.Getenv("PUBLIC_NATSSUBJECT_SETTINGS_PATCH")
+	natsSubjectWindowManagerGet     = os.Getenv("PUBLIC_NATSSUBJECT_WINDOWMANAGER_GET")
+	natsSubjectInstalledAppsGet     = os.Getenv("PUBLIC_NATSSUBJECT_WINDOWMANAGER_INSTALLEDAPPS_GET")
+	natsSubjectPieButtonExecute     = os.Getenv("PUBLIC_NATSSUBJECT_PIEBUTTON_EXECUTE_REQUEST")
+	natsSubjectShortcutsTogglePause = os.Getenv("PUBLIC_NATSSUBJECT_SHORTCUTS_TOGGLE_PAUSE")
+)
+
+// maxBodyBytes caps request bodies; a full pie menu config patch fits easily.
+const maxBodyBytes = 4 << 20
+
+// noButton marks executions that don't come from a configured button.
+const noButton = -1
+
+func (a *HttpApiAdapter) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/config", a.forward(natsSubjectPieMenuConfigGet))
+	mux.HandleFunc("PATCH /api/config", a.patchConfig)
+	mux.HandleFunc("GET /api/settings", a.forward(natsSubjectSettingsGet))
+	mux.HandleFunc("PATCH /api/settings", a.patchSettings)
+	mux.HandleFunc("GET /api/windows", a.forward(natsSubjectWindowManagerGet))
+	mux.HandleFunc("GET /api/apps", a.forward(natsSubjectInstalledAppsGet))
+	mux.HandleFunc("POST /api/buttons/execute", a.executeButton)
+	mux.HandleFunc("POST /api/functions/{name}", a.callFunction)
+	mux.HandleFunc("POST /api/menus/{menu}/pages/{page}/open", a.openPage)
+	mux.HandleFunc("POST /api/shortcuts/toggle-pause", a.toggleShortcutPause)
+	return mux
+}
+
+// forward answers with the data of a request/reply subject that takes no arguments.
+func (a *HttpApiAdapter) forward(subject string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		a.request(w, subject, struct{}{})
+	}
+}
+
+// request sends req on a request/reply subject and writes the reply's data.
+func (a *HttpApiAdapter) request(w http.ResponseWriter, subject string, req any) {
+	data, err := natsAdapter.Request[any, json.RawMessage](a.natsAdapter, subject, req, natsAdapter.DefaultRequestTimeout)
+	if err != nil {
+		writeNatsError(w, subject, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, data)
+}
+
+// patchConfig applies a JSON Patch request ({baseRevision, patch, summary}) to the pie menu config.
+func (a *HttpApiAdapter) patchConfig(w http.ResponseWriter, r *http.Request) {
+	body, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+	if a.natsAdapter.Connection == nil {
+		writeNatsError(w, natsSubjectPieMenuConfigPatch, nats.ErrConnectionClosed)
+		return
+	}
+	msg, err := a.natsAdapter.Connection.Request(natsSubjectPieMenuConfigPatch, body, natsAdapter.DefaultRequestTimeout)
+	if err != nil {
+		writeNatsError(w, natsSubjectPieMenuConfigPatch, err)
+		return
+	}
+
+	var result struct {
+		OK       bool `json:"ok"`
+		Conflict bool `json:"conflict"`
+	}
+	if err := json.Unmarshal(msg.Data, &result); err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Sprintf("unreadable reply from the config manager: %v", err))
+		return
+	}
+	status := http.StatusOK
+	switch {
+	case result.Conflict:
+		status = http.StatusConflict
+	case !result.OK:
+		status = http.StatusBadRequest
+	}
+	writeJSON(w, status, json.RawMessage(msg.Data))
+}
+
+// patchSettings sets the values of the settings named in the body, e.g. {"pauseOnEdgeProximity": true}.
+// The settings manager applies them to its current settings, so other settings saved in the meantime stay.
+func (a *HttpApiAdapter) patchSettings(w http.ResponseWriter, r *http.Request) {
+	body, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(body, &values); err != nil || len(values) == 0 {
+		writeError(w, http.StatusBadRequest, "expected an object of setting names and values")
+		return
+	}
+	if a.natsAdapter.Connection == nil {
+		writeNatsError(w, natsSubjectSettingsPatch, nats.ErrConnectionClosed)
+		return
+	}
+	msg, err := a.natsAdapter.Connection.Request(natsSubjectSettingsPatch, body, natsAdapter.DefaultRequestTimeout)
+	if err != nil {
+		writeNatsError(w, natsSubjectSettingsPatch, err)
+		return
+	}
+
+	var result struct {
+		OK      bool     `json:"ok"`
+		Unknown []string `json:"unknown"`
+	}
+	if err := json.Unmarshal(msg.Data, &result); err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Sprintf("unreadable reply from the settings manager: %v", err))
+		return
+	}
+	status := http.StatusOK
+	switch {
+	case len(result.Unknown) > 0:
+		status = http.StatusNotFound
+	case !result.OK:
+		status = http.StatusBadRequest
+	}
+	writeJSON(w, status, json.RawMessage(msg.Data))
+}
+
+// executeButton runs a button given as an execute message, e.g. {"button_type": "launch_program",
+// "properties": {...}}, and answers with its execution result. Clicks default to left clicks.
+func (a *HttpApiAdapter) executeButton(w http.ResponseWriter, r *http.Request) {
+	body, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+	var message map[string]any
+	if err := json.Unmarshal(body, &message); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid execute message: %v", err))
+		return
+	}
+	if _, ok := message["button_type"].(string); !ok {
+		writeError(w, http.StatusBadRequest, "button_type is required")
+		return
+	}
+	if _, ok := message["click_type"]; !ok {
+		message["click_type"] = core.ClickTypeLeftUp
+	}
+	for _, index := range []string{"menu_index", "page_index", "button_index"} {
+		if _, ok := message[index]; !ok {
+			message[index] = noButton
+		}
+	}
+	a.request(w, natsSubjectPieButtonExecute, message)
+}
+
+// callFunction runs a button function by its name, e.g. "Maximize".
+func (a *HttpApiAdapter) callFunction(w http.ResponseWriter, r *http.Request) {
+	a.request(w, natsSubjectPieButtonExecute, syntheticButton(core.ButtonTypeCallFunction,
+		core.CallFunctionProperties{ButtonTextUpper: r.PathValue("name")}))
+}
+
+// openPage opens a pie menu on a page at the mouse cursor.
+func (a *HttpApiAdapter) openPage(w http.ResponseWriter, r *http.Request) {
+	menuID, menuErr := strconv.Atoi(r.PathValue("menu"))
+	pageID, pageErr := strconv.Atoi(r.PathValue("page"))
+	if menuErr != nil || pageErr != nil {
+		writeError(w, http.StatusBadRequest, "menu and page must be numbers")
+		return
+	}
+	a.request(w, natsSubjectPieButtonExecute, syntheticButton(core.ButtonTypeOpenPageInMenu,
+		core.OpenSpecificPieMenuPage{MenuID: menuID, PageID: pageID}))
+}
+
+// toggleShortcutPause pauses or resumes the pie menu shortcuts.
+func (a *HttpApiAdapter) toggleShortcutPause(w http.ResponseWriter, r *http.Request) {
+	a.natsAdapter.PublishMessage(natsSubjectShortcutsTogglePause, struct{}{})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// syntheticButton builds a left click on a button that isn't in the pie menu config.
+func syntheticButton(buttonType core.ButtonType, properties any) map[string]any {
+	return map[string]any{
+		"menu_index":   noButton,
+		"page_index":   noButton,
+		"button_index": noButton,
+		"button_type":  buttonType,
+		"properties":   properties,
+		"click_type":   core.ClickTypeLeftUp,
+	}
+}
+
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("failed to read body: %v", err))
+		return nil, false
+	}
+	return body, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error("Failed to write response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// writeNatsError maps a failed NATS round trip to an HTTP status.
+func writeNatsError(w http.ResponseWriter, subject string, err error) {
+	switch {
+	case errors.Is(err, natsAdapter.ErrRequestFailed):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, nats.ErrNoResponders):
+		writeError(w, http.StatusServiceUnavailable, fmt.Sprintf("no worker answers '%s'", subject))
+	case errors.Is(err, nats.ErrTimeout):
+		writeError(w, http.StatusGatewayTimeout, fmt.Sprintf("no reply on '%s' in time", subject))
+	default:
+		log.Error("Request on '%s' failed: %v", subject, err)
+		writeError(w, http.StatusBadGateway, err.Error())
+	}
+}
@@ -0,0 +1,98 @@
+package natsAdapter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// DefaultRequestTimeout is a sensible timeout for local request/reply round trips.
+const DefaultRequestTimeout = 2 * time.Second
+
+// ErrRequestFailed wraps errors reported by the responding worker.
+var ErrRequestFailed = errors.New("request failed")
+
+// Snapshot is the reply to a `get` request: the owner's current state and its revision.
+// The revision changes whenever the state changes, so clients can tell whether a later
+// push is newer than the snapshot they fetched.
+type Snapshot[T any] struct {
+	Revision int `json:"revision"`
+	Data     T   `json:"data"`
+}
+
+// replyEnvelope is the wire format of every reply sent by HandleRequest.
+type replyEnvelope[T any] struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+	Data  T      `json:"data"`
+}
+
+// HandleRequest subscribes to subject and answers each request with the result of handler.
+// An empty request body decodes to the zero Req. Errors are sent back to the requester.
+func HandleRequest[Req, Resp any](a *NatsAdapter, subject string, handler func(Req) (Resp, error)) {
+	a.SubscribeToSubject(subject, func(msg *nats.Msg) {
+		if msg.Reply == "" {
+			log.Warn("[%s] Ignoring request on '%s' without reply subject", a.label, subject)
+			return
+		}
+
+		var envelope replyEnvelope[Resp]
+		var req Req
+		if len(msg.Data) > 0 {
+			if err := json.Unmarshal(msg.Data, &req); err != nil {
+				envelope.Error = fmt.Sprintf("invalid request: %v", err)
+				respond(a, msg, envelope)
+				return
+			}
+		}
+
+		resp, err := handler(req)
+		if err != nil {
+			envelope.Error = err.Error()
+		} else {
+			envelope.OK = true
+			envelope.Data = resp
+		}
+		respond(a, msg, envelope)
+	})
+}
+
+// Request sends req on subject and decodes the reply produced by a HandleRequest handler.
+func Request[Req, Resp any](a *NatsAdapter, subject string, req Req, timeout time.Duration) (Resp, error) {
+	var zero Resp
+	if a.Connection == nil {
+		return zero, nats.ErrConnectionClosed
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return zero, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	msg, err := a.Connection.Request(subject, data, timeout)
+	if err != nil {
+		return zero, err
+	}
+
+	var envelope replyEnvelope[Resp]
+	if err := json.Unmarshal(msg.Data, &envelope); err != nil {
+		return zero, fmt.Errorf("failed to decode reply from '%s': %w", subject, err)
+	}
+	if !envelope.OK {
+		return zero, fmt.Errorf("%w: %s", ErrRequestFailed, envelope.Error)
+	}
+	return envelope.Data, nil
+}
+
+func respond[T any](a *NatsAdapter, msg *nats.Msg, envelope replyEnvelope[T]) {
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		log.Error("[%s] Error marshaling reply on '%s': %v", a.label, msg.Subject, err)
+		return
+	}
+	if err := msg.Respond(data); err != nil {
+		log.Error("[%s] Error sending reply on '%s': %v", a.label, msg.Subject, err)
+	}
+}
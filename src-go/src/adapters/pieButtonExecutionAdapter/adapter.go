@@ -4,6 +4,7 @@
 package pieButtonExecutionAdapter
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"sync"
@@ -24,6 +25,15 @@ var (
 	natsSubjectInstalledAppsInfo   = os.Getenv("PUBLIC_NATSSUBJECT_WINDOWMANAGER_INSTALLEDAPPSINFO")
 	natsSubjectPieMenuNavigate     = os.Getenv("PUBLIC_NATSSUBJECT_PIEMENU_NAVIGATE")
 	natsSubjectPieButtonOpenFolder = os.Getenv("PUBLIC_NATSSUBJECT_PIEBUTTON_OPENFOLDER")
+	natsSubjectFocusedAppUpdate    = os.Getenv("PUBLIC_NATSSUBJECT_FOCUSEDAPP_UPDATE")
+
+	natsSubjectPieButtonCommandResult = os.Getenv("PUBLIC_NATSSUBJECT_PIEBUTTON_COMMAND_RESULT")
+	natsSubjectPieButtonMacroProgress = os.Getenv("PUBLIC_NATSSUBJECT_PIEBUTTON_MACRO_PROGRESS")
+
+	natsSubjectPieButtonExecuteResult  = os.Getenv("PUBLIC_NATSSUBJECT_PIEBUTTON_EXECUTE_RESULT")
+	natsSubjectPieButtonExecuteRequest = os.Getenv("PUBLIC_NATSSUBJECT_PIEBUTTON_EXECUTE_REQUEST")
+
+	natsSubjectPieMenuConfigBackendUpdate = os.Getenv("PUBLIC_NATSSUBJECT_PIEMENUCONFIG_BACKEND_UPDATE")
 )
 
 // PieButtonExecutionAdapter listens to NATS events and executes actions.
@@ -34,10 +44,18 @@ type PieButtonExecutionAdapter struct {
 	mu                  sync.RWMutex // Protects access to windowsList
 	windowsList         core.WindowsUpdate
 	installedAppsInfo   map[string]core.AppInfo
+	focusedApp          string // AppName of the last focused window, for type_text templates
 	functionHandlers    map[string]ButtonFunctionExecutor
+	buttonTypeHandlers  map[core.ButtonType]buttonTypeHandler
 	lastMinimizedWindow WindowHandle
 
 	lastExplorerWindowHWND WindowHandle // Stores the HWND of the last Explorer window brought to foreground
+
+	macroMu     sync.Mutex // Protects the running macro
+	macroCancel context.CancelFunc
+	macroRunID  int
+
+	usage *usageStore // Every executed button, for usage stats
 }
 
 // --- Adapter Implementation ---
@@ -48,6 +66,7 @@ func New(natsAdapter *natsAdapter.NatsAdapter) *PieButtonExecutionAdapter {
 		natsAdapter:       natsAdapter,
 		windowsList:       make(core.WindowsUpdate),
 		installedAppsInfo: make(map[string]core.AppInfo),
+		usage:             newUsageStore(),
 	}
 
 	a.functionHandlers = map[string]ButtonFunctionExecutor{
@@ -86,6 +105,9 @@ func New(natsAdapter *natsAdapter.NatsAdapter) *PieButtonExecutionAdapter {
 
 	ValidateFunctionHandlers(a.functionHandlers)
 
+	// Every type registered in core needs an executor, see registerButtonExecutor
+	a.buttonTypeHandlers = a.bindButtonExecutors()
+
 	a.subscribeToEvents() // Setup NATS subscriptions
 
 	return a
@@ -98,35 +120,31 @@ func (a *PieButtonExecutionAdapter) subscribeToEvents() {
 	a.natsAdapter.SubscribeToSubject(natsSubjectWindowManagerUpdate, a.handleWindowUpdateMessage)
 	a.natsAdapter.SubscribeToSubject(natsSubjectInstalledAppsInfo, a.handleInstalledAppsInfoMessage)
 	a.natsAdapter.SubscribeToSubject(natsSubjectPieButtonOpenFolder, a.handleOpenFolder)
+	a.natsAdapter.SubscribeToSubject(natsSubjectFocusedAppUpdate, a.handleFocusedAppMessage)
+	a.natsAdapter.SubscribeToSubject(natsSubjectPieMenuConfigBackendUpdate, a.handleConfigUpdateMessage)
+	a.handleExecuteRequests()
+	a.handleUsageRequests()
 }
 
+// buttonTypeHandler executes a button of one type.
+type buttonTypeHandler func(executionInfo *pieButtonExecute_Message) error
+
 // executeCommand dispatches the command based on the ButtonType.
 func (a *PieButtonExecutionAdapter) executeCommand(executionInfo *pieButtonExecute_Message) error {
+	if _, ok := core.LookupButtonType(executionInfo.ButtonType); !ok {
+		return withKind(ErrorKindUnsupported, fmt.Errorf("unknown button type: %s", executionInfo.ButtonType))
+	}
+	// A configured click action replaces the button's default behavior for that click
+	if action, key, ok := clickActionFor(executionInfo); ok {
+		log.Info("Button %d - Click '%s' runs its %s action", executionInfo.ButtonIndex, key, action.Type)
+		return a.executeCommand(clickActionMessage(executionInfo, action))
+	}
 
-	switch executionInfo.ButtonType {
-	case core.ButtonTypeShowProgramWindow:
-		return a.handleShowProgramWindow(executionInfo)
-	case core.ButtonTypeShowAnyWindow:
-		return a.handleShowAnyWindow(executionInfo)
-	case core.ButtonTypeCallFunction:
-		return a.handleCallFunction(executionInfo)
-	case core.ButtonTypeOpenPageInMenu:
-		return a.handleOpenPageInMenu(executionInfo)
-	case core.ButtonTypeOpenResource:
-		log.Info("Button %d - Opening resource: %s", executionInfo.ButtonIndex, executionInfo.ButtonType)
-		return a.handleOpenResource(executionInfo)
-	case core.ButtonTypeLaunchProgram:
-		log.Info("Button %d - Launching program: %s", executionInfo.ButtonIndex, executionInfo.ButtonType)
-		return a.handleLaunchProgram(executionInfo)
-	case core.ButtonTypeKeyboardShortcut:
-		log.Info("Button %d - Executing keyboard shortcut: %s", executionInfo.ButtonIndex, executionInfo.ButtonType)
-		return a.handleKeyboardShortcut(executionInfo)
-	case core.ButtonTypeDisabled:
-		log.Info("Button %d is disabled, doing nothing.", executionInfo.ButtonIndex)
-		return nil // Nothing to do for disabled buttons
-	default:
-		return fmt.Errorf("unknown button type: %s", executionInfo.ButtonType)
+	handler, ok := a.buttonTypeHandlers[executionInfo.ButtonType]
+	if !ok {
+		return withKind(ErrorKindUnsupported, fmt.Errorf("no handler for button type: %s", executionInfo.ButtonType))
 	}
+	return handler(executionInfo)
 }
 
 // Run starts the adapter's main loop (currently just blocks).
@@ -0,0 +1,43 @@
+package pieButtonExecutionAdapter
+
+import (
+	"fmt"
+
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core"
+)
+
+// buttonExecutor executes a button of one type. Handler methods are registered as method
+// expressions, e.g. (*PieButtonExecutionAdapter).handleMacro.
+type buttonExecutor func(a *PieButtonExecutionAdapter, executionInfo *pieButtonExecute_Message) error
+
+// buttonExecutors holds the executor of every button type, filled by registerButtonExecutor.
+var buttonExecutors = make(map[core.ButtonType]buttonExecutor)
+
+// registerButtonExecutor adds the executor for a button type registered in core. Each handler
+// registers itself from an init function in its own file, so a new button type needs its core
+// registration and this one call. It panics on an unknown or duplicate type.
+func registerButtonExecutor(buttonType core.ButtonType, executor buttonExecutor) {
+	if _, ok := core.LookupButtonType(buttonType); !ok {
+		panic(fmt.Sprintf("executor for button type %q, which is not registered in core", buttonType))
+	}
+	if _, exists := buttonExecutors[buttonType]; exists {
+		panic(fmt.Sprintf("executor for button type %q registered twice", buttonType))
+	}
+	buttonExecutors[buttonType] = executor
+}
+
+// bindButtonExecutors binds the executor of every registered button type to a, so a type that
+// every other worker accepts can't fail here as unknown.
+func (a *PieButtonExecutionAdapter) bindButtonExecutors() map[core.ButtonType]buttonTypeHandler {
+	handlers := make(map[core.ButtonType]buttonTypeHandler, len(buttonExecutors))
+	for _, info := range core.ButtonTypes() {
+		executor, ok := buttonExecutors[info.Type]
+		if !ok {
+			log.Fatal("Button type '%s' is registered but has no executor", info.Type)
+		}
+		handlers[info.Type] = func(executionInfo *pieButtonExecute_Message) error {
+			return executor(a, executionInfo)
+		}
+	}
+	return handlers
+}
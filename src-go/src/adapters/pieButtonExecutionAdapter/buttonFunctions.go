@@ -305,7 +305,7 @@ func LaunchApp(appNameKey string, appInfo core.AppInfo) error {
 	}
 
 	if appInfo.ExePath == "" {
-		return fmt.Errorf("no executable path or URI for application '%s'", appNameKey)
+		return withKind(ErrorKindNotFound, fmt.Errorf("no executable path or URI for application '%s'", appNameKey))
 	}
 
 	cmd, err := buildExecCmd(appInfo.ExePath, appInfo.WorkingDirectory, appInfo.Args)
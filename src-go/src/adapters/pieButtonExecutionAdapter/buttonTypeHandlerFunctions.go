@@ -8,6 +8,23 @@ import (
 	"github.com/Rayzorblade23/MightyPie-Revamped/src/core"
 )
 
+func init() {
+	registerButtonExecutor(core.ButtonTypeShowProgramWindow, (*PieButtonExecutionAdapter).handleShowProgramWindow)
+	registerButtonExecutor(core.ButtonTypeShowAnyWindow, (*PieButtonExecutionAdapter).handleShowAnyWindow)
+	registerButtonExecutor(core.ButtonTypeLaunchProgram, (*PieButtonExecutionAdapter).handleLaunchProgram)
+	registerButtonExecutor(core.ButtonTypeCallFunction, (*PieButtonExecutionAdapter).handleCallFunction)
+	registerButtonExecutor(core.ButtonTypeOpenPageInMenu, (*PieButtonExecutionAdapter).handleOpenPageInMenu)
+	registerButtonExecutor(core.ButtonTypeOpenResource, (*PieButtonExecutionAdapter).handleOpenResource)
+	registerButtonExecutor(core.ButtonTypeKeyboardShortcut, (*PieButtonExecutionAdapter).handleKeyboardShortcut)
+	registerButtonExecutor(core.ButtonTypeDisabled, (*PieButtonExecutionAdapter).handleDisabled)
+}
+
+// handleDisabled does nothing; disabled buttons only fill a slot.
+func (a *PieButtonExecutionAdapter) handleDisabled(executionInfo *pieButtonExecute_Message) error {
+	log.Info("Button %d is disabled, doing nothing.", executionInfo.ButtonIndex)
+	return nil
+}
+
 func (a *PieButtonExecutionAdapter) handleShowProgramWindow(executionInfo *pieButtonExecute_Message) error {
 	var windowProps core.ShowProgramWindowProperties
 	if err := unmarshalProperties(executionInfo.Properties, &windowProps); err != nil {
@@ -191,7 +208,7 @@ func (a *PieButtonExecutionAdapter) handleCallFunction(executionInfo *pieButtonE
 		log.Info("CallFunction (Left Click): Proceeding to execute function '%s'", displayName)
 		handler, exists := a.functionHandlers[displayName]
 		if !exists {
-			return fmt.Errorf("unknown function requested for left-click: %s", displayName)
+			return withKind(ErrorKindNotFound, fmt.Errorf("unknown function requested for left-click: %s", displayName))
 		}
 		err := handler.Execute(mouseX, mouseY)
 		if err != nil {
@@ -267,7 +284,7 @@ func (a *PieButtonExecutionAdapter) handleOpenResource(executionInfo *pieButtonE
 	if executionInfo.ClickType == ClickTypeLeftUp {
 		// Check if the resource path exists
 		if _, err := os.Stat(resourceProps.ResourcePath); os.IsNotExist(err) {
-			return fmt.Errorf("resource path does not exist: %s", resourceProps.ResourcePath)
+			return withKind(ErrorKindNotFound, fmt.Errorf("resource path does not exist: %s", resourceProps.ResourcePath))
 		}
 
 		// Open the file or folder using the system's default application
@@ -307,11 +324,11 @@ func unmarshalProperties(props any, target any) error {
 	// Convert the properties to JSON and then unmarshal into the target struct
 	propsBytes, err := json.Marshal(props)
 	if err != nil {
-		return fmt.Errorf("failed to marshal properties: %w", err)
+		return withKind(ErrorKindInvalid, fmt.Errorf("failed to marshal properties: %w", err))
 	}
 
 	if err := json.Unmarshal(propsBytes, target); err != nil {
-		return fmt.Errorf("failed to unmarshal properties: %w", err)
+		return withKind(ErrorKindInvalid, fmt.Errorf("failed to unmarshal properties: %w", err))
 	}
 
 	return nil
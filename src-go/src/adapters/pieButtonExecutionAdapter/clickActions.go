@@ -0,0 +1,66 @@
+package pieButtonExecutionAdapter
+
+import (
+	"encoding/json"
+
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core"
+)
+
+// modifierKeys maps the click key modifiers to the virtual keys that hold them.
+var modifierKeys = []struct {
+	name string
+	vks  []int
+}{
+	{core.ModifierCtrl, []int{0x11}},      // VK_CONTROL
+	{core.ModifierAlt, []int{0x12}},       // VK_MENU
+	{core.ModifierShift, []int{0x10}},     // VK_SHIFT
+	{core.ModifierWin, []int{0x5B, 0x5C}}, // VK_LWIN, VK_RWIN
+}
+
+// heldModifiers asks the OS which modifiers are down right now.
+func heldModifiers() []string {
+	held := []string{}
+	if core.GetAsyncKeyState == nil {
+		return held
+	}
+	for _, modifier := range modifierKeys {
+		for _, vk := range modifier.vks {
+			if state, _, _ := core.GetAsyncKeyState.Call(uintptr(vk)); state&0x8000 != 0 {
+				held = append(held, modifier.name)
+				break
+			}
+		}
+	}
+	return held
+}
+
+// clickActionFor returns the click action the button configured for this click, if any.
+func clickActionFor(executionInfo *pieButtonExecute_Message) (core.ClickAction, string, bool) {
+	var props core.ButtonActions
+	if err := unmarshalProperties(executionInfo.Properties, &props); err != nil || len(props.ClickActions) == 0 {
+		return core.ClickAction{}, "", false
+	}
+
+	held := executionInfo.Modifiers
+	if held == nil {
+		held = heldModifiers()
+	}
+	return core.LookupClickAction(props.ClickActions, executionInfo.ClickType, held)
+}
+
+// clickActionMessage turns a click action into a left click on a button of the action's type.
+func clickActionMessage(executionInfo *pieButtonExecute_Message, action core.ClickAction) *pieButtonExecute_Message {
+	var properties any = json.RawMessage("{}")
+	if len(action.Properties) > 0 {
+		properties = action.Properties
+	}
+	return &pieButtonExecute_Message{
+		MenuIndex:   executionInfo.MenuIndex,
+		PageIndex:   executionInfo.PageIndex,
+		ButtonIndex: executionInfo.ButtonIndex,
+		ButtonType:  core.ButtonType(action.Type),
+		Properties:  properties,
+		ClickType:   ClickTypeLeftUp,
+		Modifiers:   []string{}, // the modifiers were used up choosing this action
+	}
+}
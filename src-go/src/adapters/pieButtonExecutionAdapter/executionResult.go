@@ -0,0 +1,110 @@
+package pieButtonExecutionAdapter
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/adapters/natsAdapter"
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core"
+)
+
+// ErrorKind classifies why a button failed, so the UI can explain it.
+type ErrorKind string
+
+const (
+	ErrorKindNotFound    ErrorKind = "not_found"   // program, function, file or window is missing
+	ErrorKindPermission  ErrorKind = "permission"  // the OS refused access
+	ErrorKindTimeout     ErrorKind = "timeout"     // the action took too long
+	ErrorKindUnsupported ErrorKind = "unsupported" // the button type or option isn't supported
+	ErrorKindInvalid     ErrorKind = "invalid"     // the button's properties are malformed
+	ErrorKindFailed      ErrorKind = "failed"      // anything else
+)
+
+// kindError tags an error with its kind.
+type kindError struct {
+	kind ErrorKind
+	err  error
+}
+
+func (e *kindError) Error() string { return e.err.Error() }
+func (e *kindError) Unwrap() error { return e.err }
+
+// withKind tags err with kind, unless it is nil.
+func withKind(kind ErrorKind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &kindError{kind: kind, err: err}
+}
+
+// errorKindOf classifies err. Explicit tags win, then well-known OS errors.
+func errorKindOf(err error) ErrorKind {
+	var tagged *kindError
+	switch {
+	case errors.As(err, &tagged):
+		return tagged.kind
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, exec.ErrNotFound):
+		return ErrorKindNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return ErrorKindPermission
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded):
+		return ErrorKindTimeout
+	default:
+		return ErrorKindFailed
+	}
+}
+
+// ButtonExecutionResult_Message is published for every executed button and returned to requesters.
+// Actions that continue in the background (macros, run_command waiting for exit) report
+// their outcome on their own subjects; this result covers starting them.
+type ButtonExecutionResult_Message struct {
+	CorrelationID string          `json:"correlation_id,omitempty"`
+	MenuIndex     int             `json:"menu_index"`
+	PageIndex     int             `json:"page_index"`
+	ButtonIndex   int             `json:"button_index"`
+	ButtonType    core.ButtonType `json:"button_type"`
+	ClickType     string          `json:"click_type"`
+	Success       bool            `json:"success"`
+	ErrorKind     ErrorKind       `json:"error_kind,omitempty"`
+	Error         string          `json:"error,omitempty"`
+	DurationMs    int64           `json:"duration_ms"`
+}
+
+// execute runs a button and publishes its result.
+func (a *PieButtonExecutionAdapter) execute(executionInfo *pieButtonExecute_Message) ButtonExecutionResult_Message {
+	started := time.Now()
+	err := a.executeCommand(executionInfo)
+
+	result := ButtonExecutionResult_Message{
+		CorrelationID: executionInfo.CorrelationID,
+		MenuIndex:     executionInfo.MenuIndex,
+		PageIndex:     executionInfo.PageIndex,
+		ButtonIndex:   executionInfo.ButtonIndex,
+		ButtonType:    executionInfo.ButtonType,
+		ClickType:     executionInfo.ClickType,
+		Success:       err == nil,
+		DurationMs:    time.Since(started).Milliseconds(),
+	}
+	if err != nil {
+		result.ErrorKind = errorKindOf(err)
+		result.Error = err.Error()
+		log.Error("Failed to execute command for button %d (Type: %s, %s): %v", executionInfo.ButtonIndex, executionInfo.ButtonType, result.ErrorKind, err)
+	}
+
+	a.natsAdapter.PublishMessage(natsSubjectPieButtonExecuteResult, result)
+	a.recordUsage(executionInfo, result)
+	return result
+}
+
+// handleExecuteRequests executes buttons for callers that await the result. A failed button
+// is still a successful request; the result says what went wrong.
+func (a *PieButtonExecutionAdapter) handleExecuteRequests() {
+	natsAdapter.HandleRequest(a.natsAdapter, natsSubjectPieButtonExecuteRequest,
+		func(message pieButtonExecute_Message) (ButtonExecutionResult_Message, error) {
+			return a.execute(&message), nil
+		})
+}
@@ -0,0 +1,157 @@
+package pieButtonExecutionAdapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core"
+)
+
+// Macro progress statuses. Step 0 reports on the macro as a whole.
+const (
+	MacroStatusRunning   = "running"
+	MacroStatusSucceeded = "succeeded"
+	MacroStatusFailed    = "failed"
+	MacroStatusCancelled = "cancelled"
+)
+
+// MacroProgress_Message reports a macro starting, each of its steps, and how it ended.
+type MacroProgress_Message struct {
+	RunID       int    `json:"run_id"`
+	PageIndex   int    `json:"page_index"`
+	ButtonIndex int    `json:"button_index"`
+	Step        int    `json:"step"` // 1-based; 0 for the macro itself
+	Steps       int    `json:"steps"`
+	StepType    string `json:"step_type,omitempty"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+}
+
+func init() {
+	registerButtonExecutor(core.ButtonTypeMacro, (*PieButtonExecutionAdapter).handleMacro)
+}
+
+func (a *PieButtonExecutionAdapter) handleMacro(executionInfo *pieButtonExecute_Message) error {
+	raw, err := json.Marshal(executionInfo.Properties)
+	if err != nil {
+		return fmt.Errorf("failed to process properties for macro: %w", err)
+	}
+	// Validate up front, so a broken step can't run after the earlier ones already did
+	if err := core.ValidateButton(string(core.ButtonTypeMacro), raw); err != nil {
+		return withKind(ErrorKindInvalid, fmt.Errorf("macro: %w", err))
+	}
+	var props core.MacroProperties
+	if err := json.Unmarshal(raw, &props); err != nil {
+		return fmt.Errorf("failed to process properties for macro: %w", err)
+	}
+
+	log.Info("Button %d - Action: Macro '%s' (%d steps) - ClickType: %s",
+		executionInfo.ButtonIndex, props.ButtonTextUpper, len(props.Steps), executionInfo.ClickType)
+
+	// Only respond to left-click
+	if executionInfo.ClickType != ClickTypeLeftUp {
+		return nil
+	}
+
+	ctx, runID := a.startMacroRun()
+	go a.runMacro(ctx, runID, executionInfo, props.Steps)
+	return nil
+}
+
+// startMacroRun cancels the macro that is still running, if any, and registers a new run.
+func (a *PieButtonExecutionAdapter) startMacroRun() (context.Context, int) {
+	a.macroMu.Lock()
+	defer a.macroMu.Unlock()
+	if a.macroCancel != nil {
+		a.macroCancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	a.macroCancel = cancel
+	a.macroRunID++
+	return ctx, a.macroRunID
+}
+
+// finishMacroRun releases the run's context, unless a newer macro already replaced it.
+func (a *PieButtonExecutionAdapter) finishMacroRun(runID int) {
+	a.macroMu.Lock()
+	defer a.macroMu.Unlock()
+	if a.macroRunID == runID && a.macroCancel != nil {
+		a.macroCancel()
+		a.macroCancel = nil
+	}
+}
+
+func (a *PieButtonExecutionAdapter) runMacro(ctx context.Context, runID int, executionInfo *pieButtonExecute_Message, steps []core.MacroStep) {
+	defer a.finishMacroRun(runID)
+
+	progress := func(step int, stepType, status string, err error) {
+		msg := MacroProgress_Message{
+			RunID:       runID,
+			PageIndex:   executionInfo.PageIndex,
+			ButtonIndex: executionInfo.ButtonIndex,
+			Step:        step,
+			Steps:       len(steps),
+			StepType:    stepType,
+			Status:      status,
+		}
+		if err != nil {
+			msg.Error = err.Error()
+		}
+		a.natsAdapter.PublishMessage(natsSubjectPieButtonMacroProgress, msg)
+	}
+
+	progress(0, "", MacroStatusRunning, nil)
+	var failed error
+	for i, step := range steps {
+		if ctx.Err() != nil {
+			log.Info("Macro run %d cancelled before step %d", runID, i+1)
+			progress(0, "", MacroStatusCancelled, nil)
+			return
+		}
+
+		progress(i+1, step.Type, MacroStatusRunning, nil)
+		err := a.runMacroStep(ctx, executionInfo, step)
+		switch {
+		case err == nil:
+			progress(i+1, step.Type, MacroStatusSucceeded, nil)
+		case ctx.Err() != nil:
+			log.Info("Macro run %d cancelled during step %d", runID, i+1)
+			progress(i+1, step.Type, MacroStatusCancelled, nil)
+			progress(0, "", MacroStatusCancelled, nil)
+			return
+		default:
+			log.Error("Macro run %d: step %d (%s) failed: %v", runID, i+1, step.Type, err)
+			progress(i+1, step.Type, MacroStatusFailed, err)
+			if step.OnError != core.MacroOnErrorContinue {
+				progress(0, "", MacroStatusFailed, fmt.Errorf("step %d: %w", i+1, err))
+				return
+			}
+			failed = fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+
+	// A macro that continued past a failure still reports it at the end
+	if failed != nil {
+		progress(0, "", MacroStatusFailed, failed)
+		return
+	}
+	progress(0, "", MacroStatusSucceeded, nil)
+}
+
+// runMacroStep runs one step as if its button had been left-clicked without modifiers.
+func (a *PieButtonExecutionAdapter) runMacroStep(ctx context.Context, executionInfo *pieButtonExecute_Message, step core.MacroStep) error {
+	if step.Type == core.MacroStepDelay {
+		timer := time.NewTimer(time.Duration(step.DelayMs) * time.Millisecond)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return a.executeCommand(clickActionMessage(executionInfo, core.ClickAction{Type: step.Type, Properties: step.Properties}))
+}
@@ -19,10 +19,7 @@ func (a *PieButtonExecutionAdapter) handlePieButtonExecuteMessage(msg *nats.Msg)
 		return
 	}
 
-	if err := a.executeCommand(&message); err != nil {
-		log.Error("Failed to execute command for button %d (Type: %s): %v", message.ButtonIndex, message.ButtonType, err)
-		// Optionally, publish an error response back via NATS
-	}
+	a.execute(&message)
 }
 
 // handleShortcutPressedMessage stores the mouse coordinates when a shortcut is detected.
@@ -68,6 +65,21 @@ func (a *PieButtonExecutionAdapter) handleWindowUpdateMessage(msg *nats.Msg) {
 	a.mu.Unlock()
 }
 
+// handleFocusedAppMessage remembers the focused app for type_text templates.
+func (a *PieButtonExecutionAdapter) handleFocusedAppMessage(msg *nats.Msg) {
+	var message struct {
+		AppName string `json:"appName"`
+	}
+	if err := json.Unmarshal(msg.Data, &message); err != nil {
+		log.Error("Failed to decode focused app message: %v. Data: %s", err, string(msg.Data))
+		return
+	}
+
+	a.mu.Lock()
+	a.focusedApp = message.AppName
+	a.mu.Unlock()
+}
+
 func (a *PieButtonExecutionAdapter) handleOpenFolder(msg *nats.Msg) {
 	var folderType string
 	if err := json.Unmarshal(msg.Data, &folderType); err != nil {
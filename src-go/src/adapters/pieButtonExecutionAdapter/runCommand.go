@@ -0,0 +1,187 @@
+package pieButtonExecutionAdapter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core"
+)
+
+// maxCommandOutput caps the captured stdout and stderr of a run_command button, each.
+const maxCommandOutput = 4096
+
+// commandWaitDelay is how long a timed-out command's output pipes may stay open after it was
+// killed, e.g. because a child process inherited them.
+const commandWaitDelay = 2 * time.Second
+
+// RunCommandResult_Message is published when a run_command button that waits for its process finished.
+type RunCommandResult_Message struct {
+	PageIndex   int    `json:"page_index"`
+	ButtonIndex int    `json:"button_index"`
+	Executable  string `json:"executable"`
+	ExitCode    int    `json:"exit_code"` // -1 if the process did not exit on its own
+	Stdout      string `json:"stdout,omitempty"`
+	Stderr      string `json:"stderr,omitempty"`
+	Truncated   bool   `json:"truncated"`
+	TimedOut    bool   `json:"timed_out"`
+	Error       string `json:"error,omitempty"`
+	DurationMs  int64  `json:"duration_ms"`
+}
+
+// cappedBuffer keeps the first limit bytes written to it and drops the rest.
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if room := b.limit - b.buf.Len(); len(p) > room {
+		p = p[:max(room, 0)]
+		b.truncated = true
+	}
+	b.buf.Write(p)
+	return n, nil
+}
+
+func (b *cappedBuffer) String() string {
+	// A cut may have split a multi-byte character
+	return strings.ToValidUTF8(b.buf.String(), "")
+}
+
+// runningCommand is a started run_command process.
+type runningCommand struct {
+	cmd     *exec.Cmd
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stdout  *cappedBuffer
+	stderr  *cappedBuffer
+	started time.Time
+}
+
+// startCommand starts the process described by props. Unless the command waits for exit,
+// the returned command is already detached and must not be waited on.
+func startCommand(props core.RunCommandProperties) (*runningCommand, error) {
+	if props.Executable == "" {
+		return nil, withKind(ErrorKindInvalid, fmt.Errorf("no executable set"))
+	}
+
+	wait := props.WaitForExit || props.CaptureOutput
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if wait && props.TimeoutMs > 0 {
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(props.TimeoutMs)*time.Millisecond)
+	}
+
+	cmd := exec.CommandContext(ctx, props.Executable, props.Args...)
+	cmd.Dir = props.WorkingDirectory
+	if len(props.Env) > 0 {
+		// exec uses the last value of a duplicated key, so overrides go after the inherited environment
+		cmd.Env = os.Environ()
+		for name, value := range props.Env {
+			cmd.Env = append(cmd.Env, name+"="+value)
+		}
+	}
+	cmd.WaitDelay = commandWaitDelay
+
+	running := &runningCommand{cmd: cmd, ctx: ctx, cancel: cancel}
+	if props.CaptureOutput {
+		running.stdout = &cappedBuffer{limit: maxCommandOutput}
+		running.stderr = &cappedBuffer{limit: maxCommandOutput}
+		cmd.Stdout, cmd.Stderr = running.stdout, running.stderr
+	}
+
+	running.started = time.Now()
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to start '%s': %w", props.Executable, err)
+	}
+
+	if !wait {
+		// Reap the process in the background; nobody is interested in how it ends
+		go func() {
+			_ = cmd.Wait()
+			cancel()
+		}()
+	}
+	return running, nil
+}
+
+// wait blocks until the process exited or timed out and describes the outcome.
+func (r *runningCommand) wait() RunCommandResult_Message {
+	defer r.cancel()
+	err := r.cmd.Wait()
+
+	result := RunCommandResult_Message{
+		Executable: r.cmd.Path,
+		ExitCode:   r.cmd.ProcessState.ExitCode(),
+		DurationMs: time.Since(r.started).Milliseconds(),
+	}
+	if r.stdout != nil {
+		result.Stdout = r.stdout.String()
+		result.Stderr = r.stderr.String()
+		result.Truncated = r.stdout.truncated || r.stderr.truncated
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		// Exited with code 0
+	case errors.Is(r.ctx.Err(), context.DeadlineExceeded):
+		result.TimedOut = true
+		result.ExitCode = -1 // the exit code of a killed process is whatever the kill set, e.g. 1 on Windows
+		result.Error = "timed out"
+	case errors.As(err, &exitErr):
+		result.Error = fmt.Sprintf("exited with code %d", result.ExitCode)
+	default:
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func init() {
+	registerButtonExecutor(core.ButtonTypeRunCommand, (*PieButtonExecutionAdapter).handleRunCommand)
+}
+
+func (a *PieButtonExecutionAdapter) handleRunCommand(executionInfo *pieButtonExecute_Message) error {
+	var props core.RunCommandProperties
+	if err := unmarshalProperties(executionInfo.Properties, &props); err != nil {
+		return fmt.Errorf("failed to process properties for run_command: %w", err)
+	}
+
+	log.Info("Button %d - Action: RunCommand - ClickType: %s", executionInfo.ButtonIndex, executionInfo.ClickType)
+	log.Info("↳ Command: %s %s", props.Executable, strings.Join(props.Args, " "))
+
+	// Only respond to left-click
+	if executionInfo.ClickType != ClickTypeLeftUp {
+		return nil
+	}
+
+	running, err := startCommand(props)
+	if err != nil {
+		return fmt.Errorf("run_command: %w", err)
+	}
+	if !props.WaitForExit && !props.CaptureOutput {
+		return nil
+	}
+
+	// Wait in the background so a long-running command doesn't hold up other buttons
+	go func() {
+		result := running.wait()
+		result.PageIndex = executionInfo.PageIndex
+		result.ButtonIndex = executionInfo.ButtonIndex
+		if result.Error != "" {
+			log.Warn("Command '%s' (Button %d) failed after %dms: %s", props.Executable, executionInfo.ButtonIndex, result.DurationMs, result.Error)
+		} else {
+			log.Info("Command '%s' (Button %d) finished after %dms", props.Executable, executionInfo.ButtonIndex, result.DurationMs)
+		}
+		a.natsAdapter.PublishMessage(natsSubjectPieButtonCommandResult, result)
+	}()
+	return nil
+}
@@ -0,0 +1,111 @@
+package pieButtonExecutionAdapter
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core"
+)
+
+// shell runs a cmd.exe command line, which every Windows machine has.
+func shell(commandLine string) core.RunCommandProperties {
+	return core.RunCommandProperties{Executable: "cmd", Args: []string{"/c", commandLine}, CaptureOutput: true}
+}
+
+func runToEnd(t *testing.T, props core.RunCommandProperties) RunCommandResult_Message {
+	t.Helper()
+	running, err := startCommand(props)
+	if err != nil {
+		t.Fatalf("startCommand: %v", err)
+	}
+	return running.wait()
+}
+
+func TestRunCommandCapturesOutput(t *testing.T) {
+	result := runToEnd(t, shell("echo hello & echo oops 1>&2"))
+	if result.ExitCode != 0 || result.Error != "" || result.TimedOut {
+		t.Fatalf("got %+v, want a clean exit", result)
+	}
+	if got := strings.TrimSpace(result.Stdout); got != "hello" {
+		t.Errorf("stdout = %q, want %q", got, "hello")
+	}
+	if got := strings.TrimSpace(result.Stderr); got != "oops" {
+		t.Errorf("stderr = %q, want %q", got, "oops")
+	}
+}
+
+func TestRunCommandExitCode(t *testing.T) {
+	result := runToEnd(t, shell("exit 3"))
+	if result.ExitCode != 3 || result.Error != "exited with code 3" {
+		t.Fatalf("got %+v, want exit code 3", result)
+	}
+}
+
+func TestRunCommandEnvAndWorkingDirectory(t *testing.T) {
+	dir := t.TempDir()
+	props := shell("echo %MIGHTYPIE_TEST%& cd")
+	props.Env = map[string]string{"MIGHTYPIE_TEST": "from-button"}
+	props.WorkingDirectory = dir
+
+	result := runToEnd(t, props)
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(result.Stdout, "\r\n", "\n")), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("stdout = %q, want the variable and the directory", result.Stdout)
+	}
+	if strings.TrimSpace(lines[0]) != "from-button" {
+		t.Errorf("variable = %q, want %q", lines[0], "from-button")
+	}
+	if !strings.EqualFold(filepath.Clean(strings.TrimSpace(lines[1])), filepath.Clean(dir)) {
+		t.Errorf("working directory = %q, want %q", lines[1], dir)
+	}
+}
+
+func TestRunCommandTimeout(t *testing.T) {
+	props := shell("ping -n 30 127.0.0.1 >nul")
+	props.TimeoutMs = 200
+
+	result := runToEnd(t, props)
+	if !result.TimedOut || result.ExitCode != -1 {
+		t.Fatalf("got %+v, want a timeout", result)
+	}
+	if result.DurationMs > 10_000 {
+		t.Errorf("took %dms; the timeout did not stop the command", result.DurationMs)
+	}
+}
+
+func TestRunCommandTruncatesOutput(t *testing.T) {
+	result := runToEnd(t, shell("for /L %i in (1,1,1000) do @echo 0123456789"))
+	if !result.Truncated || len(result.Stdout) != maxCommandOutput {
+		t.Fatalf("got %d bytes, truncated=%v; want %d bytes, truncated", len(result.Stdout), result.Truncated, maxCommandOutput)
+	}
+}
+
+func TestStartCommandErrors(t *testing.T) {
+	if _, err := startCommand(core.RunCommandProperties{}); errorKindOf(err) != ErrorKindInvalid {
+		t.Errorf("no executable: got %v (%s), want %s", err, errorKindOf(err), ErrorKindInvalid)
+	}
+	missing := core.RunCommandProperties{Executable: "mightypie-no-such-program.exe"}
+	if _, err := startCommand(missing); errorKindOf(err) != ErrorKindNotFound {
+		t.Errorf("missing executable: got %v (%s), want %s", err, errorKindOf(err), ErrorKindNotFound)
+	}
+}
+
+func TestCappedBuffer(t *testing.T) {
+	b := &cappedBuffer{limit: 5}
+	for _, chunk := range []string{"abc", "def", "ghi"} {
+		if n, err := b.Write([]byte(chunk)); n != len(chunk) || err != nil {
+			t.Fatalf("Write(%q) = %d, %v; want the whole chunk accepted", chunk, n, err)
+		}
+	}
+	if b.String() != "abcde" || !b.truncated {
+		t.Fatalf("got %q, truncated=%v; want %q, truncated", b.String(), b.truncated, "abcde")
+	}
+
+	// A multi-byte character cut in half is dropped rather than left invalid
+	b = &cappedBuffer{limit: 2}
+	b.Write([]byte("aé"))
+	if b.String() != "a" {
+		t.Fatalf("got %q, want %q", b.String(), "a")
+	}
+}
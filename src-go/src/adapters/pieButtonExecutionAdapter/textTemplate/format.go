@@ -0,0 +1,87 @@
+package textTemplate
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// timeTokens maps format tokens to their values, longest tokens first so "MMMM" wins over "MM".
+var timeTokens = []struct {
+	token  string
+	format func(t time.Time) string
+}{
+	{"YYYY", func(t time.Time) string { return fmt.Sprintf("%04d", t.Year()) }},
+	{"MMMM", func(t time.Time) string { return t.Month().String() }},
+	{"dddd", func(t time.Time) string { return t.Weekday().String() }},
+	{"MMM", func(t time.Time) string { return t.Month().String()[:3] }},
+	{"ddd", func(t time.Time) string { return t.Weekday().String()[:3] }},
+	{"YY", func(t time.Time) string { return fmt.Sprintf("%02d", t.Year()%100) }},
+	{"MM", func(t time.Time) string { return fmt.Sprintf("%02d", int(t.Month())) }},
+	{"DD", func(t time.Time) string { return fmt.Sprintf("%02d", t.Day()) }},
+	{"HH", func(t time.Time) string { return fmt.Sprintf("%02d", t.Hour()) }},
+	{"hh", func(t time.Time) string { return fmt.Sprintf("%02d", hour12(t)) }},
+	{"mm", func(t time.Time) string { return fmt.Sprintf("%02d", t.Minute()) }},
+	{"ss", func(t time.Time) string { return fmt.Sprintf("%02d", t.Second()) }},
+	{"M", func(t time.Time) string { return fmt.Sprint(int(t.Month())) }},
+	{"D", func(t time.Time) string { return fmt.Sprint(t.Day()) }},
+	{"H", func(t time.Time) string { return fmt.Sprint(t.Hour()) }},
+	{"h", func(t time.Time) string { return fmt.Sprint(hour12(t)) }},
+	{"m", func(t time.Time) string { return fmt.Sprint(t.Minute()) }},
+	{"s", func(t time.Time) string { return fmt.Sprint(t.Second()) }},
+	{"A", func(t time.Time) string { return amPM(t) }},
+	{"a", func(t time.Time) string { return strings.ToLower(amPM(t)) }},
+}
+
+// FormatTime formats t using tokens common in date pickers rather than Go's reference time:
+//
+//	YYYY YY          year: 2024, 24
+//	MMMM MMM MM M    month: January, Jan, 01, 1
+//	DD D             day of month: 05, 5
+//	dddd ddd         weekday: Friday, Fri
+//	HH H / hh h      hour, 24h / 12h: 09, 9
+//	mm m, ss s       minute, second
+//	A a              AM/PM, am/pm
+//
+// Text in square brackets is copied as is, e.g. "[Week of] MMM D". Other characters are kept.
+func FormatTime(t time.Time, format string) string {
+	var out strings.Builder
+	for rest := format; rest != ""; {
+		if rest[0] == '[' {
+			if end := strings.IndexByte(rest, ']'); end >= 0 {
+				out.WriteString(rest[1:end])
+				rest = rest[end+1:]
+				continue
+			}
+		}
+
+		matched := false
+		for _, tok := range timeTokens {
+			if strings.HasPrefix(rest, tok.token) {
+				out.WriteString(tok.format(t))
+				rest = rest[len(tok.token):]
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			out.WriteByte(rest[0])
+			rest = rest[1:]
+		}
+	}
+	return out.String()
+}
+
+func hour12(t time.Time) int {
+	if h := t.Hour() % 12; h != 0 {
+		return h
+	}
+	return 12
+}
+
+func amPM(t time.Time) string {
+	if t.Hour() < 12 {
+		return "AM"
+	}
+	return "PM"
+}
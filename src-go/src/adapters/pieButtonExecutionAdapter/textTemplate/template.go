@@ -0,0 +1,190 @@
+// Package textTemplate expands the variables in the text of type_text buttons.
+//
+// A variable is written {{name}} or {{name:argument}}; \{{ produces a literal {{.
+//
+//	{{date}}, {{date:DD.MM.YYYY}}    current date, "YYYY-MM-DD" by default
+//	{{time}}, {{time:hh:mm A}}       current time, "HH:mm" by default
+//	{{datetime}}                     current date and time, "YYYY-MM-DD HH:mm" by default
+//	{{clipboard}}                    text on the clipboard
+//	{{app}}                          name of the focused app
+//	{{env:NAME}}                     environment variable NAME
+//
+// Date and time formats are described in FormatTime. The package has no platform dependencies;
+// the executor supplies the clipboard and the focused app through Context.
+package textTemplate
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Context supplies the values of a template's variables.
+type Context struct {
+	Now        time.Time
+	FocusedApp string
+	// Clipboard reads the clipboard text. It is only called if the template uses {{clipboard}}.
+	Clipboard func() (string, error)
+	// Getenv looks up an environment variable. os.LookupEnv if nil.
+	Getenv func(name string) (string, bool)
+}
+
+// Template is a parsed template text.
+type Template struct {
+	segments []segment
+}
+
+// segment is either literal text or a variable.
+type segment struct {
+	literal string
+	name    string
+	arg     string
+}
+
+// variable describes a known variable.
+type variable struct {
+	needsArg   bool
+	allowsArg  bool
+	defaultArg string
+	expand     func(ctx Context, arg string) (string, error)
+}
+
+var variables = map[string]variable{
+	"date": {allowsArg: true, defaultArg: "YYYY-MM-DD", expand: func(ctx Context, arg string) (string, error) {
+		return FormatTime(ctx.Now, arg), nil
+	}},
+	"time": {allowsArg: true, defaultArg: "HH:mm", expand: func(ctx Context, arg string) (string, error) {
+		return FormatTime(ctx.Now, arg), nil
+	}},
+	"datetime": {allowsArg: true, defaultArg: "YYYY-MM-DD HH:mm", expand: func(ctx Context, arg string) (string, error) {
+		return FormatTime(ctx.Now, arg), nil
+	}},
+	"clipboard": {expand: func(ctx Context, _ string) (string, error) {
+		if ctx.Clipboard == nil {
+			return "", fmt.Errorf("clipboard is not available")
+		}
+		text, err := ctx.Clipboard()
+		if err != nil {
+			return "", fmt.Errorf("failed to read clipboard: %w", err)
+		}
+		return text, nil
+	}},
+	"app": {expand: func(ctx Context, _ string) (string, error) {
+		return ctx.FocusedApp, nil
+	}},
+	"env": {needsArg: true, allowsArg: true, expand: func(ctx Context, name string) (string, error) {
+		getenv := ctx.Getenv
+		if getenv == nil {
+			getenv = os.LookupEnv
+		}
+		value, ok := getenv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable '%s' is not set", name)
+		}
+		return value, nil
+	}},
+}
+
+// Parse parses text, rejecting unknown variables and unclosed braces.
+func Parse(text string) (*Template, error) {
+	t := &Template{}
+	var literal strings.Builder
+	for rest := text; rest != ""; {
+		switch {
+		case strings.HasPrefix(rest, `\{{`):
+			literal.WriteString("{{")
+			rest = rest[3:]
+
+		case strings.HasPrefix(rest, "{{"):
+			end := strings.Index(rest, "}}")
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed '{{' at offset %d", len(text)-len(rest))
+			}
+			seg, err := parseVariable(rest[2:end])
+			if err != nil {
+				return nil, err
+			}
+			if literal.Len() > 0 {
+				t.segments = append(t.segments, segment{literal: literal.String()})
+				literal.Reset()
+			}
+			t.segments = append(t.segments, seg)
+			rest = rest[end+2:]
+
+		default:
+			next := strings.Index(rest[1:], "{{")
+			if backslash := strings.Index(rest[1:], `\{{`); backslash >= 0 && (next < 0 || backslash < next) {
+				next = backslash
+			}
+			if next < 0 {
+				literal.WriteString(rest)
+				rest = ""
+			} else {
+				literal.WriteString(rest[:next+1])
+				rest = rest[next+1:]
+			}
+		}
+	}
+	if literal.Len() > 0 {
+		t.segments = append(t.segments, segment{literal: literal.String()})
+	}
+	return t, nil
+}
+
+func parseVariable(body string) (segment, error) {
+	name, arg, hasArg := strings.Cut(body, ":")
+	name = strings.TrimSpace(name)
+	v, ok := variables[name]
+	if !ok {
+		return segment{}, fmt.Errorf("unknown variable '{{%s}}'", body)
+	}
+	switch {
+	case hasArg && !v.allowsArg:
+		return segment{}, fmt.Errorf("variable '%s' takes no argument", name)
+	case v.needsArg && strings.TrimSpace(arg) == "":
+		return segment{}, fmt.Errorf("variable '%s' needs an argument, e.g. {{%s:NAME}}", name, name)
+	case !hasArg:
+		arg = v.defaultArg
+	}
+	if v.needsArg {
+		arg = strings.TrimSpace(arg)
+	}
+	return segment{name: name, arg: arg}, nil
+}
+
+// Uses reports whether the template contains the variable name.
+func (t *Template) Uses(name string) bool {
+	for _, seg := range t.segments {
+		if seg.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Expand substitutes the variables using ctx.
+func (t *Template) Expand(ctx Context) (string, error) {
+	var out strings.Builder
+	for _, seg := range t.segments {
+		if seg.name == "" {
+			out.WriteString(seg.literal)
+			continue
+		}
+		value, err := variables[seg.name].expand(ctx, seg.arg)
+		if err != nil {
+			return "", fmt.Errorf("{{%s}}: %w", seg.name, err)
+		}
+		out.WriteString(value)
+	}
+	return out.String(), nil
+}
+
+// Expand parses and expands text in one go.
+func Expand(text string, ctx Context) (string, error) {
+	t, err := Parse(text)
+	if err != nil {
+		return "", err
+	}
+	return t.Expand(ctx)
+}
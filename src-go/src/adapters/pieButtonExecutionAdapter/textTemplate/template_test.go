@@ -0,0 +1,187 @@
+package textTemplate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// friday is Friday, 5 January 2024, 09:07:03.
+var friday = time.Date(2024, time.January, 5, 9, 7, 3, 0, time.UTC)
+
+func TestFormatTime(t *testing.T) {
+	tests := []struct {
+		name   string
+		at     time.Time
+		format string
+		want   string
+	}{
+		{"iso date", friday, "YYYY-MM-DD", "2024-01-05"},
+		{"short year and unpadded", friday, "YY M D", "24 1 5"},
+		{"month names", friday, "MMMM MMM", "January Jan"},
+		{"weekday names", friday, "dddd ddd", "Friday Fri"},
+		{"24h padded", friday, "HH:mm:ss", "09:07:03"},
+		{"12h unpadded lower", friday, "h:m:s a", "9:7:3 am"},
+		{"12h afternoon", friday.Add(12 * time.Hour), "hh:mm A", "09:07 PM"},
+		{"midnight is 12 AM", time.Date(2024, time.January, 5, 0, 30, 0, 0, time.UTC), "h:mm A", "12:30 AM"},
+		{"24h unpadded", friday, "H", "9"},
+		{"bracketed text", friday, "[Week of] MMM D", "Week of Jan 5"},
+		{"unclosed bracket", friday, "[YYYY", "[2024"},
+		{"other characters", friday, "DD/MM, YYYY!", "05/01, 2024!"},
+		{"empty", friday, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatTime(tt.at, tt.format); got != tt.want {
+				t.Errorf("FormatTime(%q) = %q, want %q", tt.format, got, tt.want)
+			}
+		})
+	}
+}
+
+func testContext() Context {
+	env := map[string]string{"PROJECT": "mightypie", "EMPTY": ""}
+	return Context{
+		Now:        friday,
+		FocusedApp: "Notepad",
+		Clipboard:  func() (string, error) { return "copied text", nil },
+		Getenv: func(name string) (string, bool) {
+			value, ok := env[name]
+			return value, ok
+		},
+	}
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"plain text", "hello", "hello"},
+		{"empty", "", ""},
+		{"date default", "{{date}}", "2024-01-05"},
+		{"time default", "{{time}}", "09:07"},
+		{"datetime default", "{{datetime}}", "2024-01-05 09:07"},
+		{"date with format", "{{date:DD.MM.YYYY}}", "05.01.2024"},
+		{"format containing a colon", "{{time:hh:mm A}}", "09:07 AM"},
+		{"env", "{{env:PROJECT}}", "mightypie"},
+		{"env name is trimmed", "{{env: PROJECT }}", "mightypie"},
+		{"env set but empty", "[{{env:EMPTY}}]", "[]"},
+		{"clipboard", "> {{clipboard}}", "> copied text"},
+		{"focused app", "Sent from {{app}}", "Sent from Notepad"},
+		{"several variables", "{{app}} on {{date:ddd}}: {{clipboard}}", "Notepad on Fri: copied text"},
+		{"escaped braces", `\{{date}}`, "{{date}}"},
+		{"escape next to a variable", `a \{{b {{app}}`, "a {{b Notepad"},
+		{"single braces", "{a} }}", "{a} }}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Expand(tt.text, testContext())
+			if err != nil {
+				t.Fatalf("Expand(%q) failed: %v", tt.text, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expand(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		wantErr string
+	}{
+		{"unknown variable", "{{nope}}", "unknown variable '{{nope}}'"},
+		{"unknown variable with argument", "{{user:name}}", "unknown variable '{{user:name}}'"},
+		{"unclosed", "abc {{date", "unclosed '{{' at offset 4"},
+		{"argument not allowed", "{{app:x}}", "variable 'app' takes no argument"},
+		{"argument missing", "{{env}}", "variable 'env' needs an argument"},
+		{"argument blank", "{{env: }}", "variable 'env' needs an argument"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.text)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Parse(%q) error = %v, want %q", tt.text, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExpandErrors(t *testing.T) {
+	clipboardErr := errors.New("clipboard is locked")
+	tests := []struct {
+		name    string
+		text    string
+		modify  func(ctx *Context)
+		wantErr string
+	}{
+		{"env not set", "{{env:MISSING}}", nil, "environment variable 'MISSING' is not set"},
+		{"no clipboard", "{{clipboard}}", func(ctx *Context) { ctx.Clipboard = nil }, "clipboard is not available"},
+		{"clipboard fails", "{{clipboard}}", func(ctx *Context) {
+			ctx.Clipboard = func() (string, error) { return "", clipboardErr }
+		}, "failed to read clipboard"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := testContext()
+			if tt.modify != nil {
+				tt.modify(&ctx)
+			}
+			_, err := Expand(tt.text, ctx)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Expand(%q) error = %v, want %q", tt.text, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExpandWrapsClipboardError(t *testing.T) {
+	clipboardErr := errors.New("clipboard is locked")
+	ctx := testContext()
+	ctx.Clipboard = func() (string, error) { return "", clipboardErr }
+	if _, err := Expand("{{clipboard}}", ctx); !errors.Is(err, clipboardErr) {
+		t.Fatalf("got %v, want it to wrap %v", err, clipboardErr)
+	}
+}
+
+func TestClipboardOnlyReadWhenUsed(t *testing.T) {
+	ctx := testContext()
+	ctx.Clipboard = func() (string, error) {
+		t.Error("clipboard was read by a template that doesn't use it")
+		return "", nil
+	}
+
+	tmpl, err := Parse("{{app}} {{date}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.Uses("clipboard") {
+		t.Error("Uses(clipboard) = true, want false")
+	}
+	if _, err := tmpl.Expand(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err = Parse(`\{{clipboard}} {{clipboard}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tmpl.Uses("clipboard") {
+		t.Error("Uses(clipboard) = false, want true")
+	}
+}
+
+func TestEnvFallsBackToProcessEnvironment(t *testing.T) {
+	t.Setenv("MIGHTYPIE_TEMPLATE_TEST", "from-process")
+	ctx := testContext()
+	ctx.Getenv = nil
+	got, err := Expand("{{env:MIGHTYPIE_TEMPLATE_TEST}}", ctx)
+	if err != nil || got != "from-process" {
+		t.Fatalf("got %q, %v; want %q", got, err, "from-process")
+	}
+}
@@ -0,0 +1,81 @@
+package pieButtonExecutionAdapter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/adapters/pieButtonExecutionAdapter/textTemplate"
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core"
+	"github.com/go-vgo/robotgo"
+)
+
+// pasteSettleDelay gives the target app time to read the clipboard before it is restored.
+const pasteSettleDelay = 300 * time.Millisecond
+
+func init() {
+	registerButtonExecutor(core.ButtonTypeTypeText, (*PieButtonExecutionAdapter).handleTypeText)
+}
+
+func (a *PieButtonExecutionAdapter) handleTypeText(executionInfo *pieButtonExecute_Message) error {
+	var props core.TypeTextProperties
+	if err := unmarshalProperties(executionInfo.Properties, &props); err != nil {
+		return fmt.Errorf("failed to process properties for type_text: %w", err)
+	}
+
+	log.Info("Button %d - Action: TypeText (%s) - ClickType: %s", executionInfo.ButtonIndex, props.Delivery, executionInfo.ClickType)
+
+	// Only respond to left-click
+	if executionInfo.ClickType != ClickTypeLeftUp {
+		return nil
+	}
+
+	a.mu.RLock()
+	focusedApp := a.focusedApp
+	a.mu.RUnlock()
+
+	text, err := textTemplate.Expand(props.Text, textTemplate.Context{
+		Now:        time.Now(),
+		FocusedApp: focusedApp,
+		Clipboard:  robotgo.ReadAll,
+	})
+	if err != nil {
+		return fmt.Errorf("type_text: %w", err)
+	}
+	if text == "" {
+		return nil
+	}
+
+	releaseAllModifiers()
+	switch props.Delivery {
+	case core.TypeTextDeliveryType:
+		robotgo.TypeStr(text)
+		return nil
+	case core.TypeTextDeliveryPaste:
+		return pasteText(text)
+	default:
+		return withKind(ErrorKindUnsupported, fmt.Errorf("type_text: unknown delivery '%s'", props.Delivery))
+	}
+}
+
+// pasteText pastes text through the clipboard and puts the previous clipboard text back.
+// Only text survives the round trip; an image on the clipboard is lost.
+func pasteText(text string) error {
+	previous, readErr := robotgo.ReadAll()
+
+	if err := robotgo.WriteAll(text); err != nil {
+		return fmt.Errorf("type_text: failed to write clipboard: %w", err)
+	}
+	if err := robotgo.KeyTap("v", "ctrl"); err != nil {
+		return fmt.Errorf("type_text: failed to paste: %w", err)
+	}
+
+	if readErr != nil {
+		log.Warn("Could not read the clipboard before pasting, it is not restored: %v", readErr)
+		return nil
+	}
+	time.Sleep(pasteSettleDelay)
+	if err := robotgo.WriteAll(previous); err != nil {
+		log.Warn("Failed to restore the clipboard after pasting: %v", err)
+	}
+	return nil
+}
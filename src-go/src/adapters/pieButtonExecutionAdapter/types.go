@@ -5,9 +5,9 @@ import (
 )
 
 const (
-	ClickTypeLeftUp   = "left_up"
-	ClickTypeRightUp  = "right_up"
-	ClickTypeMiddleUp = "middle_up"
+	ClickTypeLeftUp   = core.ClickTypeLeftUp
+	ClickTypeRightUp  = core.ClickTypeRightUp
+	ClickTypeMiddleUp = core.ClickTypeMiddleUp
 )
 
 // Message type for pie button execution
@@ -17,4 +17,10 @@ type pieButtonExecute_Message struct {
 	ButtonType  core.ButtonType `json:"button_type"`
 	Properties  any             `json:"properties"`
 	ClickType   string          `json:"click_type"`
+	// Modifiers held during the click, e.g. "shift". If nil, the executor reads the keyboard.
+	Modifiers []string `json:"modifiers,omitempty"`
+	// MenuIndex locates the button together with PageIndex and ButtonIndex in results.
+	MenuIndex int `json:"menu_index"`
+	// CorrelationID is echoed in the execution result.
+	CorrelationID string `json:"correlation_id,omitempty"`
 }
@@ -0,0 +1,335 @@
+package pieButtonExecutionAdapter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/adapters/natsAdapter"
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core"
+)
+
+var (
+	natsSubjectPieButtonUsageStats  = os.Getenv("PUBLIC_NATSSUBJECT_PIEBUTTON_USAGE_STATS")
+	natsSubjectPieButtonUsageExport = os.Getenv("PUBLIC_NATSSUBJECT_PIEBUTTON_USAGE_EXPORT")
+)
+
+// Defaults for usage requests that leave a field at 0.
+const (
+	defaultUsageDays = 30
+	defaultUsageTop  = 10
+)
+
+const usageDateLayout = "2006-01-02"
+
+// UsageStatsRequest asks for usage rollups.
+type UsageStatsRequest struct {
+	Days       int `json:"days"`        // window for per-day counts and rankings; 0 means 30
+	Top        int `json:"top"`         // length of the most and least used lists; 0 means 10
+	UnusedDays int `json:"unused_days"` // configured buttons not clicked for this many days are unused; 0 means Days
+}
+
+// DailyUsage counts the executions of one local calendar day.
+type DailyUsage struct {
+	Date  string `json:"date"` // YYYY-MM-DD
+	Count int    `json:"count"`
+}
+
+// ButtonUsage sums up the executions of one button slot. Type and target are the latest seen.
+type ButtonUsage struct {
+	MenuIndex    int       `json:"menu_index"`
+	PageIndex    int       `json:"page_index"`
+	ButtonIndex  int       `json:"button_index"`
+	ButtonType   string    `json:"button_type"`
+	Target       string    `json:"target,omitempty"`
+	Count        int       `json:"count"`
+	Failures     int       `json:"failures"`
+	AvgLatencyMs int64     `json:"avg_latency_ms"`
+	LastUsed     time.Time `json:"last_used,omitzero"` // zero if never used
+}
+
+// UsageStats is the reply to a UsageStatsRequest.
+type UsageStats struct {
+	From      time.Time     `json:"from"`
+	To        time.Time     `json:"to"`
+	Total     int           `json:"total"`
+	PerDay    []DailyUsage  `json:"per_day"`    // every day of the window, oldest first
+	MostUsed  []ButtonUsage `json:"most_used"`  // buttons used in the window, most first
+	LeastUsed []ButtonUsage `json:"least_used"` // buttons used in the window, fewest first
+	// Unused lists configured buttons not clicked for UnusedDays, with their last use if any.
+	// It is empty until the worker has received a pie menu config.
+	Unused []ButtonUsage `json:"unused"`
+}
+
+// Usage export reports and formats.
+const (
+	UsageReportExecutions = "executions"
+	UsageReportDaily      = "daily"
+	UsageReportButtons    = "buttons"
+
+	UsageFormatCSV  = "csv"
+	UsageFormatJSON = "json"
+)
+
+// UsageExportRequest asks for a usage report as a CSV or JSON document.
+type UsageExportRequest struct {
+	Report string `json:"report"` // executions, daily or buttons
+	Format string `json:"format"` // csv or json
+	Days   int    `json:"days"`   // 0 means 30
+}
+
+// UsageExport is the reply to a UsageExportRequest.
+type UsageExport struct {
+	Report  string `json:"report"`
+	Format  string `json:"format"`
+	Content string `json:"content"`
+}
+
+// handleUsageRequests answers usage stats and export requests.
+func (a *PieButtonExecutionAdapter) handleUsageRequests() {
+	natsAdapter.HandleRequest(a.natsAdapter, natsSubjectPieButtonUsageStats,
+		func(req UsageStatsRequest) (UsageStats, error) {
+			records, configured := a.usage.snapshot()
+			return computeUsageStats(records, configured, req, time.Now()), nil
+		})
+	natsAdapter.HandleRequest(a.natsAdapter, natsSubjectPieButtonUsageExport,
+		func(req UsageExportRequest) (UsageExport, error) {
+			records, configured := a.usage.snapshot()
+			return exportUsage(records, configured, req, time.Now())
+		})
+}
+
+// recordUsage adds an executed button to the usage log. Executions with a negative index don't
+// come from a configured button, e.g. functions called over the HTTP API, and aren't counted.
+func (a *PieButtonExecutionAdapter) recordUsage(executionInfo *pieButtonExecute_Message, result ButtonExecutionResult_Message) {
+	if result.MenuIndex < 0 || result.PageIndex < 0 || result.ButtonIndex < 0 {
+		return
+	}
+	a.usage.record(UsageRecord{
+		MenuIndex:   result.MenuIndex,
+		PageIndex:   result.PageIndex,
+		ButtonIndex: result.ButtonIndex,
+		ButtonType:  string(result.ButtonType),
+		Target:      usageTarget(executionInfo),
+		ClickType:   result.ClickType,
+		LatencyMs:   result.DurationMs,
+		ErrorKind:   result.ErrorKind,
+	})
+}
+
+// startOfDay returns local midnight of t's day.
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// usageWindow returns the start of the window covering the last days calendar days, today included.
+func usageWindow(now time.Time, days int) time.Time {
+	if days <= 0 {
+		days = defaultUsageDays
+	}
+	return startOfDay(now).AddDate(0, 0, 1-days)
+}
+
+// sumButtonUsage sums records per button slot. Type and target come from the last record in
+// log order, LastUsed is the latest time.
+func sumButtonUsage(records []UsageRecord) map[buttonSlot]*ButtonUsage {
+	totalLatency := make(map[buttonSlot]int64)
+	buttons := make(map[buttonSlot]*ButtonUsage)
+	for _, record := range records {
+		slot := buttonSlot{record.MenuIndex, record.PageIndex, record.ButtonIndex}
+		usage, ok := buttons[slot]
+		if !ok {
+			usage = &ButtonUsage{MenuIndex: slot.MenuIndex, PageIndex: slot.PageIndex, ButtonIndex: slot.ButtonIndex}
+			buttons[slot] = usage
+		}
+		usage.ButtonType = record.ButtonType
+		usage.Target = record.Target
+		usage.Count++
+		if record.ErrorKind != "" {
+			usage.Failures++
+		}
+		if record.Time.After(usage.LastUsed) {
+			usage.LastUsed = record.Time
+		}
+		totalLatency[slot] += record.LatencyMs
+		usage.AvgLatencyMs = totalLatency[slot] / int64(usage.Count)
+	}
+	return buttons
+}
+
+// since returns the records at or after from, in log order. The log is in the order executions
+// happened, but the wall clock they are stamped with can go back, e.g. when the system time is
+// corrected, so every record is checked.
+func since(records []UsageRecord, from time.Time) []UsageRecord {
+	var window []UsageRecord
+	for _, record := range records {
+		if !record.Time.Before(from) {
+			window = append(window, record)
+		}
+	}
+	return window
+}
+
+// compareSlots orders buttons by menu, page and button.
+func compareSlots(a, b ButtonUsage) int {
+	if a.MenuIndex != b.MenuIndex {
+		return a.MenuIndex - b.MenuIndex
+	}
+	if a.PageIndex != b.PageIndex {
+		return a.PageIndex - b.PageIndex
+	}
+	return a.ButtonIndex - b.ButtonIndex
+}
+
+// unusedButtons lists the configured buttons without executions since from.
+func unusedButtons(records []UsageRecord, configured map[buttonSlot]string, from time.Time) []ButtonUsage {
+	allTime := sumButtonUsage(records)
+	recent := sumButtonUsage(since(records, from))
+
+	unused := []ButtonUsage{}
+	for slot, buttonType := range configured {
+		if buttonType == string(core.ButtonTypeDisabled) || recent[slot] != nil {
+			continue
+		}
+		usage := ButtonUsage{MenuIndex: slot.MenuIndex, PageIndex: slot.PageIndex, ButtonIndex: slot.ButtonIndex, ButtonType: buttonType}
+		if earlier := allTime[slot]; earlier != nil {
+			usage.Target = earlier.Target
+			usage.LastUsed = earlier.LastUsed
+		}
+		unused = append(unused, usage)
+	}
+	slices.SortFunc(unused, compareSlots)
+	return unused
+}
+
+func computeUsageStats(records []UsageRecord, configured map[buttonSlot]string, req UsageStatsRequest, now time.Time) UsageStats {
+	top := req.Top
+	if top <= 0 {
+		top = defaultUsageTop
+	}
+	unusedDays := req.UnusedDays
+	if unusedDays <= 0 {
+		unusedDays = req.Days
+	}
+
+	from := usageWindow(now, req.Days)
+	window := since(records, from)
+	stats := UsageStats{From: from, To: now, Total: len(window), PerDay: dailyUsage(window, from, now)}
+
+	var used []ButtonUsage
+	for _, usage := range sumButtonUsage(window) {
+		used = append(used, *usage)
+	}
+	// Ties go to the lower slot, so the lists are stable between requests
+	slices.SortFunc(used, func(a, b ButtonUsage) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return compareSlots(a, b)
+	})
+	stats.MostUsed = used[:min(top, len(used))]
+	stats.LeastUsed = make([]ButtonUsage, 0, min(top, len(used)))
+	for i := len(used) - 1; i >= 0 && len(stats.LeastUsed) < top; i-- {
+		stats.LeastUsed = append(stats.LeastUsed, used[i])
+	}
+	if stats.MostUsed == nil {
+		stats.MostUsed = []ButtonUsage{}
+	}
+
+	stats.Unused = []ButtonUsage{}
+	if configured != nil {
+		stats.Unused = unusedButtons(records, configured, usageWindow(now, unusedDays))
+	}
+	return stats
+}
+
+// dailyUsage counts the records per day from the day of from through the day of now.
+func dailyUsage(records []UsageRecord, from, now time.Time) []DailyUsage {
+	counts := make(map[string]int)
+	for _, record := range records {
+		counts[record.Time.In(now.Location()).Format(usageDateLayout)]++
+	}
+	var days []DailyUsage
+	for day := startOfDay(from); !day.After(now); day = day.AddDate(0, 0, 1) {
+		date := day.Format(usageDateLayout)
+		days = append(days, DailyUsage{Date: date, Count: counts[date]})
+	}
+	return days
+}
+
+// exportUsage renders one usage report.
+func exportUsage(records []UsageRecord, configured map[buttonSlot]string, req UsageExportRequest, now time.Time) (UsageExport, error) {
+	export := UsageExport{Report: req.Report, Format: req.Format}
+	if req.Format != UsageFormatCSV && req.Format != UsageFormatJSON {
+		return export, fmt.Errorf("unknown format '%s', expected %s or %s", req.Format, UsageFormatCSV, UsageFormatJSON)
+	}
+
+	from := usageWindow(now, req.Days)
+	window := since(records, from)
+
+	var rows any
+	var table [][]string
+	switch req.Report {
+	case UsageReportExecutions:
+		rows = append([]UsageRecord{}, window...)
+		table = append(table, []string{"time", "menu_index", "page_index", "button_index", "button_type", "target", "click_type", "latency_ms", "error_kind"})
+		for _, r := range window {
+			table = append(table, []string{
+				r.Time.Format(time.RFC3339), strconv.Itoa(r.MenuIndex), strconv.Itoa(r.PageIndex), strconv.Itoa(r.ButtonIndex),
+				r.ButtonType, r.Target, r.ClickType, strconv.FormatInt(r.LatencyMs, 10), string(r.ErrorKind),
+			})
+		}
+	case UsageReportDaily:
+		daily := dailyUsage(window, from, now)
+		rows = daily
+		table = append(table, []string{"date", "count"})
+		for _, d := range daily {
+			table = append(table, []string{d.Date, strconv.Itoa(d.Count)})
+		}
+	case UsageReportButtons:
+		// Every button used in the window, plus configured buttons that weren't
+		buttons := []ButtonUsage{}
+		for _, usage := range sumButtonUsage(window) {
+			buttons = append(buttons, *usage)
+		}
+		buttons = append(buttons, unusedButtons(records, configured, from)...)
+		slices.SortFunc(buttons, compareSlots)
+		rows = buttons
+		table = append(table, []string{"menu_index", "page_index", "button_index", "button_type", "target", "count", "failures", "avg_latency_ms", "last_used"})
+		for _, b := range buttons {
+			lastUsed := ""
+			if !b.LastUsed.IsZero() {
+				lastUsed = b.LastUsed.Format(time.RFC3339)
+			}
+			table = append(table, []string{
+				strconv.Itoa(b.MenuIndex), strconv.Itoa(b.PageIndex), strconv.Itoa(b.ButtonIndex), b.ButtonType, b.Target,
+				strconv.Itoa(b.Count), strconv.Itoa(b.Failures), strconv.FormatInt(b.AvgLatencyMs, 10), lastUsed,
+			})
+		}
+	default:
+		return export, fmt.Errorf("unknown report '%s', expected %s, %s or %s", req.Report, UsageReportExecutions, UsageReportDaily, UsageReportButtons)
+	}
+
+	if req.Format == UsageFormatJSON {
+		data, err := json.MarshalIndent(rows, "", "  ")
+		if err != nil {
+			return export, err
+		}
+		export.Content = string(data)
+		return export, nil
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.WriteAll(table); err != nil {
+		return export, err
+	}
+	export.Content = buf.String()
+	return export, nil
+}
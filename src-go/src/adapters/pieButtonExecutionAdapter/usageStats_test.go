@@ -0,0 +1,316 @@
+package pieButtonExecutionAdapter
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// usageNow is Friday, 5 January 2024, 18:00 UTC.
+var usageNow = time.Date(2024, time.January, 5, 18, 0, 0, 0, time.UTC)
+
+// used returns an execution of slot at the given time.
+func used(at time.Time, slot buttonSlot) UsageRecord {
+	return UsageRecord{
+		Time: at, MenuIndex: slot.MenuIndex, PageIndex: slot.PageIndex, ButtonIndex: slot.ButtonIndex,
+		ButtonType: "show_any_window", Target: "Notepad", ClickType: "left_up", LatencyMs: 10,
+	}
+}
+
+// slotsOf lists the slots of buttons, in order.
+func slotsOf(buttons []ButtonUsage) []buttonSlot {
+	slots := []buttonSlot{}
+	for _, b := range buttons {
+		slots = append(slots, buttonSlot{b.MenuIndex, b.PageIndex, b.ButtonIndex})
+	}
+	return slots
+}
+
+func TestUsageWindow(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, time.January, d, 0, 0, 0, 0, time.UTC) }
+	slot := buttonSlot{0, 0, 0}
+	records := []UsageRecord{
+		used(day(3).Add(-time.Nanosecond), slot), // just before a 3-day window
+		used(day(3), slot),
+		used(day(4).Add(23*time.Hour), slot),
+		used(usageNow, slot),
+	}
+
+	tests := []struct {
+		name       string
+		days       int
+		wantFrom   time.Time
+		wantTotal  int
+		wantPerDay []DailyUsage
+	}{
+		{"today only", 1, day(5), 1, []DailyUsage{{"2024-01-05", 1}}},
+		{"three days", 3, day(3), 3, []DailyUsage{{"2024-01-03", 1}, {"2024-01-04", 1}, {"2024-01-05", 1}}},
+		{"four days", 4, day(2), 4, []DailyUsage{{"2024-01-02", 1}, {"2024-01-03", 1}, {"2024-01-04", 1}, {"2024-01-05", 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := computeUsageStats(records, nil, UsageStatsRequest{Days: tt.days}, usageNow)
+			if !stats.From.Equal(tt.wantFrom) || !stats.To.Equal(usageNow) {
+				t.Errorf("window = %v to %v, want %v to %v", stats.From, stats.To, tt.wantFrom, usageNow)
+			}
+			if stats.Total != tt.wantTotal {
+				t.Errorf("Total = %d, want %d", stats.Total, tt.wantTotal)
+			}
+			if !slices.Equal(stats.PerDay, tt.wantPerDay) {
+				t.Errorf("PerDay = %v, want %v", stats.PerDay, tt.wantPerDay)
+			}
+		})
+	}
+
+	t.Run("default is 30 days", func(t *testing.T) {
+		stats := computeUsageStats(nil, nil, UsageStatsRequest{}, usageNow)
+		if len(stats.PerDay) != defaultUsageDays || stats.PerDay[0].Date != "2023-12-07" || stats.PerDay[29].Date != "2024-01-05" {
+			t.Errorf("PerDay = %v, want the 30 days up to 2024-01-05", stats.PerDay)
+		}
+	})
+}
+
+func TestDailyUsageCountsLocalDays(t *testing.T) {
+	// 23:30 UTC on the 4th is already the 5th at UTC+2
+	zone := time.FixedZone("UTC+2", 2*60*60)
+	now := usageNow.In(zone)
+	records := []UsageRecord{used(time.Date(2024, time.January, 4, 23, 30, 0, 0, time.UTC), buttonSlot{})}
+	got := dailyUsage(records, startOfDay(now).AddDate(0, 0, -1), now)
+	want := []DailyUsage{{"2024-01-04", 0}, {"2024-01-05", 1}}
+	if !slices.Equal(got, want) {
+		t.Errorf("dailyUsage = %v, want %v", got, want)
+	}
+}
+
+func TestSince(t *testing.T) {
+	at := func(hour int) time.Time { return time.Date(2024, time.January, 5, hour, 0, 0, 0, time.UTC) }
+	from := at(10)
+	tests := []struct {
+		name  string
+		hours []int
+		want  []int
+	}{
+		{"empty", nil, nil},
+		{"in time order", []int{8, 9, 10, 11}, []int{10, 11}},
+		{"all before", []int{8, 9}, nil},
+		{"clock set back", []int{11, 8, 12, 9}, []int{11, 12}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var records []UsageRecord
+			for _, hour := range tt.hours {
+				records = append(records, used(at(hour), buttonSlot{}))
+			}
+			var got []int
+			for _, record := range since(records, from) {
+				got = append(got, record.Time.Hour())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("since = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUsageRanking(t *testing.T) {
+	a, b, c, d := buttonSlot{0, 0, 0}, buttonSlot{0, 0, 1}, buttonSlot{0, 1, 0}, buttonSlot{1, 0, 0}
+	hour := func(h int) time.Time { return usageNow.Add(-time.Duration(h) * time.Hour) }
+	records := []UsageRecord{
+		used(hour(9), c), used(hour(8), a), used(hour(7), d), used(hour(6), a),
+		used(hour(5), b), used(hour(4), d), used(hour(3), a),
+	}
+
+	tests := []struct {
+		name      string
+		top       int
+		wantMost  []buttonSlot
+		wantLeast []buttonSlot
+	}{
+		// Ties go to the lower slot in the most used list, so the least used list ends with it
+		{"top 2", 2, []buttonSlot{a, d}, []buttonSlot{c, b}},
+		{"default top", 0, []buttonSlot{a, d, b, c}, []buttonSlot{c, b, d, a}},
+		{"top above count", 10, []buttonSlot{a, d, b, c}, []buttonSlot{c, b, d, a}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := computeUsageStats(records, nil, UsageStatsRequest{Days: 1, Top: tt.top}, usageNow)
+			if got := slotsOf(stats.MostUsed); !slices.Equal(got, tt.wantMost) {
+				t.Errorf("MostUsed = %v, want %v", got, tt.wantMost)
+			}
+			if got := slotsOf(stats.LeastUsed); !slices.Equal(got, tt.wantLeast) {
+				t.Errorf("LeastUsed = %v, want %v", got, tt.wantLeast)
+			}
+		})
+	}
+
+	t.Run("sums", func(t *testing.T) {
+		failed := used(hour(2), a)
+		failed.ErrorKind = ErrorKindTimeout
+		failed.LatencyMs = 50
+		failed.Target = "Notepad++"
+		stats := computeUsageStats(append(records, failed), nil, UsageStatsRequest{Days: 1}, usageNow)
+		got := stats.MostUsed[0]
+		want := ButtonUsage{ButtonType: "show_any_window", Target: "Notepad++", Count: 4, Failures: 1, AvgLatencyMs: 20, LastUsed: hour(2)}
+		if got != want {
+			t.Errorf("MostUsed[0] = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("no records", func(t *testing.T) {
+		stats := computeUsageStats(nil, nil, UsageStatsRequest{}, usageNow)
+		data, err := json.Marshal(stats)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, field := range []string{`"most_used":[]`, `"least_used":[]`, `"unused":[]`} {
+			if !strings.Contains(string(data), field) {
+				t.Errorf("stats %s lack %s", data, field)
+			}
+		}
+	})
+}
+
+func TestUnusedButtons(t *testing.T) {
+	oldUse, recentUse, neverUsed, disabled := buttonSlot{0, 0, 0}, buttonSlot{0, 0, 1}, buttonSlot{0, 0, 5}, buttonSlot{2, 0, 0}
+	configured := map[buttonSlot]string{
+		oldUse:    "show_any_window",
+		recentUse: "show_any_window",
+		neverUsed: "call_function",
+		disabled:  "disabled",
+	}
+	records := []UsageRecord{
+		used(usageNow.AddDate(0, 0, -40), oldUse),
+		used(usageNow.AddDate(0, 0, -5), recentUse),
+	}
+
+	tests := []struct {
+		name       string
+		req        UsageStatsRequest
+		configured map[buttonSlot]string
+		want       []buttonSlot
+	}{
+		{"defaults to 30 days", UsageStatsRequest{}, configured, []buttonSlot{oldUse, neverUsed}},
+		{"defaults to days", UsageStatsRequest{Days: 3}, configured, []buttonSlot{oldUse, recentUse, neverUsed}},
+		{"unused days", UsageStatsRequest{Days: 3, UnusedDays: 60}, configured, []buttonSlot{neverUsed}},
+		{"no config yet", UsageStatsRequest{}, nil, []buttonSlot{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := computeUsageStats(records, tt.configured, tt.req, usageNow)
+			if got := slotsOf(stats.Unused); !slices.Equal(got, tt.want) {
+				t.Errorf("Unused = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("last use", func(t *testing.T) {
+		stats := computeUsageStats(records, configured, UsageStatsRequest{}, usageNow)
+		old, never := stats.Unused[0], stats.Unused[1]
+		if !old.LastUsed.Equal(records[0].Time) || old.Target != "Notepad" || old.Count != 0 {
+			t.Errorf("Unused[0] = %+v, want no count and the use 40 days ago", old)
+		}
+		if !never.LastUsed.IsZero() || never.ButtonType != "call_function" {
+			t.Errorf("Unused[1] = %+v, want a call_function button never used", never)
+		}
+	})
+}
+
+func TestExportUsage(t *testing.T) {
+	usedSlot, unusedSlot := buttonSlot{0, 1, 2}, buttonSlot{1, 0, 0}
+	configured := map[buttonSlot]string{usedSlot: "show_any_window", unusedSlot: "call_function"}
+	failed := UsageRecord{
+		Time: usageNow.Add(-time.Hour), MenuIndex: 0, PageIndex: 1, ButtonIndex: 2, ButtonType: "show_any_window",
+		Target: "Notepad, Inc.", ClickType: "left_up", LatencyMs: 12, ErrorKind: ErrorKindNotFound,
+	}
+	records := []UsageRecord{failed}
+
+	tests := []struct {
+		name    string
+		records []UsageRecord
+		req     UsageExportRequest
+		want    string
+	}{
+		{
+			"executions csv",
+			records,
+			UsageExportRequest{Report: UsageReportExecutions, Format: UsageFormatCSV, Days: 1},
+			"time,menu_index,page_index,button_index,button_type,target,click_type,latency_ms,error_kind\n" +
+				"2024-01-05T17:00:00Z,0,1,2,show_any_window,\"Notepad, Inc.\",left_up,12,not_found\n",
+		},
+		{
+			"daily csv",
+			records,
+			UsageExportRequest{Report: UsageReportDaily, Format: UsageFormatCSV, Days: 2},
+			"date,count\n2024-01-04,0\n2024-01-05,1\n",
+		},
+		{
+			"buttons csv",
+			records,
+			UsageExportRequest{Report: UsageReportButtons, Format: UsageFormatCSV, Days: 1},
+			"menu_index,page_index,button_index,button_type,target,count,failures,avg_latency_ms,last_used\n" +
+				"0,1,2,show_any_window,\"Notepad, Inc.\",1,1,12,2024-01-05T17:00:00Z\n" +
+				"1,0,0,call_function,,0,0,0,\n",
+		},
+		{
+			"daily json",
+			records,
+			UsageExportRequest{Report: UsageReportDaily, Format: UsageFormatJSON, Days: 1},
+			"[\n  {\n    \"date\": \"2024-01-05\",\n    \"count\": 1\n  }\n]",
+		},
+		{
+			"executions json without executions",
+			nil,
+			UsageExportRequest{Report: UsageReportExecutions, Format: UsageFormatJSON, Days: 1},
+			"[]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			export, err := exportUsage(tt.records, configured, tt.req, usageNow)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if export.Report != tt.req.Report || export.Format != tt.req.Format {
+				t.Errorf("export is %s/%s, want %s/%s", export.Report, export.Format, tt.req.Report, tt.req.Format)
+			}
+			if export.Content != tt.want {
+				t.Errorf("content = %q, want %q", export.Content, tt.want)
+			}
+		})
+	}
+
+	t.Run("buttons json", func(t *testing.T) {
+		export, err := exportUsage(records, configured, UsageExportRequest{Report: UsageReportButtons, Format: UsageFormatJSON}, usageNow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buttons []ButtonUsage
+		if err := json.Unmarshal([]byte(export.Content), &buttons); err != nil {
+			t.Fatalf("unreadable content %s: %v", export.Content, err)
+		}
+		if got := slotsOf(buttons); !slices.Equal(got, []buttonSlot{usedSlot, unusedSlot}) || buttons[0].Failures != 1 {
+			t.Errorf("buttons = %+v, want the used button with its failure, then the unused one", buttons)
+		}
+	})
+}
+
+func TestExportUsageErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     UsageExportRequest
+		wantErr string
+	}{
+		{"unknown format", UsageExportRequest{Report: UsageReportDaily, Format: "xml"}, "unknown format 'xml'"},
+		{"unknown report", UsageExportRequest{Report: "weekly", Format: UsageFormatCSV}, "unknown report 'weekly'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := exportUsage(nil, nil, tt.req, usageNow)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("exportUsage error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package pieButtonExecutionAdapter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core"
+	"github.com/nats-io/nats.go"
+)
+
+// usageRetention is how long executions are kept. Older ones are dropped on startup.
+const usageRetention = 400 * 24 * time.Hour
+
+// UsageRecord is one button execution in the usage log (JSON lines, short keys to keep it small).
+type UsageRecord struct {
+	Time        time.Time `json:"t"`
+	MenuIndex   int       `json:"m"`
+	PageIndex   int       `json:"p"`
+	ButtonIndex int       `json:"b"`
+	ButtonType  string    `json:"type"`
+	Target      string    `json:"target,omitempty"` // app, function, file or command the button acts on
+	ClickType   string    `json:"click"`
+	LatencyMs   int64     `json:"ms"`
+	ErrorKind   ErrorKind `json:"err,omitempty"` // empty if the execution succeeded
+}
+
+// buttonSlot is a button's place in the pie menu config.
+type buttonSlot struct {
+	MenuIndex   int
+	PageIndex   int
+	ButtonIndex int
+}
+
+// usageStore keeps the usage log in memory and appends new executions to its file.
+type usageStore struct {
+	mu         sync.Mutex
+	path       string
+	records    []UsageRecord
+	configured map[buttonSlot]string // button type per configured slot; nil until a config arrives
+}
+
+// newUsageStore loads the usage log from the AppData directory. Without a usable path,
+// executions are still counted for the lifetime of the worker.
+func newUsageStore() *usageStore {
+	s := &usageStore{}
+	rel := os.Getenv("PUBLIC_DIR_BUTTONUSAGE")
+	if rel == "" {
+		log.Warn("PUBLIC_DIR_BUTTONUSAGE is not set; button usage is not saved")
+		return s
+	}
+	appDataDir, err := core.GetAppDataDir()
+	if err != nil {
+		log.Warn("Failed to resolve app data dir for button usage: %v", err)
+		return s
+	}
+	s.path = filepath.Join(appDataDir, rel)
+
+	if err := s.load(time.Now().Add(-usageRetention)); err != nil {
+		log.Error("Failed to load button usage from '%s': %v", s.path, err)
+	}
+	return s
+}
+
+// load reads the usage log, dropping executions before cutoff and lines it can't parse.
+// The file is rewritten if anything was dropped.
+func (s *usageStore) load(cutoff time.Time) error {
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	dropped := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var record UsageRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil || record.Time.Before(cutoff) {
+			dropped++
+			continue
+		}
+		s.records = append(s.records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	log.Info("Loaded %d button executions from '%s'", len(s.records), s.path)
+
+	if dropped > 0 {
+		log.Info("Dropping %d expired or unreadable button executions", dropped)
+		return s.rewrite()
+	}
+	return nil
+}
+
+// rewrite replaces the usage log with the records in memory.
+func (s *usageStore) rewrite() error {
+	var buf bytes.Buffer
+	for _, record := range s.records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		buf.Write(append(data, '\n'))
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// record timestamps an execution and appends it to the usage log. Stamping under the lock
+// keeps the log in the order executions happened.
+func (s *usageStore) record(record UsageRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record.Time = time.Now()
+	s.records = append(s.records, record)
+	if s.path == "" {
+		return
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		log.Error("Failed to encode button usage: %v", err)
+		return
+	}
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		log.Error("Failed to open button usage '%s': %v", s.path, err)
+		return
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		log.Error("Failed to write button usage: %v", err)
+	}
+}
+
+// snapshot returns the records and configured buttons for a rollup.
+func (s *usageStore) snapshot() ([]UsageRecord, map[buttonSlot]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]UsageRecord(nil), s.records...), s.configured
+}
+
+// setConfigured replaces the configured buttons, used to find buttons nobody clicks.
+func (s *usageStore) setConfigured(configured map[buttonSlot]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.configured = configured
+}
+
+// handleConfigUpdateMessage keeps track of which buttons are configured.
+func (a *PieButtonExecutionAdapter) handleConfigUpdateMessage(msg *nats.Msg) {
+	// Menu ID -> page ID -> button ID
+	var payload struct {
+		Buttons map[string]map[string]map[string]struct {
+			ButtonType string `json:"button_type"`
+		} `json:"buttons"`
+	}
+	if err := json.Unmarshal(msg.Data, &payload); err != nil {
+		log.Error("Failed to decode full config for button usage: %v", err)
+		return
+	}
+
+	configured := make(map[buttonSlot]string)
+	for menuID, pages := range payload.Buttons {
+		for pageID, buttons := range pages {
+			for buttonID, button := range buttons {
+				slot, err := parseButtonSlot(menuID, pageID, buttonID)
+				if err != nil {
+					log.Warn("Skipping button with a malformed ID in usage tracking: %v", err)
+					continue
+				}
+				configured[slot] = button.ButtonType
+			}
+		}
+	}
+	a.usage.setConfigured(configured)
+}
+
+func parseButtonSlot(menuID, pageID, buttonID string) (buttonSlot, error) {
+	var slot buttonSlot
+	var err error
+	if slot.MenuIndex, err = strconv.Atoi(menuID); err != nil {
+		return slot, fmt.Errorf("menu '%s': %w", menuID, err)
+	}
+	if slot.PageIndex, err = strconv.Atoi(pageID); err != nil {
+		return slot, fmt.Errorf("page '%s': %w", pageID, err)
+	}
+	if slot.ButtonIndex, err = strconv.Atoi(buttonID); err != nil {
+		return slot, fmt.Errorf("button '%s': %w", buttonID, err)
+	}
+	return slot, nil
+}
+
+// usageTarget names what a button acts on, so a slot that changes content over time
+// can be told apart in the usage log.
+func usageTarget(executionInfo *pieButtonExecute_Message) string {
+	var props struct {
+		ButtonTextUpper string `json:"button_text_upper"`
+		ButtonTextLower string `json:"button_text_lower"`
+		ResourcePath    string `json:"resource_path"`
+		Keys            string `json:"keys"`
+		Executable      string `json:"executable"`
+		MenuID          int    `json:"menu_id"`
+		PageID          int    `json:"page_id"`
+	}
+	if err := unmarshalProperties(executionInfo.Properties, &props); err != nil {
+		return ""
+	}
+
+	switch executionInfo.ButtonType {
+	case core.ButtonTypeShowProgramWindow, core.ButtonTypeShowAnyWindow:
+		return props.ButtonTextLower // AppName
+	case core.ButtonTypeOpenResource:
+		return props.ResourcePath
+	case core.ButtonTypeKeyboardShortcut:
+		return props.Keys
+	case core.ButtonTypeRunCommand:
+		return props.Executable
+	case core.ButtonTypeOpenPageInMenu:
+		return fmt.Sprintf("menu %d page %d", props.MenuID, props.PageID)
+	default:
+		return props.ButtonTextUpper
+	}
+}
@@ -2,6 +2,7 @@ package piemenuConfigManager
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/Rayzorblade23/MightyPie-Revamped/src/adapters/natsAdapter"
 	"github.com/Rayzorblade23/MightyPie-Revamped/src/core"
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core/jsonUtils"
 	"github.com/Rayzorblade23/MightyPie-Revamped/src/core/logger"
 	"github.com/nats-io/nats.go"
 )
@@ -19,6 +21,23 @@ type Adapter struct {
 	nats *natsAdapter.NatsAdapter
 	mu   sync.RWMutex
 	cfg  PieMenuConfig
+	// configFileLocked is set when the on-disk config comes from a newer schema version;
+	// the file is then never overwritten by this build.
+	configFileLocked bool
+	// history holds the undo/redo revisions of cfg; guarded by mu.
+	history *configHistory
+
+	configPath     string
+	backendSubject string
+	historySubject string
+
+	// Cross-reference data for the linter; nil until known. Guarded by mu.
+	functionNames map[string]struct{}
+	installedApps map[string]core.AppInfo
+
+	// pauseToggleKeys mirrors the pauseToggleShortcut setting for conflict detection. Guarded by mu.
+	pauseToggleKeys  string
+	conflictsSubject string
 }
 
 // logShortcuts prints a concise summary of current shortcuts for visibility
@@ -56,25 +75,86 @@ func New(na *natsAdapter.NatsAdapter) *Adapter {
 		log.Warn("Failed to resolve app data dir: %v.", err)
 	}
 	configPath := filepath.Join(appDataDir, relConfig)
+	ad.configPath = configPath
+	ad.backendSubject = backendSubject
+	ad.historySubject = os.Getenv("PUBLIC_NATSSUBJECT_PIEMENUCONFIG_HISTORY")
+	ad.conflictsSubject = os.Getenv("PUBLIC_NATSSUBJECT_PIEMENUCONFIG_SHORTCUT_CONFLICTS")
+
+	historyPath := ""
+	if rel := os.Getenv("PUBLIC_DIR_PIEMENUCONFIGHISTORY"); rel != "" {
+		historyPath = filepath.Join(appDataDir, rel)
+	}
+	ad.history = loadConfigHistory(historyPath, defaultHistoryLimit)
 
-	// Load file (if exists) and publish initial
-	if cfg, err := ReadConfigFromFile(configPath); err == nil {
+	loadErrorSubject := os.Getenv("PUBLIC_NATSSUBJECT_PIEMENUCONFIG_LOAD_ERROR")
+
+	// Load file (if exists), migrating older schema versions, and publish initial
+	if cfg, fromVersion, recovery, err := readConfigFile(configPath); err == nil {
+		if recovery != nil {
+			log.Warn("Config file '%s' was damaged (%s); restored last good copy from '%s'",
+				recovery.Path, recovery.Reason, recovery.BackupPath)
+			if loadErrorSubject != "" {
+				ad.nats.PublishMessage(loadErrorSubject, map[string]any{
+					"path":      recovery.Path,
+					"error":     recovery.Reason,
+					"message":   "Config file was damaged; restored the last good copy",
+					"recovered": true,
+				})
+			}
+		}
+		needsWrite := fromVersion < CurrentSchemaVersion
+		if needsWrite {
+			if backupPath, err := backupPreMigrationFile(configPath, fromVersion); err != nil {
+				log.Warn("Failed to keep pre-migration copy of '%s': %v", configPath, err)
+			} else {
+				log.Info("Pre-migration copy (schema v%d) written to '%s'", fromVersion, backupPath)
+			}
+		}
 		// If buttons section is empty, populate defaults
 		if len(cfg.Buttons) == 0 {
 			cfg.Buttons = newDefaultButtons()
-			// persist the defaulted config so everyone sees baseline structure
+			needsWrite = true
+		}
+		// persist migrated/defaulted config so everyone sees baseline structure
+		if needsWrite {
 			if err := WriteConfigToFile(configPath, cfg); err != nil {
-				log.Error("Failed to write defaulted full config: %v", err)
+				log.Error("Failed to write upgraded full config: %v", err)
 			}
 		}
 		ad.setConfig(cfg)
-		log.Info("Loaded pie menu config from '%s'", configPath)
+		ad.seedHistory(cfg, "Loaded from disk")
+		log.Info("Loaded pie menu config from '%s' (schema v%d)", configPath, cfg.SchemaVersion)
 		logShortcuts(cfg.Shortcuts)
+	} else if errors.Is(err, ErrNewerSchemaVersion) {
+		// Never overwrite a file from a newer build: run on in-memory defaults only.
+		def := PieMenuConfig{Buttons: newDefaultButtons(), Shortcuts: map[string]ShortcutEntry{}, Starred: nil}
+		normalizeConfig(&def)
+		ad.setConfig(def)
+		ad.configFileLocked = true
+		log.Error("Refusing to load '%s': %v. Running with in-memory defaults; the file is left untouched.", configPath, err)
+		if loadErrorSubject != "" {
+			ad.nats.PublishMessage(loadErrorSubject, map[string]any{
+				"path":    configPath,
+				"error":   fmt.Sprintf("%v", err),
+				"message": "Config file was written by a newer version of MightyPie",
+			})
+		}
+		logShortcuts(def.Shortcuts)
 	} else {
 		// Initialize with default buttons and empty shortcuts
 		def := PieMenuConfig{Buttons: newDefaultButtons(), Shortcuts: map[string]ShortcutEntry{}, Starred: nil}
+		normalizeConfig(&def)
 		ad.setConfig(def)
+		ad.seedHistory(def, "Created default config")
 		log.Warn("Failed to read config from '%s': %v. Starting with defaults.", configPath, err)
+		if errors.Is(err, jsonUtils.ErrNoUsableBackup) && loadErrorSubject != "" {
+			ad.nats.PublishMessage(loadErrorSubject, map[string]any{
+				"path":      configPath,
+				"error":     fmt.Sprintf("%v", err),
+				"message":   "Config file was damaged and had no usable backup; started from defaults",
+				"recovered": true,
+			})
+		}
 		// Best-effort write to create the file for future runs
 		if err := WriteConfigToFile(configPath, def); err != nil {
 			log.Error("Failed to write default full config: %v", err)
@@ -82,6 +162,8 @@ func New(na *natsAdapter.NatsAdapter) *Adapter {
 		logShortcuts(def.Shortcuts)
 	}
 	ad.publish(backendSubject)
+	ad.publishHistory()
+	ad.publishShortcutConflicts()
 
 	// Subscribe to frontend updates (full config)
 	ad.nats.SubscribeToSubject(frontendSubject, func(msg *nats.Msg) {
@@ -90,19 +172,23 @@ func New(na *natsAdapter.NatsAdapter) *Adapter {
 			log.Error("Failed to unmarshal PieMenuConfig: %v", err)
 			return
 		}
-		// Basic sanity: ensure maps are non-nil
-		if incoming.Buttons == nil {
-			incoming.Buttons = ConfigData{}
+		if incoming.SchemaVersion > CurrentSchemaVersion {
+			log.Error("Rejected config update with schema version %d (supported up to %d)", incoming.SchemaVersion, CurrentSchemaVersion)
+			return
 		}
-		if incoming.Shortcuts == nil {
-			incoming.Shortcuts = map[string]ShortcutEntry{}
+		if ad.configFileLocked {
+			ad.rejectLockedUpdate(loadErrorSubject, "Config update")
+			return
 		}
-		ad.setConfig(incoming)
-		if err := WriteConfigToFile(configPath, incoming); err != nil {
+		// Basic sanity: ensure maps are non-nil and stamp the schema version
+		normalizeConfig(&incoming)
+		if err := ad.commitConfig(incoming, ""); err != nil {
 			log.Error("Failed to write config to file: %v", err)
 			return
 		}
 		ad.publish(backendSubject)
+		ad.publishHistory()
+		ad.publishShortcutConflicts()
 	})
 
     // Removed partial shortcut update/delete handling. Only full config updates are persisted.
@@ -110,7 +196,6 @@ func New(na *natsAdapter.NatsAdapter) *Adapter {
     // Backups are owned by the config manager
     saveBackupSubject := os.Getenv("PUBLIC_NATSSUBJECT_PIEMENUCONFIG_SAVE_BACKUP")
     loadBackupSubject := os.Getenv("PUBLIC_NATSSUBJECT_PIEMENUCONFIG_LOAD_BACKUP")
-    loadErrorSubject := os.Getenv("PUBLIC_NATSSUBJECT_PIEMENUCONFIG_LOAD_ERROR")
 
     // Save backup: if payload contains a path, write there; otherwise use default backup location
     ad.nats.SubscribeToSubject(saveBackupSubject, func(msg *nats.Msg) {
@@ -165,22 +250,32 @@ func New(na *natsAdapter.NatsAdapter) *Adapter {
             }
             return
         }
-        // Basic sanity: ensure maps are non-nil
-        if loaded.Buttons == nil {
-            loaded.Buttons = ConfigData{}
-        }
-        if loaded.Shortcuts == nil {
-            loaded.Shortcuts = map[string]ShortcutEntry{}
+        if ad.configFileLocked {
+            ad.rejectLockedUpdate(loadErrorSubject, "Loading the backup")
+            return
         }
-        ad.setConfig(loaded)
-        if err := WriteConfigToFile(configPath, loaded); err != nil {
+        if err := ad.commitConfig(loaded, fmt.Sprintf("Loaded backup '%s'", filepath.Base(backupPath))); err != nil {
             log.Error("Failed to write config to file: %v", err)
             return
         }
         ad.publish(backendSubject)
+        ad.publishHistory()
+        ad.publishShortcutConflicts()
         log.Info("Full config loaded from backup and published.")
     })
 
+    ad.subscribeHistory()
+    ad.subscribePatch()
+
+    ad.subscribeLint()
+    ad.subscribeShortcutConflicts()
+    ad.serveButtonTypes()
+
+    natsAdapter.HandleRequest(ad.nats, os.Getenv("PUBLIC_NATSSUBJECT_PIEMENUCONFIG_GET"),
+        func(struct{}) (natsAdapter.Snapshot[PieMenuConfig], error) {
+            return ad.snapshot(), nil
+        })
+
 	return ad
 }
 
@@ -190,12 +285,56 @@ func (a *Adapter) setConfig(c PieMenuConfig) {
 	a.mu.Unlock()
 }
 
+// snapshot returns the current config together with its history revision.
+func (a *Adapter) snapshot() natsAdapter.Snapshot[PieMenuConfig] {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return natsAdapter.Snapshot[PieMenuConfig]{Revision: a.history.currentRevision(), Data: a.cfg}
+}
+
 func (a *Adapter) getConfig() PieMenuConfig {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 	return a.cfg
 }
 
+// seedHistory records cfg as the first revision of this session unless the history already ends with it.
+func (a *Adapter) seedHistory(cfg PieMenuConfig, summary string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if current, ok := a.history.current(); ok && summarizeChange(current.Config, cfg) == "" {
+		return
+	}
+	a.history.record(cfg, summary)
+	if err := a.history.save(); err != nil {
+		log.Warn("Failed to persist config history: %v", err)
+	}
+}
+
+// writeConfigFile persists cfg to the main config file unless that file belongs to a newer build.
+// Callers hold a.mu, so changes reach the file in the order the history recorded them.
+func (a *Adapter) writeConfigFile(path string, cfg PieMenuConfig) error {
+	if a.configFileLocked {
+		return fmt.Errorf("'%s' is from a newer schema version and is left untouched: %w", path, ErrNewerSchemaVersion)
+	}
+	return WriteConfigToFile(path, cfg)
+}
+
+// rejectLockedUpdate tells the frontend that a change was not applied because the config file
+// belongs to a newer build, and republishes the unchanged config so the editor drops the change.
+func (a *Adapter) rejectLockedUpdate(loadErrorSubject, what string) {
+	log.Error("%s rejected: '%s' is from a newer schema version and is read-only", what, a.configPath)
+	if loadErrorSubject != "" {
+		a.nats.PublishMessage(loadErrorSubject, map[string]any{
+			"path":     a.configPath,
+			"error":    ErrNewerSchemaVersion.Error(),
+			"message":  what + " was rejected: the config file was written by a newer version of MightyPie",
+			"rejected": true,
+		})
+	}
+	a.publish(a.backendSubject)
+}
+
 func (a *Adapter) publish(subject string) {
 	cfg := a.getConfig()
 	a.nats.PublishMessage(subject, cfg)
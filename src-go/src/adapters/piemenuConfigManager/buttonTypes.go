@@ -0,0 +1,42 @@
+package piemenuConfigManager
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/adapters/natsAdapter"
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core"
+)
+
+// ButtonTypeDescriptor tells the editor what a button type looks like.
+type ButtonTypeDescriptor struct {
+	Type             core.ButtonType `json:"type"`
+	WindowAssignment bool            `json:"windowAssignment"`
+	// DefaultProperties are the properties of a newly created button.
+	DefaultProperties json.RawMessage `json:"defaultProperties"`
+	// Schema is a JSON Schema for the properties.
+	Schema map[string]any `json:"schema"`
+}
+
+// DescribeButtonTypes lists every registered button type in registration order.
+func DescribeButtonTypes() []ButtonTypeDescriptor {
+	infos := core.ButtonTypes()
+	descriptors := make([]ButtonTypeDescriptor, 0, len(infos))
+	for _, info := range infos {
+		descriptors = append(descriptors, ButtonTypeDescriptor{
+			Type:              info.Type,
+			WindowAssignment:  info.WindowAssignment,
+			DefaultProperties: info.DefaultProperties(),
+			Schema:            info.Schema(),
+		})
+	}
+	return descriptors
+}
+
+// serveButtonTypes answers requests for the registered button types.
+func (a *Adapter) serveButtonTypes() {
+	natsAdapter.HandleRequest(a.nats, os.Getenv("PUBLIC_NATSSUBJECT_PIEMENUCONFIG_BUTTONTYPES"),
+		func(struct{}) ([]ButtonTypeDescriptor, error) {
+			return DescribeButtonTypes(), nil
+		})
+}
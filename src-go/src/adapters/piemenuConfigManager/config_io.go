@@ -1,72 +1,48 @@
 package piemenuConfigManager
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 
 	"github.com/Rayzorblade23/MightyPie-Revamped/src/core"
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core/jsonUtils"
 )
 
-// ReadConfigFromFile reads the PieMenuConfig from disk.
-// It primarily expects the full-format and ensures non-nil maps on success.
-// If the file contains a legacy buttons-only structure (ConfigData), it will be
-// wrapped into a PieMenuConfig to allow seamless migration.
+// ReadConfigFromFile reads the PieMenuConfig from disk and upgrades it to CurrentSchemaVersion.
+// Legacy buttons-only files (ConfigData) are wrapped by the v0 -> v1 migration.
+// Files written by a newer schema are rejected with ErrNewerSchemaVersion,
+// and files whose embedded checksum does not match are rejected as damaged.
 func ReadConfigFromFile(path string) (PieMenuConfig, error) {
-	f, err := os.Open(path)
+	data, err := jsonUtils.ReadVerifiedFile(path)
 	if err != nil {
 		return PieMenuConfig{}, err
 	}
-	defer f.Close()
-	data, err := io.ReadAll(f)
-	if err != nil {
-		return PieMenuConfig{}, err
-	}
-
-	// Try full-format first
-	var cfg PieMenuConfig
-	if err := json.Unmarshal(data, &cfg); err == nil {
-		if cfg.Buttons == nil {
-			cfg.Buttons = ConfigData{}
-		}
-		if cfg.Shortcuts == nil {
-			cfg.Shortcuts = map[string]ShortcutEntry{}
-		}
-		return cfg, nil
-	}
+	cfg, _, err := migrateConfig(data)
+	return cfg, err
+}
 
-	// Fallback: legacy buttons-only file. Attempt to unmarshal as ConfigData and wrap.
-	var buttonsOnly ConfigData
-	if err := json.Unmarshal(data, &buttonsOnly); err == nil && buttonsOnly != nil {
-		// Ensure non-nil maps
-		wrapped := PieMenuConfig{
-			Buttons:   buttonsOnly,
-			Shortcuts: map[string]ShortcutEntry{},
-			Starred:   nil,
-		}
-		return wrapped, nil
+// readConfigFile reads and migrates the main config at path and reports the schema version
+// the file was written with, so callers can decide whether to persist the upgrade.
+// A damaged file is replaced by its last good copy; the returned Recovery describes that fallback.
+func readConfigFile(path string) (PieMenuConfig, int, *jsonUtils.Recovery, error) {
+	data, recovery, err := jsonUtils.ReadVerifiedFileWithRecovery(path)
+	if err != nil {
+		return PieMenuConfig{}, 0, nil, err
 	}
-
-	return PieMenuConfig{}, errors.New("invalid PieMenuConfig format")
+	cfg, fromVersion, err := migrateConfig(data)
+	return cfg, fromVersion, recovery, err
 }
 
+// WriteConfigToFile atomically writes cfg to path with an embedded checksum,
+// keeping the previous good version next to it as a backup.
 func WriteConfigToFile(path string, cfg PieMenuConfig) error {
 	if path == "" {
 		return errors.New("empty config path")
 	}
-	// Ensure directory exists
-	dir := filepath.Dir(path)
-	if dir != "." && dir != "" {
-		_ = os.MkdirAll(dir, 0755)
-	}
-	data, err := json.MarshalIndent(cfg, "", "  ")
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(path, data, 0644)
+	normalizeConfig(&cfg)
+	return jsonUtils.WriteToFile(path, cfg)
 }
 
 // BackupFullConfigToFile writes the PieMenuConfig to a backup file in the standard backups directory.
@@ -92,11 +68,8 @@ func BackupFullConfigToFile(cfg PieMenuConfig) error {
 		backupPath = filepath.Join(backupDir, fmt.Sprintf("%s_%d%s", "piemenuConfig_BACKUP", idx, ".json"))
 	}
 
-	data, err := json.MarshalIndent(cfg, "", "  ")
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(backupPath, data, 0644)
+	normalizeConfig(&cfg)
+	return jsonUtils.WriteToFile(backupPath, cfg)
 }
 
 func getDir(path string) string {
@@ -0,0 +1,119 @@
+package piemenuConfigManager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core/jsonUtils"
+	"github.com/nats-io/nats.go"
+)
+
+// errStaleRevision is returned when a patch was computed against an outdated config.
+var errStaleRevision = errors.New("config has changed since the base revision")
+
+// ConfigPatchRequest carries RFC 6902 operations computed against BaseRevision.
+type ConfigPatchRequest struct {
+	BaseRevision int                        `json:"baseRevision"`
+	Patch        []jsonUtils.PatchOperation `json:"patch"`
+	Summary      string                     `json:"summary,omitempty"`
+}
+
+// ConfigPatchResult is the reply to a patch request. On a conflict, Revision is the
+// current revision the client has to rebase onto.
+type ConfigPatchResult struct {
+	OK       bool   `json:"ok"`
+	Conflict bool   `json:"conflict,omitempty"`
+	Revision int    `json:"revision"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ConfigPatchApplied is published after a patch was applied, alongside the full config.
+type ConfigPatchApplied struct {
+	BaseRevision int                        `json:"baseRevision"`
+	Revision     int                        `json:"revision"`
+	Patch        []jsonUtils.PatchOperation `json:"patch"`
+}
+
+// subscribePatch wires the JSON Patch subject.
+func (a *Adapter) subscribePatch() {
+	patchSubject := os.Getenv("PUBLIC_NATSSUBJECT_PIEMENUCONFIG_PATCH")
+	appliedSubject := os.Getenv("PUBLIC_NATSSUBJECT_PIEMENUCONFIG_PATCH_APPLIED")
+
+	a.nats.SubscribeToSubject(patchSubject, func(msg *nats.Msg) {
+		var req ConfigPatchRequest
+		if err := json.Unmarshal(msg.Data, &req); err != nil {
+			a.replyPatch(msg, ConfigPatchResult{Error: fmt.Sprintf("invalid patch request: %v", err)})
+			return
+		}
+
+		revision, err := a.applyPatch(req)
+		if err != nil {
+			result := ConfigPatchResult{Revision: revision, Error: err.Error()}
+			result.Conflict = errors.Is(err, errStaleRevision)
+			log.Warn("Rejected config patch against revision %d: %v", req.BaseRevision, err)
+			a.replyPatch(msg, result)
+			return
+		}
+
+		a.publish(a.backendSubject)
+		if appliedSubject != "" {
+			a.nats.PublishMessage(appliedSubject, ConfigPatchApplied{
+				BaseRevision: req.BaseRevision,
+				Revision:     revision,
+				Patch:        req.Patch,
+			})
+		}
+		a.publishHistory()
+		a.publishShortcutConflicts()
+		log.Info("Applied config patch (%d operations): revision %d -> %d", len(req.Patch), req.BaseRevision, revision)
+		a.replyPatch(msg, ConfigPatchResult{OK: true, Revision: revision})
+	})
+}
+
+// applyPatch applies req to the current config under a.mu and writes the result to the config
+// file before releasing it. The config is only replaced if the base revision is current and
+// every operation succeeds. It returns the resulting revision, or the current revision
+// alongside the error.
+func (a *Adapter) applyPatch(req ConfigPatchRequest) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	current := a.history.currentRevision()
+	if a.configFileLocked {
+		return current, fmt.Errorf("config file is read-only: %w", ErrNewerSchemaVersion)
+	}
+	if req.BaseRevision != current {
+		return current, fmt.Errorf("%w (base %d, current %d)", errStaleRevision, req.BaseRevision, current)
+	}
+
+	doc, err := json.Marshal(a.cfg)
+	if err != nil {
+		return current, err
+	}
+	patched, err := jsonUtils.ApplyPatch(doc, req.Patch)
+	if err != nil {
+		return current, err
+	}
+	var cfg PieMenuConfig
+	if err := json.Unmarshal(patched, &cfg); err != nil {
+		return current, fmt.Errorf("patched config is invalid: %w", err)
+	}
+	if cfg.SchemaVersion != CurrentSchemaVersion {
+		return current, fmt.Errorf("patch must not change schemaVersion")
+	}
+	normalizeConfig(&cfg)
+
+	a.commitConfigLocked(cfg, req.Summary)
+	if err := a.writeConfigFile(a.configPath, cfg); err != nil {
+		log.Error("Failed to write config to file: %v", err)
+	}
+	return a.history.currentRevision(), nil
+}
+
+func (a *Adapter) replyPatch(msg *nats.Msg, result ConfigPatchResult) {
+	if msg.Reply != "" {
+		respondJSON(msg, result)
+	}
+}
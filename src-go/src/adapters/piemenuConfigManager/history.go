@@ -0,0 +1,285 @@
+package piemenuConfigManager
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core/jsonUtils"
+)
+
+const (
+	// defaultHistoryLimit is the number of config revisions kept on disk.
+	defaultHistoryLimit = 50
+	// maxSummaryParts limits how many individual changes are spelled out in a summary.
+	maxSummaryParts = 4
+)
+
+var (
+	errNothingToUndo    = errors.New("nothing to undo")
+	errNothingToRedo    = errors.New("nothing to redo")
+	errRevisionNotFound = errors.New("revision not found in history")
+)
+
+// HistoryEntry is one stored revision of the full PieMenuConfig.
+type HistoryEntry struct {
+	Revision  int           `json:"revision"`
+	Timestamp time.Time     `json:"timestamp"`
+	Summary   string        `json:"summary"`
+	Config    PieMenuConfig `json:"config"`
+}
+
+// HistoryInfo describes a revision without its config payload, for listing.
+type HistoryInfo struct {
+	Revision  int       `json:"revision"`
+	Timestamp time.Time `json:"timestamp"`
+	Summary   string    `json:"summary"`
+	Current   bool      `json:"current"`
+}
+
+// HistoryState is published whenever the history changes and in reply to list requests.
+type HistoryState struct {
+	CurrentRevision int           `json:"currentRevision"`
+	CanUndo         bool          `json:"canUndo"`
+	CanRedo         bool          `json:"canRedo"`
+	Entries         []HistoryInfo `json:"entries"`
+}
+
+// RestoreRevisionRequest selects a revision for the restore subject.
+type RestoreRevisionRequest struct {
+	Revision int `json:"revision"`
+}
+
+// configHistory is a bounded undo/redo stack of config revisions.
+// Entries[Cursor] is the current config; entries after it can be redone.
+// It is not safe for concurrent use; the Adapter guards it with its mutex.
+type configHistory struct {
+	Entries      []HistoryEntry `json:"entries"`
+	Cursor       int            `json:"cursor"`
+	NextRevision int            `json:"nextRevision"`
+
+	path  string
+	limit int
+}
+
+// loadConfigHistory reads the persisted history at path, starting empty if it is missing or unreadable.
+func loadConfigHistory(path string, limit int) *configHistory {
+	h := &configHistory{Cursor: -1, NextRevision: 1}
+	if path != "" {
+		if err := jsonUtils.ReadFromFile(path, h); err != nil {
+			log.Warn("Failed to read config history from '%s': %v. Starting with empty history.", path, err)
+			h = &configHistory{Cursor: -1, NextRevision: 1}
+		}
+	}
+	h.path = path
+	h.limit = limit
+	if h.Cursor < -1 || h.Cursor >= len(h.Entries) {
+		h.Cursor = len(h.Entries) - 1
+	}
+	for _, e := range h.Entries {
+		h.NextRevision = max(h.NextRevision, e.Revision+1)
+	}
+	return h
+}
+
+// save persists the history next to the config file.
+func (h *configHistory) save() error {
+	if h.path == "" {
+		return nil
+	}
+	return jsonUtils.WriteToFile(h.path, h)
+}
+
+// current returns the entry the cursor points at.
+func (h *configHistory) current() (HistoryEntry, bool) {
+	if h.Cursor < 0 || h.Cursor >= len(h.Entries) {
+		return HistoryEntry{}, false
+	}
+	return h.Entries[h.Cursor], true
+}
+
+// currentRevision returns the revision number of the current config, or 0 if there is none.
+func (h *configHistory) currentRevision() int {
+	if e, ok := h.current(); ok {
+		return e.Revision
+	}
+	return 0
+}
+
+// record appends cfg as a new revision, discarding any redo entries, and trims to the limit.
+func (h *configHistory) record(cfg PieMenuConfig, summary string) HistoryEntry {
+	entry := HistoryEntry{
+		Revision:  h.NextRevision,
+		Timestamp: time.Now(),
+		Summary:   summary,
+		Config:    cfg,
+	}
+	h.NextRevision++
+
+	h.Entries = append(h.Entries[:h.Cursor+1], entry)
+	if h.limit > 0 && len(h.Entries) > h.limit {
+		h.Entries = slices.Clone(h.Entries[len(h.Entries)-h.limit:])
+	}
+	h.Cursor = len(h.Entries) - 1
+	return entry
+}
+
+// undo moves the cursor one revision back.
+func (h *configHistory) undo() (HistoryEntry, error) {
+	if h.Cursor <= 0 {
+		return HistoryEntry{}, errNothingToUndo
+	}
+	h.Cursor--
+	return h.Entries[h.Cursor], nil
+}
+
+// redo moves the cursor one revision forward.
+func (h *configHistory) redo() (HistoryEntry, error) {
+	if h.Cursor+1 >= len(h.Entries) {
+		return HistoryEntry{}, errNothingToRedo
+	}
+	h.Cursor++
+	return h.Entries[h.Cursor], nil
+}
+
+// find returns the entry with the given revision.
+func (h *configHistory) find(revision int) (HistoryEntry, error) {
+	for _, e := range h.Entries {
+		if e.Revision == revision {
+			return e, nil
+		}
+	}
+	return HistoryEntry{}, fmt.Errorf("%w: %d", errRevisionNotFound, revision)
+}
+
+// state returns the listing published to clients, newest revision first.
+func (h *configHistory) state() HistoryState {
+	st := HistoryState{
+		CurrentRevision: h.currentRevision(),
+		CanUndo:         h.Cursor > 0,
+		CanRedo:         h.Cursor+1 < len(h.Entries),
+		Entries:         make([]HistoryInfo, 0, len(h.Entries)),
+	}
+	for i := len(h.Entries) - 1; i >= 0; i-- {
+		e := h.Entries[i]
+		st.Entries = append(st.Entries, HistoryInfo{
+			Revision:  e.Revision,
+			Timestamp: e.Timestamp,
+			Summary:   e.Summary,
+			Current:   i == h.Cursor,
+		})
+	}
+	return st
+}
+
+// summarizeChange builds a short, human-readable description of what differs between prev and next.
+// It returns an empty string if the configs are equivalent.
+func summarizeChange(prev, next PieMenuConfig) string {
+	var parts []string
+
+	menuIDs := unionKeys(prev.Buttons, next.Buttons)
+	for _, menuID := range menuIDs {
+		prevMenu, inPrev := prev.Buttons[menuID]
+		nextMenu, inNext := next.Buttons[menuID]
+		switch {
+		case !inPrev:
+			parts = append(parts, fmt.Sprintf("added menu %s", menuID))
+			continue
+		case !inNext:
+			parts = append(parts, fmt.Sprintf("removed menu %s", menuID))
+			continue
+		}
+		for _, pageID := range unionKeys(prevMenu, nextMenu) {
+			prevPage, inPrevPage := prevMenu[pageID]
+			nextPage, inNextPage := nextMenu[pageID]
+			switch {
+			case !inPrevPage:
+				parts = append(parts, fmt.Sprintf("added page %s in menu %s", pageID, menuID))
+				continue
+			case !inNextPage:
+				parts = append(parts, fmt.Sprintf("removed page %s in menu %s", pageID, menuID))
+				continue
+			}
+			changed := 0
+			for _, btnID := range unionKeys(prevPage, nextPage) {
+				if !buttonsEqual(prevPage[btnID], nextPage[btnID]) {
+					changed++
+				}
+			}
+			if changed == 1 {
+				parts = append(parts, fmt.Sprintf("changed 1 button on menu %s page %s", menuID, pageID))
+			} else if changed > 1 {
+				parts = append(parts, fmt.Sprintf("changed %d buttons on menu %s page %s", changed, menuID, pageID))
+			}
+		}
+	}
+
+	if !jsonEqual(prev.Shortcuts, next.Shortcuts) {
+		parts = append(parts, "changed shortcuts")
+	}
+	if !jsonEqual(prev.Starred, next.Starred) {
+		parts = append(parts, "changed starred page")
+	}
+	if !jsonEqual(prev.MenuAliases, next.MenuAliases) {
+		parts = append(parts, "changed menu names")
+	}
+	if !jsonEqual(prev.AppPageRules, next.AppPageRules) {
+		parts = append(parts, "changed app page rules")
+	}
+
+	if len(parts) > maxSummaryParts {
+		more := len(parts) - maxSummaryParts
+		parts = append(parts[:maxSummaryParts], fmt.Sprintf("%d more change(s)", more))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	summary := strings.Join(parts, ", ")
+	return strings.ToUpper(summary[:1]) + summary[1:]
+}
+
+// unionKeys returns the sorted union of the keys of a and b.
+func unionKeys[V any](a, b map[string]V) []string {
+	seen := make(map[string]struct{}, len(a)+len(b))
+	for k := range a {
+		seen[k] = struct{}{}
+	}
+	for k := range b {
+		seen[k] = struct{}{}
+	}
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) < len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// buttonsEqual compares two buttons, ignoring whitespace differences in their properties.
+func buttonsEqual(a, b Button) bool {
+	if a.ButtonType != b.ButtonType {
+		return false
+	}
+	var ca, cb bytes.Buffer
+	if json.Compact(&ca, a.Properties) != nil || json.Compact(&cb, b.Properties) != nil {
+		return bytes.Equal(a.Properties, b.Properties)
+	}
+	return bytes.Equal(ca.Bytes(), cb.Bytes())
+}
+
+// jsonEqual compares two values by their JSON encoding.
+func jsonEqual(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
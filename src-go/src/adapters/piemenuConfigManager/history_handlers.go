@@ -0,0 +1,179 @@
+package piemenuConfigManager
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/nats-io/nats.go"
+)
+
+// HistoryActionResult is the reply to undo, redo and restore requests.
+type HistoryActionResult struct {
+	OK       bool   `json:"ok"`
+	Revision int    `json:"revision"`
+	Summary  string `json:"summary,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// subscribeHistory wires the undo, redo, list and restore subjects.
+func (a *Adapter) subscribeHistory() {
+	undoSubject := os.Getenv("PUBLIC_NATSSUBJECT_PIEMENUCONFIG_UNDO")
+	redoSubject := os.Getenv("PUBLIC_NATSSUBJECT_PIEMENUCONFIG_REDO")
+	listSubject := os.Getenv("PUBLIC_NATSSUBJECT_PIEMENUCONFIG_HISTORY_LIST")
+	restoreSubject := os.Getenv("PUBLIC_NATSSUBJECT_PIEMENUCONFIG_HISTORY_RESTORE")
+
+	a.nats.SubscribeToSubject(undoSubject, func(msg *nats.Msg) {
+		entry, err := a.navigateHistory((*configHistory).undo)
+		a.replyHistoryAction(msg, "undo", entry, err)
+	})
+
+	a.nats.SubscribeToSubject(redoSubject, func(msg *nats.Msg) {
+		entry, err := a.navigateHistory((*configHistory).redo)
+		a.replyHistoryAction(msg, "redo", entry, err)
+	})
+
+	a.nats.SubscribeToSubject(listSubject, func(msg *nats.Msg) {
+		state := a.historyState()
+		if msg.Reply != "" {
+			respondJSON(msg, state)
+		}
+		a.publishHistory()
+	})
+
+	a.nats.SubscribeToSubject(restoreSubject, func(msg *nats.Msg) {
+		var req RestoreRevisionRequest
+		if err := json.Unmarshal(msg.Data, &req); err != nil {
+			a.replyHistoryAction(msg, "restore", HistoryEntry{}, fmt.Errorf("invalid restore request: %w", err))
+			return
+		}
+		entry, err := a.restoreRevision(req.Revision)
+		a.replyHistoryAction(msg, "restore", entry, err)
+	})
+}
+
+// commitConfig makes cfg the current config, records it as a new revision and writes it to the
+// config file. If summary is empty it is derived from the difference to the previous config;
+// a config identical to the current one is not recorded. The returned error is the file write's;
+// cfg is current either way.
+func (a *Adapter) commitConfig(cfg PieMenuConfig, summary string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.commitConfigLocked(cfg, summary)
+	return a.writeConfigFile(a.configPath, cfg)
+}
+
+// commitConfigLocked is commitConfig without the file write, for callers that already hold a.mu.
+// They write the file before releasing a.mu.
+func (a *Adapter) commitConfigLocked(cfg PieMenuConfig, summary string) {
+	changes := summarizeChange(a.cfg, cfg)
+	a.cfg = cfg
+	if _, ok := a.history.current(); ok && changes == "" {
+		return
+	}
+	if summary == "" {
+		summary = changes
+	}
+	entry := a.history.record(cfg, summary)
+	if err := a.history.save(); err != nil {
+		log.Warn("Failed to persist config history: %v", err)
+	}
+	log.Info("Recorded config revision %d: %s", entry.Revision, entry.Summary)
+}
+
+// navigateHistory moves the history cursor with step, then persists and publishes the resulting config.
+func (a *Adapter) navigateHistory(step func(*configHistory) (HistoryEntry, error)) (HistoryEntry, error) {
+	if a.configFileLocked {
+		return HistoryEntry{}, fmt.Errorf("config file is read-only: %w", ErrNewerSchemaVersion)
+	}
+
+	a.mu.Lock()
+	entry, err := step(a.history)
+	if err != nil {
+		a.mu.Unlock()
+		return HistoryEntry{}, err
+	}
+	cfg := entry.Config
+	normalizeConfig(&cfg)
+	a.cfg = cfg
+	if err := a.history.save(); err != nil {
+		log.Warn("Failed to persist config history: %v", err)
+	}
+	if err := a.writeConfigFile(a.configPath, cfg); err != nil {
+		log.Error("Failed to write config to file: %v", err)
+	}
+	a.mu.Unlock()
+
+	a.publish(a.backendSubject)
+	a.publishHistory()
+	a.publishShortcutConflicts()
+	return entry, nil
+}
+
+// restoreRevision makes an older revision current again by recording it as a new revision,
+// so the restore itself can be undone.
+func (a *Adapter) restoreRevision(revision int) (HistoryEntry, error) {
+	if a.configFileLocked {
+		return HistoryEntry{}, fmt.Errorf("config file is read-only: %w", ErrNewerSchemaVersion)
+	}
+
+	a.mu.RLock()
+	entry, err := a.history.find(revision)
+	a.mu.RUnlock()
+	if err != nil {
+		return HistoryEntry{}, err
+	}
+
+	cfg := entry.Config
+	normalizeConfig(&cfg)
+	if err := a.commitConfig(cfg, fmt.Sprintf("Restored revision %d (%s)", entry.Revision, entry.Summary)); err != nil {
+		log.Error("Failed to write config to file: %v", err)
+	}
+	a.publish(a.backendSubject)
+	a.publishHistory()
+	a.publishShortcutConflicts()
+
+	a.mu.RLock()
+	current, _ := a.history.current()
+	a.mu.RUnlock()
+	return current, nil
+}
+
+func (a *Adapter) historyState() HistoryState {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.history.state()
+}
+
+// publishHistory publishes the revision listing so editors can update their undo/redo controls.
+func (a *Adapter) publishHistory() {
+	if a.historySubject == "" {
+		return
+	}
+	a.nats.PublishMessage(a.historySubject, a.historyState())
+}
+
+// replyHistoryAction logs the outcome of a history request and answers it if the sender expects a reply.
+func (a *Adapter) replyHistoryAction(msg *nats.Msg, action string, entry HistoryEntry, err error) {
+	result := HistoryActionResult{OK: err == nil, Revision: entry.Revision, Summary: entry.Summary}
+	if err != nil {
+		result.Error = err.Error()
+		log.Warn("Config %s failed: %v", action, err)
+	} else {
+		log.Info("Config %s -> revision %d: %s", action, entry.Revision, entry.Summary)
+	}
+	if msg.Reply != "" {
+		respondJSON(msg, result)
+	}
+}
+
+func respondJSON(msg *nats.Msg, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Error("Failed to marshal reply for '%s': %v", msg.Subject, err)
+		return
+	}
+	if err := msg.Respond(data); err != nil {
+		log.Error("Failed to reply on '%s': %v", msg.Subject, err)
+	}
+}
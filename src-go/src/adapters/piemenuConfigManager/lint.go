@@ -0,0 +1,363 @@
+package piemenuConfigManager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/adapters/natsAdapter"
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/adapters/pieButtonExecutionAdapter/textTemplate"
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/adapters/shortcutSetterAdapter"
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core"
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core/appPageRules"
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core/shortcutMatcher"
+	"github.com/nats-io/nats.go"
+)
+
+// LintSeverity ranks a lint finding.
+type LintSeverity string
+
+const (
+	LintError   LintSeverity = "error"
+	LintWarning LintSeverity = "warning"
+	LintInfo    LintSeverity = "info"
+)
+
+// LintFinding is a single problem found in a config.
+// Location is a slash-separated path into the config, e.g. "buttons/0/1/3" or "shortcuts/2".
+type LintFinding struct {
+	Severity LintSeverity `json:"severity"`
+	Location string       `json:"location"`
+	Message  string       `json:"message"`
+}
+
+// LintReport is the result of linting a config.
+type LintReport struct {
+	Errors   int           `json:"errors"`
+	Warnings int           `json:"warnings"`
+	Findings []LintFinding `json:"findings"`
+}
+
+// LintRequest optionally carries a draft config to lint instead of the current one.
+type LintRequest struct {
+	Config *PieMenuConfig `json:"config,omitempty"`
+}
+
+// LintContext provides the external data the linter cross-references.
+// A nil FunctionNames or InstalledApps skips the corresponding checks.
+type LintContext struct {
+	FunctionNames map[string]struct{}
+	InstalledApps map[string]core.AppInfo
+	// PauseToggleKeys is the pauseToggleShortcut setting, checked for shortcut collisions.
+	PauseToggleKeys string
+	// PathExists reports whether a local resource exists; defaults to os.Stat.
+	PathExists func(path string) bool
+}
+
+// LintConfig cross-references cfg against itself and ctx. It never modifies cfg.
+// Findings are ordered by menu, page and button.
+func LintConfig(cfg PieMenuConfig, ctx LintContext) LintReport {
+	l := &linter{cfg: cfg, ctx: ctx}
+	if l.ctx.PathExists == nil {
+		l.ctx.PathExists = func(path string) bool {
+			_, err := os.Stat(path)
+			return err == nil
+		}
+	}
+
+	if ctx.FunctionNames == nil {
+		l.add(LintInfo, "", "Button function list unavailable; call_function buttons were not checked")
+	}
+	if ctx.InstalledApps == nil {
+		l.add(LintInfo, "", "Installed apps unavailable; program buttons were not checked")
+	}
+
+	for _, menuID := range unionKeys(cfg.Buttons, nil) {
+		menu := cfg.Buttons[menuID]
+		for _, pageID := range unionKeys(menu, nil) {
+			page := menu[pageID]
+			for _, btnID := range unionKeys(page, nil) {
+				l.lintButton(fmt.Sprintf("buttons/%s/%s/%s", menuID, pageID, btnID), page[btnID])
+			}
+		}
+	}
+
+	if s := cfg.Starred; s != nil && !l.pageExists(s.MenuID, s.PageID) {
+		l.add(LintError, "starred", fmt.Sprintf("Starred page %d in menu %d does not exist", s.PageID, s.MenuID))
+	}
+	for _, menuID := range unionKeys(cfg.MenuAliases, nil) {
+		if _, ok := cfg.Buttons[menuID]; !ok {
+			l.add(LintWarning, "menuAliases/"+menuID, fmt.Sprintf("Name '%s' is set for menu %s, which does not exist", cfg.MenuAliases[menuID], menuID))
+		}
+	}
+	for _, key := range unionKeys(cfg.Shortcuts, nil) {
+		if _, ok := cfg.Buttons[key]; !ok {
+			l.add(LintWarning, "shortcuts/"+key, fmt.Sprintf("Shortcut '%s' is assigned to menu %s, which does not exist", cfg.Shortcuts[key].Label, key))
+		}
+		if entry := cfg.Shortcuts[key]; len(entry.Sequence) > 0 {
+			if !shortcutSetterAdapter.IsValidShortcut(entry.Sequence...) {
+				l.add(LintError, "shortcuts/"+key, fmt.Sprintf("Shortcut sequence '%s' is invalid", shortcutLabel(entry)))
+			}
+			if entry.SequenceTimeoutMs < 0 {
+				l.add(LintError, "shortcuts/"+key, fmt.Sprintf("Shortcut sequence '%s' has a negative timeout", shortcutLabel(entry)))
+			}
+		}
+		l.lintShortcutTrigger(key, cfg.Shortcuts[key])
+	}
+	for _, c := range DetectShortcutConflicts(cfg.Shortcuts, ctx.PauseToggleKeys) {
+		l.add(LintWarning, "shortcuts/"+c.Shortcuts[0], c.Message)
+	}
+	for _, menuID := range unionKeys(cfg.AppPageRules, nil) {
+		l.lintAppPageRules(menuID, cfg.AppPageRules[menuID])
+	}
+
+	return l.report
+}
+
+// lintShortcutTrigger checks the trigger mode and target page of a menu shortcut.
+func (l *linter) lintShortcutTrigger(key string, entry ShortcutEntry) {
+	loc := "shortcuts/" + key
+	if mode := shortcutMatcher.TriggerMode(entry.TriggerMode); mode != "" {
+		if !slices.Contains(shortcutMatcher.TriggerModes, mode) {
+			l.add(LintError, loc, fmt.Sprintf("Unknown trigger mode '%s'", entry.TriggerMode))
+		} else if mode != shortcutMatcher.TriggerPress && len(entry.Sequence) > 1 {
+			l.add(LintWarning, loc, fmt.Sprintf("Trigger mode '%s' is ignored for shortcut sequences", entry.TriggerMode))
+		}
+	}
+	if entry.PageID != nil {
+		menuID, err := strconv.Atoi(key)
+		if err == nil && !l.pageExists(menuID, *entry.PageID) {
+			l.add(LintError, loc, fmt.Sprintf("Shortcut opens page %d in menu %s, which does not exist", *entry.PageID, key))
+		}
+	}
+}
+
+// lintAppPageRules checks the focused-app rules of one menu.
+func (l *linter) lintAppPageRules(menuID string, rules []appPageRules.Rule) {
+	if _, ok := l.cfg.Buttons[menuID]; !ok {
+		l.add(LintWarning, "appPageRules/"+menuID, fmt.Sprintf("App rules are set for menu %s, which does not exist", menuID))
+		return
+	}
+	menu, _ := strconv.Atoi(menuID)
+	for i, rule := range rules {
+		loc := fmt.Sprintf("appPageRules/%s/%d", menuID, i)
+		if _, err := rule.Matcher(); err != nil {
+			l.add(LintError, loc, fmt.Sprintf("App rule is broken: %v", err))
+		}
+		if !l.pageExists(menu, rule.PageID) {
+			l.add(LintError, loc, fmt.Sprintf("App rule for '%s' opens page %d in menu %s, which does not exist", rule.App, rule.PageID, menuID))
+		}
+	}
+}
+
+type linter struct {
+	cfg    PieMenuConfig
+	ctx    LintContext
+	report LintReport
+}
+
+func (l *linter) add(severity LintSeverity, location, message string) {
+	switch severity {
+	case LintError:
+		l.report.Errors++
+	case LintWarning:
+		l.report.Warnings++
+	}
+	l.report.Findings = append(l.report.Findings, LintFinding{Severity: severity, Location: location, Message: message})
+}
+
+func (l *linter) pageExists(menuID, pageID int) bool {
+	menu, ok := l.cfg.Buttons[strconv.Itoa(menuID)]
+	if !ok {
+		return false
+	}
+	_, ok = menu[strconv.Itoa(pageID)]
+	return ok
+}
+
+func (l *linter) lintButton(loc string, btn Button) {
+	info, ok := core.LookupButtonType(core.ButtonType(btn.ButtonType))
+	if !ok {
+		l.add(LintError, loc, fmt.Sprintf("Unknown button type '%s'", btn.ButtonType))
+		return
+	}
+	props, ok := l.decode(loc, btn, info)
+	if !ok {
+		return
+	}
+
+	switch props := props.(type) {
+	case core.OpenSpecificPieMenuPage:
+		if !l.pageExists(props.MenuID, props.PageID) {
+			l.add(LintError, loc, fmt.Sprintf("Opens page %d in menu %d, which does not exist", props.PageID, props.MenuID))
+		}
+
+	case core.CallFunctionProperties:
+		if props.ButtonTextUpper == "" {
+			l.add(LintWarning, loc, "No function selected")
+		} else if l.ctx.FunctionNames != nil {
+			if _, ok := l.ctx.FunctionNames[props.ButtonTextUpper]; !ok {
+				l.add(LintError, loc, fmt.Sprintf("Function '%s' does not exist", props.ButtonTextUpper))
+			}
+		}
+
+	case core.LaunchProgramProperties:
+		l.checkApp(loc, props.ButtonTextUpper)
+
+	case core.ShowProgramWindowProperties:
+		l.checkApp(loc, props.ButtonTextLower)
+
+	case core.OpenResourceProperties:
+		l.checkResource(loc, props.ResourcePath)
+
+	case core.RunCommandProperties:
+		if props.Executable == "" {
+			l.add(LintWarning, loc, "No command set")
+		}
+
+	case core.TypeTextProperties:
+		if props.Text == "" {
+			l.add(LintWarning, loc, "No text set")
+		} else if _, err := textTemplate.Parse(props.Text); err != nil {
+			l.add(LintError, loc, fmt.Sprintf("Invalid text template: %v", err))
+		}
+
+	case core.MacroProperties:
+		if len(props.Steps) == 0 {
+			l.add(LintWarning, loc, "Macro has no steps")
+		}
+		for i, step := range props.Steps {
+			if step.Type != core.MacroStepDelay {
+				l.lintButton(fmt.Sprintf("%s/steps/%d", loc, i), Button{ButtonType: step.Type, Properties: step.Properties})
+			}
+		}
+
+	default:
+		// Nothing to cross-reference.
+	}
+}
+
+// decode decodes the button properties for its registered type, reporting a finding on failure.
+func (l *linter) decode(loc string, btn Button, info *core.ButtonTypeInfo) (any, bool) {
+	props, err := info.DecodeProperties(btn.Properties)
+	if err == nil {
+		return props, true
+	}
+	if len(btn.Properties) == 0 {
+		l.add(LintError, loc, fmt.Sprintf("%s button has no properties", btn.ButtonType))
+	} else {
+		l.add(LintError, loc, fmt.Sprintf("Invalid %s properties: %v", btn.ButtonType, errors.Unwrap(err)))
+	}
+	return nil, false
+}
+
+func (l *linter) checkApp(loc, appName string) {
+	if appName == "" {
+		l.add(LintWarning, loc, "No program selected")
+		return
+	}
+	if l.ctx.InstalledApps == nil {
+		return
+	}
+	if _, ok := l.ctx.InstalledApps[appName]; !ok {
+		l.add(LintWarning, loc, fmt.Sprintf("Program '%s' is not installed", appName))
+	}
+}
+
+func (l *linter) checkResource(loc, path string) {
+	if path == "" {
+		l.add(LintWarning, loc, "No resource path set")
+		return
+	}
+	// The executor stats the path as-is, so do the same here.
+	if !l.ctx.PathExists(path) {
+		l.add(LintWarning, loc, fmt.Sprintf("Resource '%s' does not exist", path))
+	}
+}
+
+// loadFunctionNames reads the names of all known button functions from buttonFunctions.json.
+func loadFunctionNames() (map[string]struct{}, error) {
+	assetDir, err := core.GetAssetDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine asset dir: %w", err)
+	}
+	rel := os.Getenv("PUBLIC_DIR_BUTTONFUNCTIONS")
+	if rel == "" {
+		return nil, fmt.Errorf("PUBLIC_DIR_BUTTONFUNCTIONS environment variable not set")
+	}
+	data, err := os.ReadFile(filepath.Join(assetDir, rel))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read buttonFunctions.json: %w", err)
+	}
+	var functions map[string]json.RawMessage
+	if err := json.Unmarshal(data, &functions); err != nil {
+		return nil, fmt.Errorf("failed to parse buttonFunctions.json: %w", err)
+	}
+	names := make(map[string]struct{}, len(functions))
+	for name := range functions {
+		names[name] = struct{}{}
+	}
+	return names, nil
+}
+
+// LintConfigFile lints the config at path (the AppData config if empty) for the CLI.
+// installedApps may be nil to skip program checks.
+func LintConfigFile(path string, installedApps map[string]core.AppInfo) (LintReport, error) {
+	if path == "" {
+		appDataDir, err := core.GetAppDataDir()
+		if err != nil {
+			return LintReport{}, err
+		}
+		path = filepath.Join(appDataDir, os.Getenv("PUBLIC_DIR_PIEMENUCONFIG"))
+	}
+	cfg, err := ReadConfigFromFile(path)
+	if err != nil {
+		return LintReport{}, err
+	}
+
+	ctx := LintContext{InstalledApps: installedApps}
+	if names, err := loadFunctionNames(); err != nil {
+		log.Warn("Skipping function checks: %v", err)
+	} else {
+		ctx.FunctionNames = names
+	}
+	return LintConfig(cfg, ctx), nil
+}
+
+// subscribeLint tracks installed apps and answers lint requests.
+func (a *Adapter) subscribeLint() {
+	if names, err := loadFunctionNames(); err != nil {
+		log.Warn("Config linter cannot check functions: %v", err)
+	} else {
+		a.functionNames = names
+	}
+
+	a.nats.SubscribeToSubject(os.Getenv("PUBLIC_NATSSUBJECT_WINDOWMANAGER_INSTALLEDAPPSINFO"), func(msg *nats.Msg) {
+		var apps map[string]core.AppInfo
+		if err := json.Unmarshal(msg.Data, &apps); err != nil {
+			log.Error("Failed to decode installed apps message: %v", err)
+			return
+		}
+		a.mu.Lock()
+		a.installedApps = apps
+		a.mu.Unlock()
+	})
+
+	natsAdapter.HandleRequest(a.nats, os.Getenv("PUBLIC_NATSSUBJECT_PIEMENUCONFIG_LINT"),
+		func(req LintRequest) (LintReport, error) {
+			a.mu.RLock()
+			cfg := a.cfg
+			ctx := LintContext{FunctionNames: a.functionNames, InstalledApps: a.installedApps, PauseToggleKeys: a.pauseToggleKeys}
+			a.mu.RUnlock()
+			if req.Config != nil {
+				cfg = *req.Config
+			}
+			report := LintConfig(cfg, ctx)
+			log.Info("Config lint: %d error(s), %d warning(s)", report.Errors, report.Warnings)
+			return report, nil
+		})
+}
@@ -0,0 +1,168 @@
+package piemenuConfigManager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core"
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core/jsonUtils"
+)
+
+// CurrentSchemaVersion is the schema version written by this build.
+// Bump it together with a new entry in configMigrations.
+const CurrentSchemaVersion = 1
+
+// ErrNewerSchemaVersion is returned when a config file was written by a newer build.
+// Such files are never rewritten, so fields unknown to this build are not lost.
+var ErrNewerSchemaVersion = errors.New("config was written by a newer version of MightyPie")
+
+// rawConfig is the untyped top-level document a migration step operates on.
+type rawConfig map[string]json.RawMessage
+
+// configMigration upgrades a document from version From to From+1.
+type configMigration struct {
+	From        int
+	Description string
+	Apply       func(doc rawConfig) (rawConfig, error)
+}
+
+// configMigrations is the ordered chain of registered steps. configMigrations[i].From must equal i.
+var configMigrations = []configMigration{
+	{From: 0, Description: "wrap legacy buttons-only files and add schemaVersion", Apply: migrateV0ToV1},
+}
+
+// migrateV0ToV1 wraps legacy buttons-only files (MenuID -> PageID -> ButtonID) into the
+// full PieMenuConfig layout. Unversioned full-format files pass through unchanged.
+func migrateV0ToV1(doc rawConfig) (rawConfig, error) {
+	if _, ok := doc["buttons"]; ok {
+		return doc, nil
+	}
+	if _, ok := doc["shortcuts"]; ok {
+		return doc, nil
+	}
+	if _, ok := doc["starred"]; ok {
+		return doc, nil
+	}
+
+	legacy, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var buttons ConfigData
+	if err := json.Unmarshal(legacy, &buttons); err != nil {
+		return nil, fmt.Errorf("legacy buttons-only config is invalid: %w", err)
+	}
+	buttonsRaw, err := json.Marshal(buttons)
+	if err != nil {
+		return nil, err
+	}
+	return rawConfig{
+		"buttons":   buttonsRaw,
+		"shortcuts": json.RawMessage(`{}`),
+		"starred":   json.RawMessage(`null`),
+	}, nil
+}
+
+// schemaVersionOf returns the schemaVersion stored in doc, or 0 for unversioned files.
+func schemaVersionOf(doc rawConfig) (int, error) {
+	raw, ok := doc["schemaVersion"]
+	if !ok {
+		return 0, nil
+	}
+	var version int
+	if err := json.Unmarshal(raw, &version); err != nil {
+		return 0, fmt.Errorf("invalid schemaVersion: %w", err)
+	}
+	if version < 0 {
+		return 0, fmt.Errorf("invalid schemaVersion: %d", version)
+	}
+	return version, nil
+}
+
+// migrateConfig upgrades raw file contents to CurrentSchemaVersion.
+// It returns the decoded config and the schema version the data was written with.
+func migrateConfig(data []byte) (PieMenuConfig, int, error) {
+	var doc rawConfig
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return PieMenuConfig{}, 0, fmt.Errorf("invalid PieMenuConfig format: %w", err)
+	}
+	if doc == nil {
+		return PieMenuConfig{}, 0, errors.New("invalid PieMenuConfig format: empty document")
+	}
+
+	fromVersion, err := schemaVersionOf(doc)
+	if err != nil {
+		return PieMenuConfig{}, 0, err
+	}
+	if fromVersion > CurrentSchemaVersion {
+		return PieMenuConfig{}, fromVersion, fmt.Errorf("%w (file schema version %d, supported up to %d)",
+			ErrNewerSchemaVersion, fromVersion, CurrentSchemaVersion)
+	}
+
+	for version := fromVersion; version < CurrentSchemaVersion; version++ {
+		step := configMigrations[version]
+		log.Info("Migrating pie menu config v%d -> v%d: %s", step.From, step.From+1, step.Description)
+		if doc, err = step.Apply(doc); err != nil {
+			return PieMenuConfig{}, fromVersion, fmt.Errorf("migration v%d -> v%d failed: %w", step.From, step.From+1, err)
+		}
+		doc["schemaVersion"] = json.RawMessage(fmt.Sprintf("%d", step.From+1))
+	}
+
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return PieMenuConfig{}, fromVersion, err
+	}
+	var cfg PieMenuConfig
+	if err := json.Unmarshal(migrated, &cfg); err != nil {
+		return PieMenuConfig{}, fromVersion, fmt.Errorf("invalid PieMenuConfig format: %w", err)
+	}
+	normalizeConfig(&cfg)
+	return cfg, fromVersion, nil
+}
+
+// backupPreMigrationFile copies the untouched file at path into the backups directory
+// before a migrated config overwrites it.
+func backupPreMigrationFile(path string, fromVersion int) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	appDataDir, err := core.GetAppDataDir()
+	if err != nil {
+		return "", err
+	}
+	backupDir := filepath.Join(appDataDir, os.Getenv("PUBLIC_DIR_CONFIGBACKUPS"))
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backups directory '%s': %w", backupDir, err)
+	}
+
+	name := fmt.Sprintf("piemenuConfig_PRE_MIGRATION_v%d_%s.json", fromVersion, time.Now().Format("20060102_150405"))
+	backupPath := filepath.Join(backupDir, name)
+	if err := jsonUtils.WriteFileAtomic(backupPath, data); err != nil {
+		return "", err
+	}
+	return backupPath, nil
+}
+
+// normalizeConfig ensures non-nil maps and stamps the current schema version.
+func normalizeConfig(cfg *PieMenuConfig) {
+	if cfg.Buttons == nil {
+		cfg.Buttons = ConfigData{}
+	}
+	if cfg.Shortcuts == nil {
+		cfg.Shortcuts = map[string]ShortcutEntry{}
+	}
+	// Consumers that predate sequences only read Codes; keep it in sync with the final chord.
+	for key, entry := range cfg.Shortcuts {
+		if len(entry.Sequence) > 0 {
+			entry.Codes = slices.Clone(entry.Sequence[len(entry.Sequence)-1])
+			cfg.Shortcuts[key] = entry
+		}
+	}
+	cfg.SchemaVersion = CurrentSchemaVersion
+}
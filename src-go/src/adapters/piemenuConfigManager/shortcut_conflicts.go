@@ -0,0 +1,279 @@
+package piemenuConfigManager
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core"
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core/shortcutMatcher"
+	"github.com/nats-io/nats.go"
+)
+
+// ShortcutConflictKind classifies a shortcut conflict.
+type ShortcutConflictKind string
+
+const (
+	// ConflictDuplicate: both shortcuts fire on exactly the same key combinations.
+	ConflictDuplicate ShortcutConflictKind = "duplicate"
+	// ConflictShadowed: pressing one shortcut also satisfies the other (e.g. Ctrl+A inside Ctrl+Shift+A).
+	ConflictShadowed ShortcutConflictKind = "shadowed"
+	// ConflictPauseToggle: the shortcut collides with the pauseToggleShortcut setting,
+	// which is checked first and therefore always wins.
+	ConflictPauseToggle ShortcutConflictKind = "pause_toggle"
+)
+
+// ShortcutConflict describes shortcuts that can fire on the same key press.
+// Shortcuts holds the menu keys involved, in the order the detector would prefer them.
+type ShortcutConflict struct {
+	Kind      ShortcutConflictKind `json:"kind"`
+	Shortcuts []string             `json:"shortcuts"`
+	TargetApp string               `json:"targetApp,omitempty"`
+	Message   string               `json:"message"`
+}
+
+// ShortcutConflictReport is published after every config change.
+type ShortcutConflictReport struct {
+	Revision  int                `json:"revision"`
+	Conflicts []ShortcutConflict `json:"conflicts"`
+}
+
+func targetAppOf(e ShortcutEntry) string {
+	if e.TargetApp == nil {
+		return ""
+	}
+	return *e.TargetApp
+}
+
+// chordsOf returns the chords of a shortcut: its sequence, or Codes as a single chord.
+func chordsOf(e ShortcutEntry) [][]int {
+	if len(e.Sequence) > 0 {
+		return e.Sequence
+	}
+	if len(e.Codes) == 0 {
+		return nil
+	}
+	return [][]int{e.Codes}
+}
+
+// firesOnChords reports whether a fires while b is entered: a is not longer than b and each of
+// a's chords fires on the corresponding chord of b. A plain shortcut therefore shadows every
+// sequence whose leader chord it matches, and a sequence shadows the longer ones it starts.
+func firesOnChords(a, b [][]int) bool {
+	if len(a) == 0 || len(a) > len(b) {
+		return false
+	}
+	for i := range a {
+		if !shortcutMatcher.FiresOn(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// distinctTriggers reports whether two plain shortcuts are told apart by their trigger modes,
+// e.g. tap for one menu and hold for another on the same keys.
+func distinctTriggers(a, b ShortcutEntry) bool {
+	if len(a.Sequence) > 1 || len(b.Sequence) > 1 {
+		return false
+	}
+	ta, tb := shortcutMatcher.TriggerMode(a.TriggerMode), shortcutMatcher.TriggerMode(b.TriggerMode)
+	isPress := func(t shortcutMatcher.TriggerMode) bool { return t == "" || t == shortcutMatcher.TriggerPress }
+	return ta != tb && !isPress(ta) && !isPress(tb)
+}
+
+// DetectShortcutConflicts finds shortcuts that can fire on the same key press within the same
+// targetApp scope, plus collisions with the pause toggle (RobotGo format, e.g. "ctrl+shift+p").
+// A global shortcut that loses to a targetApp one inside that app is reported as shadowed,
+// scoped to the app (see scopedShadow). Shortcuts of two different apps never conflict.
+func DetectShortcutConflicts(shortcuts map[string]ShortcutEntry, pauseToggleKeys string) []ShortcutConflict {
+	keys := unionKeys(shortcuts, nil)
+	sortByShortcutPriority(keys, shortcuts)
+
+	var conflicts []ShortcutConflict
+	for i, ka := range keys {
+		a := shortcuts[ka]
+		chordsA := chordsOf(a)
+		if len(chordsA) == 0 {
+			continue
+		}
+		for _, kb := range keys[i+1:] {
+			b := shortcuts[kb]
+			chordsB := chordsOf(b)
+			if len(chordsB) == 0 {
+				continue
+			}
+			if appA, appB := targetAppOf(a), targetAppOf(b); appA != appB {
+				if appA == "" || appB == "" {
+					if conflict, ok := scopedShadow(ka, kb, shortcuts); ok {
+						conflicts = append(conflicts, conflict)
+					}
+				}
+				continue
+			}
+			if distinctTriggers(a, b) {
+				continue
+			}
+			aOnB, bOnA := firesOnChords(chordsA, chordsB), firesOnChords(chordsB, chordsA)
+			switch {
+			case aOnB && bOnA:
+				conflicts = append(conflicts, ShortcutConflict{
+					Kind:      ConflictDuplicate,
+					Shortcuts: []string{ka, kb},
+					TargetApp: targetAppOf(a),
+					Message: fmt.Sprintf("Menus %s and %s use the same shortcut '%s'; menu %s wins",
+						ka, kb, shortcutLabel(a), ka),
+				})
+			case aOnB || bOnA:
+				inner, outer := ka, kb
+				if bOnA {
+					inner, outer = kb, ka
+				}
+				// A shorter chord list fires before the longer one can complete.
+				winner, pair := ka, []string{ka, kb}
+				if len(chordsA) != len(chordsB) && inner == kb {
+					winner, pair = kb, []string{kb, ka}
+				}
+				conflicts = append(conflicts, ShortcutConflict{
+					Kind:      ConflictShadowed,
+					Shortcuts: pair,
+					TargetApp: targetAppOf(a),
+					Message: fmt.Sprintf("Shortcut '%s' (menu %s) also matches '%s' (menu %s); menu %s wins",
+						shortcutLabel(shortcuts[inner]), inner, shortcutLabel(shortcuts[outer]), outer, winner),
+				})
+			}
+		}
+	}
+
+	if pauseCodes, ok := core.ParseRobotGoShortcut(pauseToggleKeys); ok {
+		for _, k := range keys {
+			// The pause toggle is checked before anything else, including pending sequences.
+			if slices.ContainsFunc(chordsOf(shortcuts[k]), func(chord []int) bool {
+				return shortcutMatcher.FiresOn(chord, pauseCodes)
+			}) {
+				conflicts = append(conflicts, ShortcutConflict{
+					Kind:      ConflictPauseToggle,
+					Shortcuts: []string{k},
+					TargetApp: targetAppOf(shortcuts[k]),
+					Message: fmt.Sprintf("Shortcut '%s' (menu %s) collides with the pause toggle '%s', which takes precedence",
+						shortcutLabel(shortcuts[k]), k, pauseToggleKeys),
+				})
+			}
+		}
+	}
+	return conflicts
+}
+
+// scopedShadow checks a pair of shortcuts of which one is global and the other targets an app.
+// In that app the matcher only considers the app's shortcuts, so the app one wins wherever it
+// fires, whatever its trigger mode; the global one still wins where it completes with fewer
+// chords, because a plain shortcut fires before a sequence can start.
+func scopedShadow(ka, kb string, shortcuts map[string]ShortcutEntry) (ShortcutConflict, bool) {
+	global, scoped := ka, kb
+	if targetAppOf(shortcuts[ka]) != "" {
+		global, scoped = kb, ka
+	}
+	chordsG, chordsS := chordsOf(shortcuts[global]), chordsOf(shortcuts[scoped])
+
+	var winner, loser string
+	switch {
+	case firesOnChords(chordsS, chordsG):
+		winner, loser = scoped, global
+	case firesOnChords(chordsG, chordsS) && len(chordsG) < len(chordsS):
+		winner, loser = global, scoped
+	default:
+		return ShortcutConflict{}, false
+	}
+	app := targetAppOf(shortcuts[scoped])
+	return ShortcutConflict{
+		Kind:      ConflictShadowed,
+		Shortcuts: []string{winner, loser},
+		TargetApp: app,
+		Message: fmt.Sprintf("In %s, shortcut '%s' (menu %s) also matches '%s' (menu %s); menu %s wins there",
+			app, shortcutLabel(shortcuts[winner]), winner, shortcutLabel(shortcuts[loser]), loser, winner),
+	}, true
+}
+
+// sortByShortcutPriority orders menu keys the way the shortcut matcher breaks ties within a scope.
+func sortByShortcutPriority(keys []string, shortcuts map[string]ShortcutEntry) {
+	slices.SortFunc(keys, func(a, b string) int {
+		return shortcutMatcher.ComparePriority(a, matcherShortcut(shortcuts[a]), b, matcherShortcut(shortcuts[b]))
+	})
+}
+
+// matcherShortcut returns the part of e the matcher's priority depends on.
+func matcherShortcut(e ShortcutEntry) shortcutMatcher.Shortcut {
+	return shortcutMatcher.Shortcut{Codes: e.Codes, Sequence: e.Sequence}
+}
+
+func shortcutLabel(e ShortcutEntry) string {
+	if e.Label != "" {
+		return e.Label
+	}
+	chords := chordsOf(e)
+	labels := make([]string, len(chords))
+	for i, chord := range chords {
+		names := make([]string, len(chord))
+		for j, c := range chord {
+			if names[j] = core.FindKeyByValue(c); names[j] == "" {
+				names[j] = fmt.Sprintf("0x%X", c)
+			}
+		}
+		labels[i] = strings.Join(names, "+")
+	}
+	return strings.Join(labels, ", ")
+}
+
+// subscribeShortcutConflicts tracks the pause toggle setting so conflicts with it can be reported.
+func (a *Adapter) subscribeShortcutConflicts() {
+	settingsSubject := os.Getenv("PUBLIC_NATSSUBJECT_SETTINGS_UPDATE")
+	err := a.nats.SubscribeJetStreamPull(settingsSubject, "piemenuConfigManager_reader", func(msg *nats.Msg) {
+		var settings map[string]struct {
+			Value json.RawMessage `json:"value"`
+		}
+		if err := json.Unmarshal(msg.Data, &settings); err != nil {
+			log.Error("Failed to decode settings update: %v", err)
+			return
+		}
+		var pause struct {
+			Keys string `json:"keys"`
+		}
+		if entry, ok := settings["pauseToggleShortcut"]; ok {
+			_ = json.Unmarshal(entry.Value, &pause)
+		}
+
+		a.mu.Lock()
+		changed := a.pauseToggleKeys != pause.Keys
+		a.pauseToggleKeys = pause.Keys
+		a.mu.Unlock()
+		if changed {
+			a.publishShortcutConflicts()
+		}
+	})
+	if err != nil {
+		log.Error("Failed to subscribe to settings updates: %v", err)
+	}
+}
+
+// publishShortcutConflicts reports the shortcut conflicts of the current config.
+func (a *Adapter) publishShortcutConflicts() {
+	if a.conflictsSubject == "" {
+		return
+	}
+	a.mu.RLock()
+	report := ShortcutConflictReport{
+		Revision:  a.history.currentRevision(),
+		Conflicts: DetectShortcutConflicts(a.cfg.Shortcuts, a.pauseToggleKeys),
+	}
+	a.mu.RUnlock()
+
+	if report.Conflicts == nil {
+		report.Conflicts = []ShortcutConflict{}
+	}
+	for _, c := range report.Conflicts {
+		log.Warn("Shortcut conflict (%s): %s", c.Kind, c.Message)
+	}
+	a.nats.PublishMessage(a.conflictsSubject, report)
+}
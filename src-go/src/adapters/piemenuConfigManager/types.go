@@ -1,6 +1,10 @@
 package piemenuConfigManager
 
-import "encoding/json"
+import (
+    "encoding/json"
+
+    "github.com/Rayzorblade23/MightyPie-Revamped/src/core/appPageRules"
+)
 
 // Mirror the frontend structure for the config file
 
@@ -24,6 +28,13 @@ type ShortcutEntry struct {
     Codes     []int   `json:"codes"`
     Label     string  `json:"label"`
     TargetApp *string `json:"targetApp,omitempty"`
+    // Sequence optionally lists chords pressed one after another; Codes mirrors the final chord.
+    Sequence          [][]int `json:"sequence,omitempty"`
+    SequenceTimeoutMs int     `json:"sequenceTimeoutMs,omitempty"`
+    // TriggerMode is "press" (default), "tap", "hold" or "double_tap".
+    TriggerMode string `json:"triggerMode,omitempty"`
+    // PageID, if set, opens the menu on this page.
+    PageID *int `json:"pageID,omitempty"`
 }
 
 type StarredFavorite struct {
@@ -32,8 +43,11 @@ type StarredFavorite struct {
 }
 
 type PieMenuConfig struct {
-    Buttons     ConfigData                `json:"buttons"`
-    Shortcuts   map[string]ShortcutEntry `json:"shortcuts"`
-    Starred     *StarredFavorite          `json:"starred"`
-    MenuAliases map[string]string         `json:"menuAliases,omitempty"`
+    SchemaVersion int                      `json:"schemaVersion"`
+    Buttons       ConfigData               `json:"buttons"`
+    Shortcuts     map[string]ShortcutEntry `json:"shortcuts"`
+    Starred       *StarredFavorite         `json:"starred"`
+    MenuAliases   map[string]string        `json:"menuAliases,omitempty"`
+    // AppPageRules maps a menu ID to rules picking its page from the focused app; first match wins.
+    AppPageRules map[string][]appPageRules.Rule `json:"appPageRules,omitempty"`
 }
@@ -3,9 +3,11 @@ package settingsManagerAdapter
 import (
 	"slices"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/Rayzorblade23/MightyPie-Revamped/src/adapters/natsAdapter"
 	core "github.com/Rayzorblade23/MightyPie-Revamped/src/core"
@@ -23,7 +25,12 @@ type SettingsManagerAdapter struct {
 	natsAdapter *natsAdapter.NatsAdapter
 }
 
-var currentSettings map[string]SettingsEntry
+var (
+	currentSettings map[string]SettingsEntry
+	// settingsRevision is bumped whenever currentSettings changes.
+	settingsRevision int
+	settingsMu       sync.RWMutex
+)
 
 func New(natsAdapter *natsAdapter.NatsAdapter) *SettingsManagerAdapter {
 	a := &SettingsManagerAdapter{
@@ -32,11 +39,31 @@ func New(natsAdapter *natsAdapter.NatsAdapter) *SettingsManagerAdapter {
 
 	subject := os.Getenv("PUBLIC_NATSSUBJECT_SETTINGS_UPDATE")
 
-	settings, err := ReadSettings()
+	settings, recovery, err := ReadSettings()
 	if err != nil {
 		log.Fatal("Failed to read settings.json: %v", err)
 	}
+	settingsMu.Lock()
 	currentSettings = settings
+	settingsRevision = 1
+	settingsMu.Unlock()
+
+	if recovery != nil {
+		message := "Settings file was damaged; restored the last good copy"
+		if recovery.FromDefaults {
+			message = "Settings file was damaged and had no usable backup; reset to defaults"
+		} else {
+			log.Warn("settings.json was damaged (%s); restored last good copy from '%s'", recovery.Reason, recovery.BackupPath)
+		}
+		if loadErrorSubject := os.Getenv("PUBLIC_NATSSUBJECT_SETTINGS_LOAD_ERROR"); loadErrorSubject != "" {
+			a.natsAdapter.PublishMessage(loadErrorSubject, map[string]any{
+				"path":      recovery.Path,
+				"error":     recovery.Reason,
+				"message":   message,
+				"recovered": true,
+			})
+		}
+	}
 
 	a.natsAdapter.PublishMessage(subject, settings)
 	log.Info("Initial settings published.")
@@ -57,7 +84,9 @@ func New(natsAdapter *natsAdapter.NatsAdapter) *SettingsManagerAdapter {
 		log.Info("[SettingsManager] Received settings update with %d entries", len(newSettings))
 		
 		// Only write if settings have changed
+		settingsMu.RLock()
 		equal := settingsEqual(currentSettings, newSettings)
+		settingsMu.RUnlock()
 		log.Info("[SettingsManager] settingsEqual returned: %v", equal)
 		
 		if !equal {
@@ -66,16 +95,33 @@ func New(natsAdapter *natsAdapter.NatsAdapter) *SettingsManagerAdapter {
 				log.Error("Failed to write settings.json: %v", err)
 				return
 			}
+			settingsMu.Lock()
 			currentSettings = newSettings
+			settingsRevision++
+			settingsMu.Unlock()
 			log.Info("settings.json updated from NATS message.")
 		} else {
-			log.Warn("[SettingsManager] Received settings update, but no changes detected. This might be a bug if you just changed settings in the UI.")
+			// Patches are stored before they are published, so their updates arrive unchanged
+			log.Debug("[SettingsManager] Received settings update, but no changes detected.")
 		}
 	})
 
+	a.handleGetRequests()
+	a.handlePatchRequests()
+
 	return a
 }
 
+// handleGetRequests answers `get` requests with the current settings and their revision.
+func (a *SettingsManagerAdapter) handleGetRequests() {
+	natsAdapter.HandleRequest(a.natsAdapter, os.Getenv("PUBLIC_NATSSUBJECT_SETTINGS_GET"),
+		func(struct{}) (natsAdapter.Snapshot[map[string]SettingsEntry], error) {
+			settingsMu.RLock()
+			defer settingsMu.RUnlock()
+			return natsAdapter.Snapshot[map[string]SettingsEntry]{Revision: settingsRevision, Data: currentSettings}, nil
+		})
+}
+
 // SettingsEntry represents a single settings entry with type info, value, and metadata.
 type SettingsEntry struct {
 	Index        int      `json:"index"`
@@ -89,38 +135,51 @@ type SettingsEntry struct {
 	Options      []string `json:"options,omitempty"` // Only for enum type
 }
 
-func ReadSettings() (map[string]SettingsEntry, error) {
+// ReadSettings loads settings.json, creating it from the defaults if needed.
+// If the file is damaged, the last good copy is used and described by the returned Recovery.
+func ReadSettings() (map[string]SettingsEntry, *jsonUtils.Recovery, error) {
 	appDataDir, err := core.GetAppDataDir()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	settingsPath := filepath.Join(appDataDir, os.Getenv("PUBLIC_DIR_SETTINGS"))
 
 	// Ensure the settings file exists by copying the default if needed.
 	assetDir, err := core.GetAssetDir()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get asset dir for default settings: %w", err)
+		return nil, nil, fmt.Errorf("failed to get asset dir for default settings: %w", err)
 	}
 	defaultSettingsRel := os.Getenv("PUBLIC_DIR_DEFAULTSETTINGS")
 	if defaultSettingsRel == "" {
-		return nil, fmt.Errorf("environment variable PUBLIC_DIR_DEFAULTSETTINGS is not set")
+		return nil, nil, fmt.Errorf("environment variable PUBLIC_DIR_DEFAULTSETTINGS is not set")
 	}
 	defaultSettingsPath := filepath.Join(assetDir, defaultSettingsRel)
 
 	if err := jsonUtils.CreateFileFromDefaultIfNotExist(defaultSettingsPath, settingsPath); err != nil {
-		return nil, fmt.Errorf("failed to copy default settings if needed: %w", err)
+		return nil, nil, fmt.Errorf("failed to copy default settings if needed: %w", err)
 	}
 
 	// Load default settings for validation
 	var defaultSettings map[string]SettingsEntry
 	if err := jsonUtils.ReadFromFile(defaultSettingsPath, &defaultSettings); err != nil {
-		return nil, fmt.Errorf("failed to read default settings for validation: %w", err)
+		return nil, nil, fmt.Errorf("failed to read default settings for validation: %w", err)
 	}
 
-	// Load user settings
+	// Load user settings, falling back to the last good copy if the file is damaged
 	var settings map[string]SettingsEntry
-	if err := jsonUtils.ReadFromFile(settingsPath, &settings); err != nil {
-		return nil, err
+	recovery, err := jsonUtils.ReadFromFileWithRecovery(settingsPath, &settings)
+	if errors.Is(err, jsonUtils.ErrNoUsableBackup) {
+		// The damaged file was moved aside; the validation below rebuilds everything from defaults
+		log.Warn("settings.json is damaged and has no usable backup, starting from defaults: %v", err)
+		settings = nil
+		recovery = &jsonUtils.Recovery{
+			Path:         settingsPath,
+			BackupPath:   defaultSettingsPath,
+			Reason:       err.Error(),
+			FromDefaults: true,
+		}
+	} else if err != nil {
+		return nil, nil, err
 	}
 
 	// Initialize settings map if it's nil
@@ -212,7 +271,7 @@ func ReadSettings() (map[string]SettingsEntry, error) {
 		}
 	}
 
-	return settings, nil
+	return settings, recovery, nil
 }
 
 // WriteSettings saves the settings map to settings.json.
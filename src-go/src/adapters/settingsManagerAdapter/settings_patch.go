@@ -0,0 +1,115 @@
+package settingsManagerAdapter
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+)
+
+// SettingsPatchRequest maps setting names to their new values, e.g. {"pauseOnEdgeProximity": true}.
+type SettingsPatchRequest map[string]json.RawMessage
+
+// SettingsPatchResult is the reply to a patch request. Unknown lists the names that aren't
+// settings; nothing is applied then.
+type SettingsPatchResult struct {
+	OK       bool                     `json:"ok"`
+	Unknown  []string                 `json:"unknown,omitempty"`
+	Revision int                      `json:"revision"`
+	Settings map[string]SettingsEntry `json:"settings,omitempty"`
+	Error    string                   `json:"error,omitempty"`
+}
+
+// handlePatchRequests sets the values of the named settings and leaves all others as they are,
+// so patches don't overwrite changes saved in the meantime.
+func (a *SettingsManagerAdapter) handlePatchRequests() {
+	updateSubject := os.Getenv("PUBLIC_NATSSUBJECT_SETTINGS_UPDATE")
+
+	a.natsAdapter.SubscribeToSubject(os.Getenv("PUBLIC_NATSSUBJECT_SETTINGS_PATCH"), func(msg *nats.Msg) {
+		var req SettingsPatchRequest
+		if err := json.Unmarshal(msg.Data, &req); err != nil || len(req) == 0 {
+			replyPatch(msg, SettingsPatchResult{Error: "expected an object of setting names and values"})
+			return
+		}
+
+		result := applyPatch(req)
+		if !result.OK {
+			log.Warn("Rejected settings patch: %s", result.Error)
+			replyPatch(msg, result)
+			return
+		}
+
+		// Other workers pick up settings from the update subject; the writer finds them unchanged
+		a.natsAdapter.PublishMessage(updateSubject, result.Settings)
+		log.Info("Applied settings patch (%d settings): revision %d", len(req), result.Revision)
+		replyPatch(msg, result)
+	})
+}
+
+// applyPatch applies req to the current settings under settingsMu and writes them to
+// settings.json before releasing it.
+func applyPatch(req SettingsPatchRequest) SettingsPatchResult {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+
+	patched, unknown, err := patchSettings(currentSettings, req)
+	if err != nil {
+		return SettingsPatchResult{Unknown: unknown, Revision: settingsRevision, Error: err.Error()}
+	}
+	if err := WriteSettings(patched); err != nil {
+		return SettingsPatchResult{Revision: settingsRevision, Error: fmt.Sprintf("failed to write settings.json: %v", err)}
+	}
+	currentSettings = patched
+	settingsRevision++
+	return SettingsPatchResult{OK: true, Revision: settingsRevision, Settings: patched}
+}
+
+// patchSettings returns a copy of settings with the values of req. It fails without changes
+// if a name is not a setting, listing those names sorted, or if a value doesn't fit its setting.
+func patchSettings(settings map[string]SettingsEntry, req SettingsPatchRequest) (map[string]SettingsEntry, []string, error) {
+	var unknown []string
+	for name := range req {
+		if _, exists := settings[name]; !exists {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		return nil, unknown, fmt.Errorf("unknown settings: %s", strings.Join(unknown, ", "))
+	}
+
+	patched := maps.Clone(settings)
+	for name, raw := range req {
+		entry := patched[name]
+		var value any
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, nil, fmt.Errorf("invalid value for '%s': %v", name, err)
+		}
+		if entry.Type == "enum" && len(entry.Options) > 0 {
+			if option, ok := value.(string); !ok || !slices.Contains(entry.Options, option) {
+				return nil, nil, fmt.Errorf("invalid value for '%s': expected one of %s", name, strings.Join(entry.Options, ", "))
+			}
+		}
+		entry.Value = value
+		patched[name] = entry
+	}
+	return patched, nil, nil
+}
+
+func replyPatch(msg *nats.Msg, result SettingsPatchResult) {
+	if msg.Reply == "" {
+		return
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		log.Error("Failed to marshal reply for '%s': %v", msg.Subject, err)
+		return
+	}
+	if err := msg.Respond(data); err != nil {
+		log.Error("Failed to reply on '%s': %v", msg.Subject, err)
+	}
+}
@@ -0,0 +1,158 @@
+package settingsManagerAdapter
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core/jsonUtils"
+)
+
+func testSettings() map[string]SettingsEntry {
+	return map[string]SettingsEntry{
+		"pauseOnEdgeProximity": {Label: "Pause near edges", Type: "bool", Value: false, DefaultValue: false},
+		"windowAssignmentStrategy": {
+			Label:        "Window order",
+			Type:         "enum",
+			Value:        "Handle Order",
+			DefaultValue: "Handle Order",
+			Options:      []string{"Handle Order", "Alphabetical"},
+		},
+		"menuOpacity": {Label: "Opacity", Type: "float", Value: 0.9, DefaultValue: 0.9},
+	}
+}
+
+func TestPatchSettings(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		want  map[string]any
+	}{
+		{"one setting", `{"pauseOnEdgeProximity":true}`, map[string]any{"pauseOnEdgeProximity": true}},
+		{
+			"several settings",
+			`{"menuOpacity":0.5,"windowAssignmentStrategy":"Alphabetical"}`,
+			map[string]any{"menuOpacity": 0.5, "windowAssignmentStrategy": "Alphabetical"},
+		},
+		{"same value", `{"menuOpacity":0.9}`, map[string]any{"menuOpacity": 0.9}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req SettingsPatchRequest
+			if err := json.Unmarshal([]byte(tt.patch), &req); err != nil {
+				t.Fatal(err)
+			}
+			settings := testSettings()
+			patched, unknown, err := patchSettings(settings, req)
+			if err != nil || unknown != nil {
+				t.Fatalf("patchSettings returned %v, %v; want no error", unknown, err)
+			}
+			for name, entry := range testSettings() {
+				want, changed := tt.want[name]
+				if !changed {
+					want = entry.Value
+				}
+				if got := patched[name]; got.Value != want || got.Label != entry.Label {
+					t.Errorf("%s = %+v, want value %v and the rest unchanged", name, got, want)
+				}
+				if settings[name].Value != entry.Value {
+					t.Errorf("%s was changed in the current settings", name)
+				}
+			}
+		})
+	}
+}
+
+func TestPatchSettingsErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		patch       SettingsPatchRequest
+		wantUnknown []string
+		wantErr     string
+	}{
+		{
+			"unknown settings",
+			SettingsPatchRequest{"zoom": json.RawMessage(`2`), "pauseOnEdgeProximity": json.RawMessage(`true`), "theme": json.RawMessage(`"dark"`)},
+			[]string{"theme", "zoom"},
+			"unknown settings: theme, zoom",
+		},
+		{"invalid json", SettingsPatchRequest{"menuOpacity": json.RawMessage(`0.`)}, nil, "invalid value for 'menuOpacity'"},
+		{
+			"enum value that is no option",
+			SettingsPatchRequest{"windowAssignmentStrategy": json.RawMessage(`"Random"`)},
+			nil,
+			"invalid value for 'windowAssignmentStrategy': expected one of Handle Order, Alphabetical",
+		},
+		{"enum value that is no string", SettingsPatchRequest{"windowAssignmentStrategy": json.RawMessage(`1`)}, nil, "expected one of"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patched, unknown, err := patchSettings(testSettings(), tt.patch)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("patchSettings error = %v, want %q", err, tt.wantErr)
+			}
+			if patched != nil {
+				t.Errorf("patchSettings returned settings %v alongside the error", patched)
+			}
+			if !slices.Equal(unknown, tt.wantUnknown) {
+				t.Errorf("unknown = %v, want %v", unknown, tt.wantUnknown)
+			}
+		})
+	}
+}
+
+func TestConcurrentPatchesKeepEachOther(t *testing.T) {
+	appData := t.TempDir()
+	t.Setenv("LOCALAPPDATA", appData)
+	t.Setenv("PUBLIC_APPNAME", "test")
+	t.Setenv("PUBLIC_DIR_SETTINGS", "settings.json")
+	settingsMu.Lock()
+	currentSettings, settingsRevision = testSettings(), 1
+	settingsMu.Unlock()
+
+	// Each patch sets a different setting, like two clients saving at the same time
+	patches := []SettingsPatchRequest{
+		{"pauseOnEdgeProximity": json.RawMessage(`true`)},
+		{"menuOpacity": json.RawMessage(`0.5`)},
+		{"windowAssignmentStrategy": json.RawMessage(`"Alphabetical"`)},
+	}
+	var wg sync.WaitGroup
+	results := make([]SettingsPatchResult, len(patches))
+	for i, patch := range patches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = applyPatch(patch)
+		}()
+	}
+	wg.Wait()
+
+	var revisions []int
+	for i, result := range results {
+		if !result.OK {
+			t.Fatalf("patch %d failed: %s", i, result.Error)
+		}
+		revisions = append(revisions, result.Revision)
+	}
+	slices.Sort(revisions)
+	if !slices.Equal(revisions, []int{2, 3, 4}) {
+		t.Errorf("revisions = %v, want one per patch", revisions)
+	}
+
+	var saved map[string]SettingsEntry
+	if err := jsonUtils.ReadFromFile(filepath.Join(appData, "test", "settings.json"), &saved); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"pauseOnEdgeProximity": "true", "menuOpacity": "0.5", "windowAssignmentStrategy": "Alphabetical"}
+	for _, settings := range []map[string]SettingsEntry{currentSettings, saved} {
+		for name, value := range want {
+			if got := fmt.Sprint(settings[name].Value); got != value {
+				t.Errorf("%s = %s, want %s", name, got, value)
+			}
+		}
+	}
+}
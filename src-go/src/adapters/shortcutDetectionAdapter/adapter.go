@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -14,7 +13,9 @@ import (
 
 	"github.com/Rayzorblade23/MightyPie-Revamped/src/adapters/natsAdapter"
 	"github.com/Rayzorblade23/MightyPie-Revamped/src/core"
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core/appPageRules"
 	"github.com/Rayzorblade23/MightyPie-Revamped/src/core/logger"
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core/shortcutMatcher"
 	"github.com/nats-io/nats.go"
 )
 
@@ -22,28 +23,16 @@ import (
 var log = logger.New("ShortcutDetector")
 
 const (
-	vkLSHIFT          = 0xA0
-	vkRSHIFT          = 0xA1
-	vkLCONTROL        = 0xA2
-	vkRCONTROL        = 0xA3
-	vkLALT            = 0xA4
-	vkRALT            = 0xA5
+	vkEscape          = 0x1B
 	keyPressedMask    = 0x8000
 	keyAutorepeatFlag = 0x40000000
 )
 
-var mapSpecificEventModifierToGeneric = map[int]int{
-	vkLSHIFT: core.VK_SHIFT, vkRSHIFT: core.VK_SHIFT,
-	vkLCONTROL: core.VK_CONTROL, vkRCONTROL: core.VK_CONTROL,
-	vkLALT: core.VK_ALT, vkRALT: core.VK_ALT,
-}
-
 type ShortcutDetectionAdapter struct {
 	natsAdapter          *natsAdapter.NatsAdapter
-	keyboardHook         *KeyboardHook
+	matcher              *shortcutMatcher.Matcher
 	hook                 syscall.Handle
 	shortcuts            map[string]core.ShortcutEntry
-	pressedState         map[string]bool
 	updateHookChan       chan struct{}
 	manualPause          bool
 	edgePause            bool
@@ -53,8 +42,9 @@ type ShortcutDetectionAdapter struct {
 	pauseToggleKeys      string
 	pauseToggleLabel     string
 	settingsMutex        sync.RWMutex
+	appPageRules         map[string]appPageRules.Compiled // menu ID -> rules picking the page from the focused app
 	focusedApp           string
-	focusedAppMutex      sync.RWMutex
+	appMutex             sync.RWMutex // protects appPageRules and focusedApp
 }
 
 // Run blocks forever to keep the worker process alive.
@@ -66,8 +56,8 @@ func (a *ShortcutDetectionAdapter) Run() {
 func New(natsAdapter *natsAdapter.NatsAdapter) *ShortcutDetectionAdapter {
 	adapter := &ShortcutDetectionAdapter{
 		natsAdapter:          natsAdapter,
+		matcher:              shortcutMatcher.New(),
 		shortcuts:            make(map[string]core.ShortcutEntry),
-		pressedState:         make(map[string]bool),
 		updateHookChan:       make(chan struct{}, 1),
 		edgeMonitorStop:      make(chan struct{}),
 		pauseOnEdgeProximity: false, // Default to enabled
@@ -88,7 +78,8 @@ func New(natsAdapter *natsAdapter.NatsAdapter) *ShortcutDetectionAdapter {
 	backendSubject := os.Getenv("PUBLIC_NATSSUBJECT_PIEMENUCONFIG_BACKEND_UPDATE")
 	adapter.natsAdapter.SubscribeToSubject(backendSubject, func(natsMessage *nats.Msg) {
 		var payload struct {
-			Shortcuts map[string]core.ShortcutEntry `json:"shortcuts"`
+			Shortcuts    map[string]core.ShortcutEntry  `json:"shortcuts"`
+			AppPageRules map[string][]appPageRules.Rule `json:"appPageRules"`
 		}
 		if err := json.Unmarshal(natsMessage.Data, &payload); err != nil {
 			log.Error("Failed to decode backend config update: %v", err)
@@ -99,11 +90,15 @@ func New(natsAdapter *natsAdapter.NatsAdapter) *ShortcutDetectionAdapter {
 			payload.Shortcuts = make(map[string]core.ShortcutEntry)
 		}
 		adapter.shortcuts = payload.Shortcuts
-		newPressedState := make(map[string]bool)
-		for shortcutKey := range adapter.shortcuts {
-			newPressedState[shortcutKey] = false
+		// Compile the app rules here rather than on every press
+		compiledRules := make(map[string]appPageRules.Compiled, len(payload.AppPageRules))
+		for menuID, rules := range payload.AppPageRules {
+			compiledRules[menuID] = appPageRules.Compile(rules)
 		}
-		adapter.pressedState = newPressedState
+		adapter.appMutex.Lock()
+		adapter.appPageRules = compiledRules
+		adapter.appMutex.Unlock()
+		adapter.matcher.SetShortcuts(toMatcherShortcuts(payload.Shortcuts))
 		select {
 		case adapter.updateHookChan <- struct{}{}:
 		default:
@@ -141,11 +136,20 @@ func New(natsAdapter *natsAdapter.NatsAdapter) *ShortcutDetectionAdapter {
 			}
 		}
 
+		timing := triggerTimingFromSettings(settings)
+		adapter.matcher.SetTriggerTiming(timing)
+		log.Info("Updated shortcut trigger timing: tap<=%v, hold>=%v, double-tap<=%v", timing.TapMax, timing.Hold, timing.DoubleTapWindow)
+
 		// Update pauseToggleShortcut setting
 		if pauseShortcutSetting, ok := settings["pauseToggleShortcut"].(map[string]any); ok {
 			if valueMap, ok := pauseShortcutSetting["value"].(map[string]any); ok {
 				if keys, ok := valueMap["keys"].(string); ok {
 					adapter.pauseToggleKeys = keys
+					codes, valid := core.ParseRobotGoShortcut(keys)
+					if !valid && keys != "" {
+						log.Warn("Cannot parse pauseToggleShortcut '%s'; pause toggle disabled", keys)
+					}
+					adapter.matcher.SetPauseToggle(codes)
 				}
 				if label, ok := valueMap["label"].(string); ok {
 					adapter.pauseToggleLabel = label
@@ -166,9 +170,12 @@ func New(natsAdapter *natsAdapter.NatsAdapter) *ShortcutDetectionAdapter {
 				log.Error("Failed to decode focused app update: %v", err)
 				return
 			}
-			adapter.focusedAppMutex.Lock()
+			adapter.matcher.SetFocusedApp(payload.AppName)
+			adapter.appMutex.Lock()
 			adapter.focusedApp = payload.AppName
-			adapter.focusedAppMutex.Unlock()
+			adapter.appMutex.Unlock()
+			// Key-ups can be missed while e.g. the lock screen has the focus; drop keys no longer held.
+			adapter.matcher.Resync(keyIsDown)
 			log.Debug("Focused app updated: %s", payload.AppName)
 		})
 	} else {
@@ -207,25 +214,12 @@ func (adapter *ShortcutDetectionAdapter) updateKeyboardHook() {
 			core.UnhookWindowsHookEx.Call(uintptr(adapter.hook))
 		}
 		adapter.hook = 0
-		adapter.keyboardHook = nil
 	}
 	if len(adapter.shortcuts) == 0 {
 		return
 	}
-
-	adapter.keyboardHook = NewKeyboardHookForShortcuts(adapter.shortcuts, func(shortcutKeyIndex string, shortcutVKCodes []int, isPressedEvent bool) bool {
-		shortcutIndexInt := 0
-		fmt.Sscanf(shortcutKeyIndex, "%d", &shortcutIndexInt) // Assuming index is always numeric string.
-		previousState := adapter.pressedState[shortcutKeyIndex]
-		if isPressedEvent && !previousState {
-			adapter.publishMessage(shortcutIndexInt, true)
-			adapter.pressedState[shortcutKeyIndex] = true
-		} else if !isPressedEvent && previousState {
-			adapter.publishMessage(shortcutIndexInt, false)
-			adapter.pressedState[shortcutKeyIndex] = false
-		}
-		return true
-	})
+	// Key events were not observed while unhooked.
+	adapter.matcher.Reset()
 
 	hookProcCallback := syscall.NewCallback(adapter.hookProc)
 	if core.SetWindowsHookEx == nil {
@@ -255,7 +249,7 @@ func (adapter *ShortcutDetectionAdapter) updateKeyboardHook() {
 
 // hookProc is the callback for Windows keyboard events.
 func (adapter *ShortcutDetectionAdapter) hookProc(nCode int, wParam uintptr, lParam uintptr) uintptr {
-	if nCode == 0 && adapter.keyboardHook != nil && adapter.keyboardHook.shortcuts != nil {
+	if nCode == 0 {
 		keyboardHookStruct := (*core.KBDLLHOOKSTRUCT)(unsafe.Pointer(lParam))
 		eventVKCode := int(keyboardHookStruct.VKCode)
 		eventFlags := keyboardHookStruct.Flags
@@ -272,61 +266,28 @@ func (adapter *ShortcutDetectionAdapter) hookProc(nCode int, wParam uintptr, lPa
 			return 0
 		}
 
-		// Check if pause toggle shortcut is pressed (if configured)
-		adapter.settingsMutex.RLock()
-		pauseToggleKeys := adapter.pauseToggleKeys
-		adapter.settingsMutex.RUnlock()
-
-		if isKeyDownEvent && pauseToggleKeys != "" {
-			// Check if current key combination matches pause toggle shortcut
-			if adapter.matchesPauseToggle(eventVKCode) {
-				adapter.pauseMutex.Lock()
-				adapter.manualPause = !adapter.manualPause
-				pauseState := adapter.manualPause
-				adapter.pauseMutex.Unlock()
-				if pauseState {
-					log.Info("Shortcut detection MANUALLY PAUSED")
-				} else {
-					log.Info("Shortcut detection MANUALLY RESUMED")
-				}
-				// Publish pause state change to NATS
-				subject := os.Getenv("PUBLIC_NATSSUBJECT_SHORTCUTS_PAUSED")
+		// The hook only feeds events to the matcher and acts on its decisions.
+		if isKeyDownEvent {
+			paused := adapter.isPaused()
+			adapter.matcher.SetSuspended(paused)
+			decision := adapter.matcher.KeyDown(eventVKCode)
+
+			// Publish Escape key down so UI can close pie menu regardless of focus
+			if !paused && !decision.TogglePause && eventVKCode == vkEscape {
+				subject := os.Getenv("PUBLIC_NATSSUBJECT_PIEMENU_ESCAPE")
 				if subject != "" {
-					adapter.natsAdapter.PublishMessage(subject, map[string]any{"paused": adapter.isPaused()})
-					log.Debug("Published pause state (%v) to %s", adapter.isPaused(), subject)
+					adapter.natsAdapter.PublishMessage(subject, map[string]any{"pressed": true})
+					log.Debug("Published Escape keydown to %s", subject)
 				} else {
-					log.Warn("PUBLIC_NATSSUBJECT_SHORTCUTS_PAUSED not set; skipping pause state publish")
+					log.Warn("PUBLIC_NATSSUBJECT_PIEMENU_ESCAPE not set; skipping Escape publish")
 				}
-				return 1
-			}
-		}
-
-		// Skip all shortcut detection when paused (manual or edge-based)
-		if adapter.isPaused() {
-			if core.CallNextHookEx != nil {
-				r1, _, _ := core.CallNextHookEx.Call(0, uintptr(nCode), wParam, lParam)
-				return r1
 			}
-			return 0
-		}
 
-		// Publish Escape key down so UI can close pie menu regardless of focus
-		if isKeyDownEvent && eventVKCode == 0x1B { // VK_ESCAPE
-			subject := os.Getenv("PUBLIC_NATSSUBJECT_PIEMENU_ESCAPE")
-			if subject != "" {
-				adapter.natsAdapter.PublishMessage(subject, map[string]any{"pressed": true})
-				log.Debug("Published Escape keydown to %s", subject)
-			} else {
-				log.Warn("PUBLIC_NATSSUBJECT_PIEMENU_ESCAPE not set; skipping Escape publish")
-			}
-		}
-
-		if isKeyDownEvent {
-			if adapter.handleKeyDown(eventVKCode) {
+			if adapter.handleDecision(decision) {
 				return 1 // Event consumed
 			}
 		} else if isKeyUpEvent {
-			adapter.handleKeyUp(eventVKCode)
+			adapter.handleDecision(adapter.matcher.KeyUp(eventVKCode))
 		}
 	}
 	if core.CallNextHookEx != nil {
@@ -364,68 +325,25 @@ func (adapter *ShortcutDetectionAdapter) setEdgePause(paused bool) {
 	}
 }
 
-// matchesPauseToggle checks if the current key press matches the configured pause toggle shortcut
-func (adapter *ShortcutDetectionAdapter) matchesPauseToggle(vkCode int) bool {
-	adapter.settingsMutex.RLock()
-	pauseKeys := adapter.pauseToggleKeys
-	adapter.settingsMutex.RUnlock()
-
-	if pauseKeys == "" {
-		return false
-	}
-
-	// Parse the RobotGo format shortcut (e.g., "ctrl+shift+p")
-	parts := strings.Split(strings.ToLower(pauseKeys), "+")
-	if len(parts) == 0 {
-		return false
-	}
-
-	// Get current modifier states
-	ctrlState, _, _ := core.GetAsyncKeyState.Call(uintptr(core.VK_CONTROL))
-	ctrlPressed := (ctrlState & uintptr(keyPressedMask)) != 0
-	altState, _, _ := core.GetAsyncKeyState.Call(uintptr(core.VK_MENU))
-	altPressed := (altState & uintptr(keyPressedMask)) != 0
-	shiftState, _, _ := core.GetAsyncKeyState.Call(uintptr(core.VK_SHIFT))
-	shiftPressed := (shiftState & uintptr(keyPressedMask)) != 0
-	winState, _, _ := core.GetAsyncKeyState.Call(uintptr(core.VK_LWIN))
-	winPressed := (winState & uintptr(keyPressedMask)) != 0
-
-	// Check if modifiers match
-	expectedCtrl := false
-	expectedAlt := false
-	expectedShift := false
-	expectedWin := false
-	var expectedMainKey string
-
-	for _, part := range parts {
-		switch part {
-		case "ctrl":
-			expectedCtrl = true
-		case "alt":
-			expectedAlt = true
-		case "shift":
-			expectedShift = true
-		case "cmd":
-			expectedWin = true
-		default:
-			expectedMainKey = part
-		}
-	}
-
-	// Modifiers must match exactly
-	if ctrlPressed != expectedCtrl || altPressed != expectedAlt || shiftPressed != expectedShift || winPressed != expectedWin {
-		return false
+// toggleManualPause flips the manual pause after the pause toggle shortcut was pressed.
+func (adapter *ShortcutDetectionAdapter) toggleManualPause() {
+	adapter.pauseMutex.Lock()
+	adapter.manualPause = !adapter.manualPause
+	pauseState := adapter.manualPause
+	adapter.pauseMutex.Unlock()
+	if pauseState {
+		log.Info("Shortcut detection MANUALLY PAUSED")
+	} else {
+		log.Info("Shortcut detection MANUALLY RESUMED")
 	}
-
-	// Check if the main key matches
-	if expectedMainKey != "" {
-		// Convert the RobotGo key name to VK code
-		if expectedVK, ok := core.RobotGoKeyNameToVK(expectedMainKey); ok {
-			return vkCode == expectedVK
-		}
+	// Publish pause state change to NATS
+	subject := os.Getenv("PUBLIC_NATSSUBJECT_SHORTCUTS_PAUSED")
+	if subject != "" {
+		adapter.natsAdapter.PublishMessage(subject, map[string]any{"paused": adapter.isPaused()})
+		log.Debug("Published pause state (%v) to %s", adapter.isPaused(), subject)
+	} else {
+		log.Warn("PUBLIC_NATSSUBJECT_SHORTCUTS_PAUSED not set; skipping pause state publish")
 	}
-
-	return false
 }
 
 func (adapter *ShortcutDetectionAdapter) monitorScreenEdges() {
@@ -496,10 +414,20 @@ func (adapter *ShortcutDetectionAdapter) publishMessage(shortcutIndexInt int, is
 	}
 	shortcutLabel := ""
 	stringifiedIndex := fmt.Sprintf("%d", shortcutIndexInt)
+	outgoingMessage := core.ShortcutPressed_Message{ShortcutPressed: shortcutIndexInt, MouseX: xPos, MouseY: yPos, OpenSpecificPage: false, PageID: 0}
 	if shortcutDetails, found := adapter.shortcuts[stringifiedIndex]; found {
 		shortcutLabel = shortcutDetails.Label
+		outgoingMessage.Trigger = shortcutDetails.TriggerMode
+		if shortcutDetails.PageID != nil {
+			outgoingMessage.OpenSpecificPage = true
+			outgoingMessage.PageID = *shortcutDetails.PageID
+		}
+	}
+	// A page picked for the focused app is more specific than the shortcut's own page
+	if pageID, ok := adapter.appPage(stringifiedIndex); ok {
+		outgoingMessage.OpenSpecificPage = true
+		outgoingMessage.PageID = pageID
 	}
-	outgoingMessage := core.ShortcutPressed_Message{ShortcutPressed: shortcutIndexInt, MouseX: xPos, MouseY: yPos, OpenSpecificPage: false, PageID: 0}
 	actionString := "RELEASED"
 	if isPressedEvent {
 		actionString = "PRESSED"
@@ -513,3 +441,10 @@ func (adapter *ShortcutDetectionAdapter) publishMessage(shortcutIndexInt int, is
 	log.Info("Publishing %s for shortcut %d (%s) at (%d, %d)", actionString, shortcutIndexInt, shortcutLabel, xPos, yPos)
 	adapter.natsAdapter.PublishMessage(natsSubject, outgoingMessage)
 }
+
+// appPage resolves the page a menu opens on for the focused app, if one of its rules matches.
+func (adapter *ShortcutDetectionAdapter) appPage(menuID string) (int, bool) {
+	adapter.appMutex.RLock()
+	defer adapter.appMutex.RUnlock()
+	return adapter.appPageRules[menuID].Resolve(adapter.focusedApp)
+}
@@ -1,165 +1,133 @@
 package shortcutDetectionAdapter
 
 import (
-	"slices"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/Rayzorblade23/MightyPie-Revamped/src/core"
+	"github.com/Rayzorblade23/MightyPie-Revamped/src/core/shortcutMatcher"
 )
 
-// keyIsPressed checks the current state of a specific virtual key code.
-func keyIsPressed(virtualKeyCode int) bool {
-	if core.GetKeyState == nil {
-		log.Fatal("CRITICAL Error: core.GetKeyState is not initialized.")
-		return false
+// toMatcherShortcuts converts the config's shortcut entries into matcher shortcuts.
+func toMatcherShortcuts(entries map[string]core.ShortcutEntry) map[string]shortcutMatcher.Shortcut {
+	shortcuts := make(map[string]shortcutMatcher.Shortcut, len(entries))
+	for index, entry := range entries {
+		s := shortcutMatcher.Shortcut{
+			Codes:    entry.Codes,
+			Sequence: entry.Sequence,
+			Timeout:  time.Duration(entry.SequenceTimeoutMs) * time.Millisecond,
+			Trigger:  shortcutMatcher.TriggerMode(entry.TriggerMode),
+		}
+		if entry.TargetApp != nil {
+			s.TargetApp = *entry.TargetApp
+		}
+		shortcuts[index] = s
 	}
-	state, _, _ := core.GetKeyState.Call(uintptr(virtualKeyCode))
-	return (state & keyPressedMask) != 0
+	return shortcuts
 }
 
-// getActualModifierState checks if a generic modifier (SHIFT, CTRL, ALT) is active
-// by checking its left or right specific keys.
-func getActualModifierState(genericModifierVKCode int) bool {
-	switch genericModifierVKCode {
-	case core.VK_SHIFT:
-		return keyIsPressed(vkLSHIFT) || keyIsPressed(vkRSHIFT)
-	case core.VK_CONTROL:
-		return keyIsPressed(vkLCONTROL) || keyIsPressed(vkRCONTROL)
-	case core.VK_ALT:
-		return keyIsPressed(vkLALT) || keyIsPressed(vkRALT)
-	default:
-		return keyIsPressed(genericModifierVKCode)
+// keyIsDown asks the OS whether a key is physically held; only used to resync the matcher.
+func keyIsDown(virtualKeyCode int) bool {
+	if core.GetAsyncKeyState == nil {
+		return false
 	}
+	state, _, _ := core.GetAsyncKeyState.Call(uintptr(virtualKeyCode))
+	return (state & keyPressedMask) != 0
 }
 
-// checkShortcutModifiers verifies if all defined modifier keys for a shortcut are currently pressed.
-func checkShortcutModifiers(definedModifierCodes []int) bool {
-	for _, modCode := range definedModifierCodes {
-		isGenericMod := modCode == core.VK_SHIFT || modCode == core.VK_CONTROL || modCode == core.VK_ALT
-		if isGenericMod {
-			if !getActualModifierState(modCode) {
-				return false
-			}
-		} else {
-			if !keyIsPressed(modCode) {
-				return false
+// triggerTimingFromSettings reads the trigger mode thresholds (in milliseconds) from a settings update.
+// Missing or invalid values are left zero, which keeps the matcher's defaults.
+func triggerTimingFromSettings(settings map[string]any) shortcutMatcher.TriggerTiming {
+	millis := func(key string) time.Duration {
+		if entry, ok := settings[key].(map[string]any); ok {
+			if value, ok := entry["value"].(float64); ok && value > 0 {
+				return time.Duration(value) * time.Millisecond
 			}
 		}
+		return 0
+	}
+	return shortcutMatcher.TriggerTiming{
+		TapMax:          millis("shortcutTapMaxMs"),
+		Hold:            millis("shortcutHoldMs"),
+		DoubleTapWindow: millis("shortcutDoubleTapWindowMs"),
 	}
-	return true
 }
 
-// isEventKeyInShortcutCodes checks if an event's key code matches any key in a shortcut,
-// considering generic vs. specific (L/R) modifier mappings.
-func isEventKeyInShortcutCodes(eventVKCode int, shortcutDefinedCodes []int) bool {
-	if slices.Contains(shortcutDefinedCodes, eventVKCode) {
-		return true
+// handleDecision acts on what the matcher decided for a key event or timer.
+// Returns true if the event must be consumed.
+//
+// When several shortcuts match the same key press, the matcher picks the winner deterministically
+// (focused targetApp first, then more codes, then lowest menu index); the config manager
+// reports such overlaps as shortcut conflicts when a config is saved.
+func (adapter *ShortcutDetectionAdapter) handleDecision(decision shortcutMatcher.Decision) bool {
+	if decision.TogglePause {
+		adapter.toggleManualPause()
 	}
-	if genericEquivalent, isSpecificMod := mapSpecificEventModifierToGeneric[eventVKCode]; isSpecificMod {
-		if slices.Contains(shortcutDefinedCodes, genericEquivalent) {
-			return true
-		}
+	if decision.WakeAfter > 0 {
+		time.AfterFunc(decision.WakeAfter, func() {
+			adapter.handleDecision(adapter.matcher.Tick())
+		})
 	}
-	return false
-}
-
-// handleKeyDown processes key down events for shortcut detection.
-// Returns true if the event was consumed by a shortcut.
-func (adapter *ShortcutDetectionAdapter) handleKeyDown(eventVKCode int) bool {
-	// Get current focused app
-	adapter.focusedAppMutex.RLock()
-	currentFocusedApp := adapter.focusedApp
-	adapter.focusedAppMutex.RUnlock()
-
-	// Find all shortcuts that match the pressed keys
-	var matchingWithTargetApp []struct {
-		index      string
-		definition core.ShortcutEntry
+	if decision.Pending != nil {
+		adapter.publishSequencePending(*decision.Pending)
+	} else if decision.SequenceEnded {
+		adapter.publishSequenceEnded(decision.Pressed != "")
 	}
-	var matchingWithoutTargetApp []struct {
-		index      string
-		definition core.ShortcutEntry
+	if decision.Pressed != "" {
+		adapter.publishShortcutEvent(decision.Pressed, true)
 	}
-
-	for shortcutKeyIndex, shortcutDefinition := range adapter.keyboardHook.shortcuts {
-		// Skip shortcuts with no key codes defined
-		if len(shortcutDefinition.Codes) == 0 {
-			continue
-		}
-		
-		mainShortcutKey := shortcutDefinition.Codes[len(shortcutDefinition.Codes)-1]
-		modifierKeys := shortcutDefinition.Codes[:len(shortcutDefinition.Codes)-1]
-
-		if eventVKCode == mainShortcutKey && checkShortcutModifiers(modifierKeys) {
-			if shortcutDefinition.TargetApp != nil && *shortcutDefinition.TargetApp != "" {
-				matchingWithTargetApp = append(matchingWithTargetApp, struct {
-					index      string
-					definition core.ShortcutEntry
-				}{shortcutKeyIndex, shortcutDefinition})
-			} else {
-				matchingWithoutTargetApp = append(matchingWithoutTargetApp, struct {
-					index      string
-					definition core.ShortcutEntry
-				}{shortcutKeyIndex, shortcutDefinition})
-			}
-		}
+	for _, index := range decision.Released {
+		adapter.publishShortcutEvent(index, false)
 	}
+	return decision.Consume
+}
 
-	// Priority 1: Check if any targetApp matches the focused app
-	for _, match := range matchingWithTargetApp {
-		if match.definition.TargetApp != nil && *match.definition.TargetApp == currentFocusedApp {
-			log.Debug("Shortcut %s triggered for targetApp '%s' (focused)", match.index, *match.definition.TargetApp)
-			if adapter.keyboardHook.multiCallback != nil {
-				adapter.keyboardHook.multiCallback(match.index, match.definition.Codes, true)
-			}
-			return true
-		}
+func (adapter *ShortcutDetectionAdapter) publishShortcutEvent(index string, isPressedEvent bool) {
+	shortcutIndexInt, err := strconv.Atoi(index)
+	if err != nil {
+		log.Error("Shortcut index '%s' is not numeric", index)
+		return
 	}
+	adapter.publishMessage(shortcutIndexInt, isPressedEvent)
+}
 
-	// Priority 2: If no targetApp matched, use shortcuts without targetApp
-	if len(matchingWithoutTargetApp) > 0 {
-		// Only trigger if there are no targetApp shortcuts (already checked above)
-		match := matchingWithoutTargetApp[0]
-		log.Debug("Shortcut %s triggered (no targetApp restriction)", match.index)
-		if adapter.keyboardHook.multiCallback != nil {
-			adapter.keyboardHook.multiCallback(match.index, match.definition.Codes, true)
-		}
-		return true
+// publishSequencePending tells the UI which sequences can still complete and what they expect next.
+func (adapter *ShortcutDetectionAdapter) publishSequencePending(pending shortcutMatcher.PendingSequence) {
+	msg := core.ShortcutSequencePending_Message{
+		Pending:    true,
+		Step:       pending.Step,
+		TimeoutMs:  int(pending.Timeout / time.Millisecond),
+		Candidates: []core.ShortcutSequenceCandidate{},
 	}
-
-	// If we have targetApp shortcuts but none matched the focused app, let the key pass through
-	if len(matchingWithTargetApp) > 0 {
-		targetApp := ""
-		if matchingWithTargetApp[0].definition.TargetApp != nil {
-			targetApp = *matchingWithTargetApp[0].definition.TargetApp
+	for _, index := range pending.Candidates {
+		entry := adapter.shortcuts[index]
+		shortcutIndexInt, err := strconv.Atoi(index)
+		if err != nil || pending.Step >= len(entry.Sequence) {
+			continue
 		}
-		log.Debug("Shortcut matched but targetApp '%s' doesn't match focused app '%s', passing through",
-			targetApp, currentFocusedApp)
-		return false
+		msg.Candidates = append(msg.Candidates, core.ShortcutSequenceCandidate{
+			ShortcutIndex: shortcutIndexInt,
+			Label:         entry.Label,
+			NextChord:     entry.Sequence[pending.Step],
+		})
 	}
+	log.Debug("Shortcut sequence pending after chord %d (%d candidate(s))", pending.Step, len(msg.Candidates))
+	adapter.publishSequenceMessage(msg)
+}
 
-	return false
+func (adapter *ShortcutDetectionAdapter) publishSequenceEnded(completed bool) {
+	adapter.publishSequenceMessage(core.ShortcutSequencePending_Message{
+		Completed:  completed,
+		Candidates: []core.ShortcutSequenceCandidate{},
+	})
 }
 
-// handleKeyUp processes key up events for shortcut release detection.
-func (adapter *ShortcutDetectionAdapter) handleKeyUp(eventVKCode int) {
-	for shortcutKeyIndex, shortcutDefinition := range adapter.keyboardHook.shortcuts {
-		// Skip shortcuts with no key codes defined
-		if len(shortcutDefinition.Codes) == 0 {
-			continue
-		}
-		
-		if adapter.pressedState[shortcutKeyIndex] { // If this shortcut was active
-			if isEventKeyInShortcutCodes(eventVKCode, shortcutDefinition.Codes) { // And released key is part of it
-				mainShortcutKey := shortcutDefinition.Codes[len(shortcutDefinition.Codes)-1]
-				modifierKeys := shortcutDefinition.Codes[:len(shortcutDefinition.Codes)-1]
-				mainKeyStillHeld := getActualModifierState(mainShortcutKey)
-				allModifiersStillHeld := checkShortcutModifiers(modifierKeys)
-				if !(allModifiersStillHeld && mainKeyStillHeld) { // If shortcut no longer fully active
-					if adapter.keyboardHook.multiCallback != nil {
-						adapter.keyboardHook.multiCallback(shortcutKeyIndex, shortcutDefinition.Codes, false)
-					}
-				}
-			}
-		}
+func (adapter *ShortcutDetectionAdapter) publishSequenceMessage(msg core.ShortcutSequencePending_Message) {
+	subject := os.Getenv("PUBLIC_NATSSUBJECT_SHORTCUT_SEQUENCE_PENDING")
+	if subject == "" {
+		log.Warn("PUBLIC_NATSSUBJECT_SHORTCUT_SEQUENCE_PENDING not set; skipping sequence hint")
+		return
 	}
+	adapter.natsAdapter.PublishMessage(subject, msg)
 }
@@ -10,18 +10,34 @@ type ShortcutIndexMessage struct {
 
 type ShortcutMap map[string]core.ShortcutEntry
 
+// maxSequenceLength limits leader-key sequences to a length people can remember.
+const maxSequenceLength = 4
+
 // IsValidShortcut checks if a shortcut is valid (not just modifiers, not escape, etc.).
-func IsValidShortcut(shortcut []int) bool {
+// Passing several chords validates a leader-key sequence: every chord must be valid on its own.
+func IsValidShortcut(chords ...[]int) bool {
+	if len(chords) < 1 || len(chords) > maxSequenceLength {
+		return false
+	}
+	for _, shortcut := range chords {
+		if !isValidChord(shortcut) {
+			return false
+		}
+	}
+	return true
+}
+
+func isValidChord(shortcut []int) bool {
 	if len(shortcut) < 1 {
 		return false
 	}
 
 	// The Escape key is used for cancellation and is not a valid shortcut.
 	if slices.Contains(shortcut, core.KeyMap["Esc"]) {
-			return false
-		}
+		return false
+	}
 
 	// A shortcut must contain at least one non-modifier key.
 	mainKey := shortcut[len(shortcut)-1]
 	return !core.IsModifier(mainKey)
-}
\ No newline at end of file
+}
@@ -2,6 +2,7 @@ package windowManagementAdapter
 
 import (
 	"encoding/json"
+	"maps"
 	"os"
 	"reflect"
 	"time"
@@ -69,9 +70,28 @@ func New(natsAdapter *natsAdapter.NatsAdapter) (*WindowManagementAdapter, error)
 
 	})
 
+	a.handleGetRequests()
+
 	return a, nil
 }
 
+// handleGetRequests answers `get` requests with the last published window list and its revision,
+// and requests for the installed apps.
+func (a *WindowManagementAdapter) handleGetRequests() {
+	natsAdapter.HandleRequest(a.natsAdapter, os.Getenv("PUBLIC_NATSSUBJECT_WINDOWMANAGER_GET"),
+		func(struct{}) (natsAdapter.Snapshot[map[int]core.WindowInfo], error) {
+			a.publishedMutex.RLock()
+			defer a.publishedMutex.RUnlock()
+			return natsAdapter.Snapshot[map[int]core.WindowInfo]{Revision: a.publishedRevision, Data: a.publishedWindows}, nil
+		})
+	natsAdapter.HandleRequest(a.natsAdapter, os.Getenv("PUBLIC_NATSSUBJECT_WINDOWMANAGER_INSTALLEDAPPS_GET"),
+		func(struct{}) (map[string]core.AppInfo, error) {
+			installedAppsInfoMutex.RLock()
+			defer installedAppsInfoMutex.RUnlock()
+			return maps.Clone(installedAppsInfo), nil
+		})
+}
+
 // publishInstalledAppsInfo sends the current discovered apps list to the NATS subject
 func (a *WindowManagementAdapter) publishInstalledAppsInfo(apps map[string]core.AppInfo) {
 	// Use read lock when publishing the map
@@ -228,5 +248,10 @@ func (a *WindowManagementAdapter) publishWindowListUpdate(windows WindowMapping)
 		convertedMap[int(hwnd)] = info
 	}
 
+	a.publishedMutex.Lock()
+	a.publishedWindows = convertedMap
+	a.publishedRevision++
+	a.publishedMutex.Unlock()
+
 	a.natsAdapter.PublishMessage(os.Getenv("PUBLIC_NATSSUBJECT_WINDOWMANAGER_UPDATE"), convertedMap)
 }
@@ -14,6 +14,8 @@ import (
 
 type focusedApp_Message struct {
 	AppName string `json:"appName"`
+	// WindowHandle is the focused window, so subscribers can track focus order per window.
+	WindowHandle int `json:"windowHandle"`
 }
 
 // startFocusMonitoring sets up an event-based monitor for window focus changes
@@ -74,7 +76,7 @@ func (a *WindowManagementAdapter) detectFocusedApp(hwnd windows.HWND) {
 	className := GetClassName(winHwnd)
 	if slices.Contains(a.exclusionConfig.ExcludedClassNames, className) {
 		log.Debug("Excluded window class focused: %s, sending default", className)
-		a.publishFocusedApp("default")
+		a.publishFocusedApp(hwnd, "default")
 		return
 	}
 
@@ -82,14 +84,14 @@ func (a *WindowManagementAdapter) detectFocusedApp(hwnd windows.HWND) {
 	var pid uint32
 	procGetWindowThreadProcessId.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&pid)))
 	if pid == 0 {
-		a.publishFocusedApp("default")
+		a.publishFocusedApp(hwnd, "default")
 		return
 	}
 
 	// Get process executable path
 	handle, err := syscall.OpenProcess(PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
 	if err != nil {
-		a.publishFocusedApp("default")
+		a.publishFocusedApp(hwnd, "default")
 		return
 	}
 	defer syscall.CloseHandle(handle)
@@ -105,7 +107,7 @@ func (a *WindowManagementAdapter) detectFocusedApp(hwnd windows.HWND) {
 	)
 
 	if ret == 0 {
-		a.publishFocusedApp("default")
+		a.publishFocusedApp(hwnd, "default")
 		return
 	}
 
@@ -127,7 +129,7 @@ func (a *WindowManagementAdapter) detectFocusedApp(hwnd windows.HWND) {
 	defer installedAppsInfoMutex.RUnlock()
 
 	if len(installedAppsInfo) == 0 {
-		a.publishFocusedApp("default")
+		a.publishFocusedApp(hwnd, "default")
 		return
 	}
 
@@ -137,11 +139,11 @@ func (a *WindowManagementAdapter) detectFocusedApp(hwnd windows.HWND) {
 			// Check exclusions before publishing
 			if a.isAppExcluded(appName, windowTitle) {
 				log.Debug("Excluded app focused: %s, sending default", appName)
-				a.publishFocusedApp("default")
+				a.publishFocusedApp(hwnd, "default")
 				return
 			}
 			log.Debug("Focused window: %s (matched by path)", appName)
-			a.publishFocusedApp(appName)
+			a.publishFocusedApp(hwnd, appName)
 			return
 		}
 	}
@@ -152,18 +154,18 @@ func (a *WindowManagementAdapter) detectFocusedApp(hwnd windows.HWND) {
 			// Check exclusions before publishing
 			if a.isAppExcluded(appName, windowTitle) {
 				log.Debug("Excluded app focused: %s, sending default", appName)
-				a.publishFocusedApp("default")
+				a.publishFocusedApp(hwnd, "default")
 				return
 			}
 			log.Debug("Focused window: %s (matched by exe name)", appName)
-			a.publishFocusedApp(appName)
+			a.publishFocusedApp(hwnd, appName)
 			return
 		}
 	}
 
 	// Program not in discovered apps - send default
 	log.Debug("Focused window not in discovered apps: %s", exeName)
-	a.publishFocusedApp("default")
+	a.publishFocusedApp(hwnd, "default")
 }
 
 // isAppExcluded checks if an app/title combination should be excluded
@@ -189,9 +191,10 @@ func (a *WindowManagementAdapter) isAppExcluded(appName, title string) bool {
 }
 
 // publishFocusedApp publishes the focused app name via NATS
-func (a *WindowManagementAdapter) publishFocusedApp(appName string) {
+func (a *WindowManagementAdapter) publishFocusedApp(hwnd windows.HWND, appName string) {
 	msg := focusedApp_Message{
-		AppName: appName,
+		AppName:      appName,
+		WindowHandle: int(hwnd),
 	}
 	a.natsAdapter.PublishMessage(os.Getenv("PUBLIC_NATSSUBJECT_FOCUSEDAPP_UPDATE"), msg)
 }
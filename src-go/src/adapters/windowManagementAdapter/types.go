@@ -17,6 +17,11 @@ type WindowManagementAdapter struct {
 	winManager    *WindowManager
 	stopChan      chan struct{} // Adapter's overall stop
 	windowWatcher *WindowWatcher
+
+	// Last published window list, served to `get` requests
+	publishedMutex    sync.RWMutex
+	publishedWindows  map[int]core.WindowInfo
+	publishedRevision int
 }
 
 // WindowMapping maps window handles to window information
@@ -0,0 +1,97 @@
+// Package appPageRules picks the page a menu opens on from the focused app. It has no
+// platform dependencies, unlike the rest of core.
+package appPageRules
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// How an app page rule compares its pattern to the focused app name. All modes ignore case.
+const (
+	MatchExact = "exact" // the whole name, the default
+	MatchGlob  = "glob"  // * matches any run of characters, ? a single one
+	MatchRegex = "regex" // a Go regular expression, unanchored
+)
+
+var MatchModes = []string{MatchExact, MatchGlob, MatchRegex}
+
+// Rule opens a menu on PageID when it is opened while a matching app has the focus.
+type Rule struct {
+	App    string `json:"app"`
+	Match  string `json:"match,omitempty"`
+	PageID int    `json:"pageID"`
+}
+
+// Matcher compiles the rule into a function reporting whether an app name matches it.
+func (r Rule) Matcher() (func(app string) bool, error) {
+	if r.App == "" {
+		return nil, fmt.Errorf("no app set")
+	}
+	var expr string
+	switch r.Match {
+	case "", MatchExact:
+		return func(app string) bool { return strings.EqualFold(app, r.App) }, nil
+	case MatchGlob:
+		expr = "^" + globToRegexp(r.App) + "$"
+	case MatchRegex:
+		expr = r.App
+	default:
+		return nil, fmt.Errorf("unknown match mode '%s', expected one of %s", r.Match, strings.Join(MatchModes, ", "))
+	}
+
+	re, err := regexp.Compile("(?i)" + expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern '%s': %w", r.App, err)
+	}
+	return re.MatchString, nil
+}
+
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return b.String()
+}
+
+// Compiled holds a menu's rules with their matchers built, in rule order.
+type Compiled []compiledRule
+
+type compiledRule struct {
+	matches func(app string) bool
+	pageID  int
+}
+
+// Compile builds the matchers of rules, so resolving a page doesn't compile anything. Rules that
+// don't compile are left out; the config linter reports them.
+func Compile(rules []Rule) Compiled {
+	compiled := make(Compiled, 0, len(rules))
+	for _, rule := range rules {
+		if matches, err := rule.Matcher(); err == nil {
+			compiled = append(compiled, compiledRule{matches: matches, pageID: rule.PageID})
+		}
+	}
+	return compiled
+}
+
+// Resolve returns the page of the first rule matching app.
+func (c Compiled) Resolve(app string) (int, bool) {
+	if app == "" {
+		return 0, false
+	}
+	for _, rule := range c {
+		if rule.matches(app) {
+			return rule.pageID, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,123 @@
+package appPageRules
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		glob string
+		want string
+	}{
+		{"notepad", "notepad"},
+		{"*", ".*"},
+		{"note?ad*", "note.ad.*"},
+		{"notepad++.exe", `notepad\+\+\.exe`},
+		{"C:\\Apps\\[x]", `C:\\Apps\\\[x\]`},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.glob, func(t *testing.T) {
+			if got := globToRegexp(tt.glob); got != tt.want {
+				t.Errorf("globToRegexp(%q) = %q, want %q", tt.glob, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		matches []string
+		misses  []string
+	}{
+		{"exact is the default", Rule{App: "Notepad"}, []string{"Notepad", "NOTEPAD"}, []string{"Notepad++", "notepad ", ""}},
+		{"exact", Rule{App: "Code", Match: MatchExact}, []string{"code"}, []string{"Visual Studio Code"}},
+		{"glob star", Rule{App: "Microsoft *", Match: MatchGlob}, []string{"Microsoft Word", "microsoft "}, []string{"Word", "The Microsoft Store"}},
+		{"glob question mark", Rule{App: "App ?", Match: MatchGlob}, []string{"App 1", "app x"}, []string{"App 10", "App "}},
+		{"glob is literal otherwise", Rule{App: "Notepad++", Match: MatchGlob}, []string{"notepad++"}, []string{"Notepadd"}},
+		{"regex is unanchored", Rule{App: "word|excel", Match: MatchRegex}, []string{"Microsoft Word", "EXCEL"}, []string{"PowerPoint"}},
+		{"regex anchors", Rule{App: "^code$", Match: MatchRegex}, []string{"Code"}, []string{"Visual Studio Code"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := tt.rule.Matcher()
+			if err != nil {
+				t.Fatalf("Matcher failed: %v", err)
+			}
+			for _, app := range tt.matches {
+				if !matches(app) {
+					t.Errorf("%+v does not match %q", tt.rule, app)
+				}
+			}
+			for _, app := range tt.misses {
+				if matches(app) {
+					t.Errorf("%+v matches %q", tt.rule, app)
+				}
+			}
+		})
+	}
+}
+
+func TestMatcherErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr string
+	}{
+		{"no app", Rule{Match: MatchGlob}, "no app set"},
+		{"unknown mode", Rule{App: "x", Match: "fuzzy"}, "unknown match mode 'fuzzy', expected one of exact, glob, regex"},
+		{"invalid regex", Rule{App: "(", Match: MatchRegex}, "invalid pattern '('"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.rule.Matcher()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Matcher error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	rules := Compile([]Rule{
+		{App: "(", Match: MatchRegex, PageID: 9}, // broken, left out
+		{App: "Notepad", PageID: 1},
+		{App: "Note*", Match: MatchGlob, PageID: 2},
+		{App: "code", Match: MatchRegex, PageID: 3},
+	})
+	tests := []struct {
+		name     string
+		app      string
+		wantPage int
+		wantOK   bool
+	}{
+		{"first match wins", "notepad", 1, true},
+		{"later rule", "Notes", 2, true},
+		{"regex", "Visual Studio Code", 3, true},
+		{"no match", "Explorer", 0, false},
+		{"no focused app", "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, ok := rules.Resolve(tt.app)
+			if page != tt.wantPage || ok != tt.wantOK {
+				t.Errorf("Resolve(%q) = %d, %v; want %d, %v", tt.app, page, ok, tt.wantPage, tt.wantOK)
+			}
+		})
+	}
+
+	t.Run("no rules", func(t *testing.T) {
+		var none Compiled
+		if page, ok := none.Resolve("Notepad"); ok {
+			t.Errorf("Resolve without rules = %d, true; want no page", page)
+		}
+	})
+	t.Run("broken rules are left out", func(t *testing.T) {
+		if len(rules) != 3 {
+			t.Errorf("compiled %d rules, want 3", len(rules))
+		}
+	})
+}
@@ -0,0 +1,303 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// ButtonTypeSpec declares a button type for RegisterButtonType. P is its properties struct.
+type ButtonTypeSpec[P any] struct {
+	Type ButtonType
+	// Defaults returns the properties of a new button of this type. The zero P if nil.
+	Defaults func() P
+	// Validate checks decoded properties beyond their JSON shape. Optional.
+	Validate func(P) error
+	// WindowAssignment marks types whose buttons the button manager fills with open windows.
+	WindowAssignment bool
+}
+
+// ButtonTypeInfo is a registered button type.
+type ButtonTypeInfo struct {
+	Type             ButtonType
+	WindowAssignment bool
+
+	propsType reflect.Type
+	decode    func(json.RawMessage) (any, error)
+	defaults  func() any
+}
+
+var (
+	buttonTypes     = make(map[ButtonType]*ButtonTypeInfo)
+	buttonTypeOrder []ButtonType
+)
+
+// RegisterButtonType adds a button type to the registry every worker validates against.
+// It must be called during package initialization and panics on a duplicate type.
+func RegisterButtonType[P any](spec ButtonTypeSpec[P]) {
+	if _, exists := buttonTypes[spec.Type]; exists {
+		panic(fmt.Sprintf("button type %q registered twice", spec.Type))
+	}
+	propsType := reflect.TypeFor[P]()
+	if propsType.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("button type %q: properties must be a struct, got %s", spec.Type, propsType))
+	}
+
+	info := &ButtonTypeInfo{
+		Type:             spec.Type,
+		WindowAssignment: spec.WindowAssignment,
+		propsType:        propsType,
+		decode: func(raw json.RawMessage) (any, error) {
+			var props P
+			// A type without properties accepts a button that has none
+			if len(raw) == 0 && propsType.NumField() == 0 {
+				return props, nil
+			}
+			if err := json.Unmarshal(raw, &props); err != nil {
+				return nil, err
+			}
+			if carrier, ok := any(props).(clickActionCarrier); ok {
+				if err := validateClickActions(carrier.clickActionMap()); err != nil {
+					return nil, err
+				}
+			}
+			if spec.Validate != nil {
+				if err := spec.Validate(props); err != nil {
+					return nil, err
+				}
+			}
+			return props, nil
+		},
+		defaults: func() any {
+			if spec.Defaults == nil {
+				var props P
+				return props
+			}
+			return spec.Defaults()
+		},
+	}
+	buttonTypes[spec.Type] = info
+	buttonTypeOrder = append(buttonTypeOrder, spec.Type)
+}
+
+// LookupButtonType returns the registered button type, if any.
+func LookupButtonType(buttonType ButtonType) (*ButtonTypeInfo, bool) {
+	info, ok := buttonTypes[buttonType]
+	return info, ok
+}
+
+// ButtonTypes returns all registered button types in registration order.
+func ButtonTypes() []*ButtonTypeInfo {
+	infos := make([]*ButtonTypeInfo, 0, len(buttonTypeOrder))
+	for _, buttonType := range buttonTypeOrder {
+		infos = append(infos, buttonTypes[buttonType])
+	}
+	return infos
+}
+
+// ValidateButton checks that buttonType is registered and that properties are valid for it.
+func ValidateButton(buttonType string, properties json.RawMessage) error {
+	info, ok := LookupButtonType(ButtonType(buttonType))
+	if !ok {
+		return fmt.Errorf("unknown button type '%s'", buttonType)
+	}
+	_, err := info.DecodeProperties(properties)
+	return err
+}
+
+// DecodeProperties unmarshals and validates properties. The result holds the type's properties struct.
+func (info *ButtonTypeInfo) DecodeProperties(properties json.RawMessage) (any, error) {
+	props, err := info.decode(properties)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s properties: %w", info.Type, err)
+	}
+	return props, nil
+}
+
+// DefaultProperties returns the marshaled properties of a new button of this type.
+func (info *ButtonTypeInfo) DefaultProperties() json.RawMessage {
+	data, err := json.Marshal(info.defaults())
+	if err != nil {
+		// Registered properties are plain structs, so this is a programming error
+		panic(fmt.Sprintf("button type %q: cannot marshal default properties: %v", info.Type, err))
+	}
+	return data
+}
+
+// Schema returns a JSON Schema describing the type's properties, with the defaults filled in.
+func (info *ButtonTypeInfo) Schema() map[string]any {
+	schema := jsonSchemaFor(info.propsType)
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = string(info.Type)
+
+	var defaults map[string]any
+	if err := json.Unmarshal(info.DefaultProperties(), &defaults); err == nil {
+		if properties, ok := schema["properties"].(map[string]any); ok {
+			for name, value := range defaults {
+				if property, ok := properties[name].(map[string]any); ok {
+					property["default"] = value
+				}
+			}
+		}
+	}
+	return schema
+}
+
+// jsonSchemaFor describes how encoding/json marshals t.
+func jsonSchemaFor(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == reflect.TypeFor[json.RawMessage]():
+		// Any JSON value
+		return map[string]any{}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		// encoding/json writes byte slices as base64
+		return map[string]any{"type": "string", "contentEncoding": "base64"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": jsonSchemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": jsonSchemaFor(t.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]any)
+		required := []string{}
+		for _, field := range structFields(t) {
+			name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = jsonSchemaFor(field.Type)
+			if !strings.Contains(options, "omitempty") && !strings.Contains(options, "omitzero") {
+				required = append(required, name)
+			}
+		}
+		return map[string]any{"type": "object", "properties": properties, "required": required}
+	default:
+		// interfaces and anything else encoding/json accepts as arbitrary JSON
+		return map[string]any{}
+	}
+}
+
+// structFields returns the exported fields encoding/json marshals, flattening embedded structs.
+func structFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() || field.Tag.Get("json") == "-" {
+			continue
+		}
+		if field.Anonymous && field.Tag.Get("json") == "" && field.Type.Kind() == reflect.Struct {
+			fields = append(fields, structFields(field.Type)...)
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// DisabledProperties is the empty properties struct of disabled buttons.
+type DisabledProperties struct{}
+
+// validateRunCommand rejects properties the executor could never run. An empty executable is
+// allowed, since new buttons start out without one.
+func validateRunCommand(props RunCommandProperties) error {
+	if props.TimeoutMs < 0 {
+		return fmt.Errorf("timeout_ms must not be negative")
+	}
+	for name := range props.Env {
+		if name == "" || strings.Contains(name, "=") {
+			return fmt.Errorf("invalid environment variable name %q", name)
+		}
+	}
+	return nil
+}
+
+func validateMacro(props MacroProperties) error {
+	for i, step := range props.Steps {
+		switch {
+		case step.Type == MacroStepDelay:
+			if step.DelayMs < 0 {
+				return fmt.Errorf("step %d: delay_ms must not be negative", i+1)
+			}
+		case slices.Contains(MacroStepTypes, ButtonType(step.Type)):
+			if err := ValidateButton(step.Type, step.Properties); err != nil {
+				return fmt.Errorf("step %d: %w", i+1, err)
+			}
+		default:
+			return fmt.Errorf("step %d: '%s' can't be used in a macro", i+1, step.Type)
+		}
+
+		switch step.OnError {
+		case "", MacroOnErrorStop, MacroOnErrorContinue:
+		default:
+			return fmt.Errorf("step %d: unknown on_error '%s'", i+1, step.OnError)
+		}
+	}
+	return nil
+}
+
+// noWindow is the window handle of a window button that shows no window.
+const noWindow = -1
+
+func init() {
+	RegisterButtonType(ButtonTypeSpec[ShowProgramWindowProperties]{
+		Type:             ButtonTypeShowProgramWindow,
+		WindowAssignment: true,
+		Defaults: func() ShowProgramWindowProperties {
+			return ShowProgramWindowProperties{WindowHandle: noWindow}
+		},
+	})
+	RegisterButtonType(ButtonTypeSpec[ShowAnyWindowProperties]{
+		Type:             ButtonTypeShowAnyWindow,
+		WindowAssignment: true,
+		Defaults: func() ShowAnyWindowProperties {
+			return ShowAnyWindowProperties{WindowHandle: noWindow}
+		},
+	})
+	RegisterButtonType(ButtonTypeSpec[CallFunctionProperties]{Type: ButtonTypeCallFunction})
+	RegisterButtonType(ButtonTypeSpec[LaunchProgramProperties]{Type: ButtonTypeLaunchProgram})
+	RegisterButtonType(ButtonTypeSpec[OpenSpecificPieMenuPage]{Type: ButtonTypeOpenPageInMenu})
+	RegisterButtonType(ButtonTypeSpec[OpenResourceProperties]{Type: ButtonTypeOpenResource})
+	RegisterButtonType(ButtonTypeSpec[KeyboardShortcut]{Type: ButtonTypeKeyboardShortcut})
+	RegisterButtonType(ButtonTypeSpec[RunCommandProperties]{
+		Type: ButtonTypeRunCommand,
+		Defaults: func() RunCommandProperties {
+			return RunCommandProperties{Args: []string{}, Env: map[string]string{}, TimeoutMs: 30000}
+		},
+		Validate: validateRunCommand,
+	})
+	RegisterButtonType(ButtonTypeSpec[TypeTextProperties]{
+		Type: ButtonTypeTypeText,
+		Defaults: func() TypeTextProperties {
+			return TypeTextProperties{Delivery: TypeTextDeliveryPaste}
+		},
+		Validate: func(props TypeTextProperties) error {
+			if props.Delivery != TypeTextDeliveryType && props.Delivery != TypeTextDeliveryPaste {
+				return fmt.Errorf("unknown delivery '%s'", props.Delivery)
+			}
+			return nil
+		},
+	})
+	RegisterButtonType(ButtonTypeSpec[MacroProperties]{
+		Type: ButtonTypeMacro,
+		Defaults: func() MacroProperties {
+			return MacroProperties{Steps: []MacroStep{}}
+		},
+		Validate: validateMacro,
+	})
+	RegisterButtonType(ButtonTypeSpec[DisabledProperties]{Type: ButtonTypeDisabled})
+}
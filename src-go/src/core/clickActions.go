@@ -0,0 +1,125 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Click types sent by the pie menu when a button is released.
+const (
+	ClickTypeLeftUp   = "left_up"
+	ClickTypeRightUp  = "right_up"
+	ClickTypeMiddleUp = "middle_up"
+)
+
+// Modifiers that can be held during a click, in the order they appear in a click key.
+const (
+	ModifierCtrl  = "ctrl"
+	ModifierAlt   = "alt"
+	ModifierShift = "shift"
+	ModifierWin   = "win"
+)
+
+var (
+	clickTypes = []string{ClickTypeLeftUp, ClickTypeRightUp, ClickTypeMiddleUp}
+	modifiers  = []string{ModifierCtrl, ModifierAlt, ModifierShift, ModifierWin}
+)
+
+// ClickActionTypes are the button types a click action can run.
+var ClickActionTypes = append(slices.Clone(MacroStepTypes), ButtonTypeMacro)
+
+// ClickAction is what a button does for one kind of click instead of its default behavior.
+type ClickAction struct {
+	Type       string          `json:"type"`
+	Properties json.RawMessage `json:"properties,omitempty"`
+}
+
+// ButtonActions is embedded in the properties of every button type that can override clicks.
+type ButtonActions struct {
+	// ClickActions maps click keys like "right_up" or "shift+left_up" to their action.
+	ClickActions map[string]ClickAction `json:"click_actions,omitempty"`
+}
+
+func (b ButtonActions) clickActionMap() map[string]ClickAction {
+	return b.ClickActions
+}
+
+// clickActionCarrier is implemented by properties that embed ButtonActions.
+type clickActionCarrier interface {
+	clickActionMap() map[string]ClickAction
+}
+
+// ClickKey builds the canonical click key for a click type and the modifiers held,
+// e.g. "ctrl+shift+left_up". Unknown modifiers are ignored.
+func ClickKey(clickType string, held []string) string {
+	var parts []string
+	for _, modifier := range modifiers {
+		if slices.Contains(held, modifier) {
+			parts = append(parts, modifier)
+		}
+	}
+	return strings.Join(append(parts, clickType), "+")
+}
+
+// NormalizeClickKey validates a click key and returns it in canonical modifier order.
+func NormalizeClickKey(key string) (string, error) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(key)), "+")
+	clickType := parts[len(parts)-1]
+	if !slices.Contains(clickTypes, clickType) {
+		return "", fmt.Errorf("click key '%s' must end in one of %s", key, strings.Join(clickTypes, ", "))
+	}
+	held := parts[:len(parts)-1]
+	for i, modifier := range held {
+		if !slices.Contains(modifiers, modifier) {
+			return "", fmt.Errorf("click key '%s': unknown modifier '%s'", key, modifier)
+		}
+		if slices.Contains(held[:i], modifier) {
+			return "", fmt.Errorf("click key '%s': modifier '%s' appears twice", key, modifier)
+		}
+	}
+	return ClickKey(clickType, held), nil
+}
+
+// LookupClickAction finds the action configured for a click. An action for the exact
+// combination wins over one for the plain click type.
+func LookupClickAction(actions map[string]ClickAction, clickType string, held []string) (ClickAction, string, bool) {
+	if len(actions) == 0 {
+		return ClickAction{}, "", false
+	}
+	byKey := make(map[string]ClickAction, len(actions))
+	for key, action := range actions {
+		if normalized, err := NormalizeClickKey(key); err == nil {
+			byKey[normalized] = action
+		}
+	}
+	for _, key := range []string{ClickKey(clickType, held), clickType} {
+		if action, ok := byKey[key]; ok {
+			return action, key, true
+		}
+	}
+	return ClickAction{}, "", false
+}
+
+func validateClickActions(actions map[string]ClickAction) error {
+	seen := make(map[string]string, len(actions))
+	for key, action := range actions {
+		normalized, err := NormalizeClickKey(key)
+		if err != nil {
+			return err
+		}
+		if other, ok := seen[normalized]; ok {
+			return fmt.Errorf("click keys '%s' and '%s' are the same click", other, key)
+		}
+		seen[normalized] = key
+
+		if !slices.Contains(ClickActionTypes, ButtonType(action.Type)) {
+			return fmt.Errorf("click action '%s': '%s' can't be used as a click action", key, action.Type)
+		}
+		if err := ValidateButton(action.Type, action.Properties); err != nil {
+			return fmt.Errorf("click action '%s': %w", key, err)
+		}
+	}
+	return nil
+}
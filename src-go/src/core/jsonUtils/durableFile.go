@@ -0,0 +1,226 @@
+package jsonUtils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// ChecksumKey is the top-level key holding the embedded content checksum.
+	ChecksumKey = "_checksum"
+	// checksumPrefix identifies the hash algorithm of the embedded checksum.
+	checksumPrefix = "sha256:"
+	// BackupSuffix is appended to a file path to name the last known good copy.
+	BackupSuffix = ".bak"
+	// CorruptSuffix is appended to a file path when a damaged file is moved aside during recovery.
+	CorruptSuffix = ".corrupt"
+)
+
+var (
+	// ErrChecksumMismatch is returned when the embedded checksum does not match the file content.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrEmptyFile is returned by verified reads when the file exists but has no content.
+	ErrEmptyFile = errors.New("file is empty")
+	// ErrNoUsableBackup is returned by recovering reads when the file is damaged and has no good copy.
+	// The damaged file has been moved aside, so callers can start over from their defaults.
+	ErrNoUsableBackup = errors.New("no usable backup")
+)
+
+// Recovery describes a read that fell back to the last good copy of a file.
+type Recovery struct {
+	Path       string `json:"path"`
+	BackupPath string `json:"backupPath"`
+	Reason     string `json:"reason"`
+	// FromDefaults is set by callers that rebuilt the content from defaults instead of a backup.
+	FromDefaults bool `json:"fromDefaults,omitempty"`
+}
+
+// WriteFileAtomic replaces filePath with data without ever leaving a partially written file behind.
+// Data goes to a temp file in the same directory, is fsynced and then renamed over the target.
+// If the existing file verifies, it is kept as filePath + BackupSuffix before being replaced.
+func WriteFileAtomic(filePath string, data []byte) error {
+	dir := filepath.Dir(filePath)
+	if err := os.MkdirAll(dir, defaultDirPermissions); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	if previous, err := os.ReadFile(filePath); err == nil {
+		if _, err := VerifyChecksum(previous); err == nil {
+			if err := writeAndRename(filePath+BackupSuffix, previous); err != nil {
+				return fmt.Errorf("failed to keep backup of %s: %w", filePath, err)
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read existing %s: %w", filePath, err)
+	}
+
+	return writeAndRename(filePath, data)
+}
+
+// writeAndRename writes data to a synced temp file next to filePath and renames it into place.
+func writeAndRename(filePath string, data []byte) error {
+	dir := filepath.Dir(filePath)
+	tmp, err := os.CreateTemp(dir, filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file in %s: %w", dir, err)
+	}
+	tmpPath := tmp.Name()
+	committed := false
+	defer func() {
+		if !committed {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		return fmt.Errorf("failed to write temp file %s: %w", tmpPath, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync temp file %s: %w", tmpPath, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file %s: %w", tmpPath, err)
+	}
+	if err := os.Chmod(tmpPath, defaultFilePermissions); err != nil {
+		return fmt.Errorf("failed to set permissions on %s: %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		return fmt.Errorf("failed to move %s into place: %w", filePath, err)
+	}
+	committed = true
+	syncDir(dir)
+	return nil
+}
+
+// syncDir flushes the directory entry after a rename. Not supported on every platform, so errors are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	d.Close()
+}
+
+// MarshalWithChecksum marshals v to indented JSON and, for JSON objects, embeds a checksum
+// of the content under ChecksumKey. Other JSON values are returned without a checksum.
+func MarshalWithChecksum(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil || doc == nil {
+		return json.MarshalIndent(v, jsonPrefix, jsonIndent)
+	}
+	delete(doc, ChecksumKey)
+
+	sum, err := contentChecksum(doc)
+	if err != nil {
+		return nil, err
+	}
+	doc[ChecksumKey], err = json.Marshal(sum)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(doc, jsonPrefix, jsonIndent)
+}
+
+// VerifyChecksum checks the embedded checksum of data and returns the JSON with the checksum removed.
+// Data without an embedded checksum (e.g. hand-written defaults) is returned unchanged.
+func VerifyChecksum(data []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, ErrEmptyFile
+	}
+	if !json.Valid(data) {
+		return nil, errors.New("invalid JSON")
+	}
+
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil || doc == nil {
+		return data, nil
+	}
+	rawSum, ok := doc[ChecksumKey]
+	if !ok {
+		return data, nil
+	}
+	delete(doc, ChecksumKey)
+
+	var stored string
+	if err := json.Unmarshal(rawSum, &stored); err != nil || !strings.HasPrefix(stored, checksumPrefix) {
+		return nil, fmt.Errorf("%w: malformed checksum %s", ErrChecksumMismatch, string(rawSum))
+	}
+	actual, err := contentChecksum(doc)
+	if err != nil {
+		return nil, err
+	}
+	if stored != actual {
+		return nil, fmt.Errorf("%w: stored %s, computed %s", ErrChecksumMismatch, stored, actual)
+	}
+	return json.Marshal(doc)
+}
+
+// contentChecksum hashes the compact, key-sorted encoding of doc.
+func contentChecksum(doc map[string]json.RawMessage) (string, error) {
+	canonical, err := json.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return checksumPrefix + hex.EncodeToString(sum[:]), nil
+}
+
+// ReadVerifiedFile reads filePath and returns its verified content without the embedded checksum.
+func ReadVerifiedFile(filePath string) ([]byte, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return VerifyChecksum(data)
+}
+
+// ReadVerifiedFileWithRecovery behaves like ReadVerifiedFile, but when filePath exists and is damaged
+// (empty, invalid JSON or checksum mismatch) it moves the damaged file aside (CorruptSuffix) and
+// falls back to the BackupSuffix copy, restoring it in place. A non-nil Recovery is returned
+// alongside the recovered content. Without a usable backup the error wraps ErrNoUsableBackup.
+func ReadVerifiedFileWithRecovery(filePath string) ([]byte, *Recovery, error) {
+	raw, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, primaryErr := VerifyChecksum(raw)
+	if primaryErr == nil {
+		return data, nil, nil
+	}
+	if err := os.Rename(filePath, filePath+CorruptSuffix); err != nil {
+		return nil, nil, fmt.Errorf("%s is damaged (%v) and could not be moved aside: %w", filePath, primaryErr, err)
+	}
+
+	backupPath := filePath + BackupSuffix
+	rawBackup, err := os.ReadFile(backupPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s is damaged (%v) and %w is available: %w", filePath, primaryErr, ErrNoUsableBackup, err)
+	}
+	recovered, err := VerifyChecksum(rawBackup)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s is damaged (%v) and %w is available: %w", filePath, primaryErr, ErrNoUsableBackup, err)
+	}
+	if err := writeAndRename(filePath, rawBackup); err != nil {
+		return nil, nil, fmt.Errorf("failed to restore %s from backup: %w", filePath, err)
+	}
+
+	return recovered, &Recovery{
+		Path:       filePath,
+		BackupPath: backupPath,
+		Reason:     primaryErr.Error(),
+	}, nil
+}
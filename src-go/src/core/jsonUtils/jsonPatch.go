@@ -0,0 +1,293 @@
+package jsonUtils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// PatchOperation is a single RFC 6902 JSON Patch operation.
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// ErrPatchTestFailed is returned when a "test" operation does not match the document.
+var ErrPatchTestFailed = errors.New("patch test operation failed")
+
+// DecodePatch parses an RFC 6902 patch document (a JSON array of operations).
+func DecodePatch(data []byte) ([]PatchOperation, error) {
+	var ops []PatchOperation
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return nil, fmt.Errorf("invalid JSON patch: %w", err)
+	}
+	return ops, nil
+}
+
+// ApplyPatch applies ops to the JSON document doc and returns the patched document.
+// Operations are applied in order to a decoded copy; if any operation fails, doc is
+// left untouched and an error naming the failing operation is returned.
+func ApplyPatch(doc []byte, ops []PatchOperation) ([]byte, error) {
+	var root any
+	if err := json.Unmarshal(doc, &root); err != nil {
+		return nil, fmt.Errorf("invalid JSON document: %w", err)
+	}
+
+	for i, op := range ops {
+		var err error
+		if root, err = applyOperation(root, op); err != nil {
+			return nil, fmt.Errorf("patch operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return json.Marshal(root)
+}
+
+func applyOperation(root any, op PatchOperation) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add":
+		value, err := decodeOperationValue(op)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(root, path, value)
+
+	case "remove":
+		root, _, err = pointerRemove(root, path)
+		return root, err
+
+	case "replace":
+		value, err := decodeOperationValue(op)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := pointerGet(root, path); err != nil {
+			return nil, err
+		}
+		return pointerSet(root, path, value)
+
+	case "move":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from: %w", err)
+		}
+		if op.From == op.Path {
+			return root, nil
+		}
+		if strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, errors.New("cannot move a value into one of its own children")
+		}
+		root, value, err := pointerRemove(root, from)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(root, path, value)
+
+	case "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from: %w", err)
+		}
+		value, err := pointerGet(root, from)
+		if err != nil {
+			return nil, err
+		}
+		value, err = deepCopyJSON(value)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(root, path, value)
+
+	case "test":
+		expected, err := decodeOperationValue(op)
+		if err != nil {
+			return nil, err
+		}
+		actual, err := pointerGet(root, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			return nil, ErrPatchTestFailed
+		}
+		return root, nil
+
+	default:
+		return nil, fmt.Errorf("unknown op %q", op.Op)
+	}
+}
+
+func decodeOperationValue(op PatchOperation) (any, error) {
+	if op.Value == nil {
+		return nil, errors.New("missing value")
+	}
+	var value any
+	if err := json.Unmarshal(op.Value, &value); err != nil {
+		return nil, fmt.Errorf("invalid value: %w", err)
+	}
+	return value, nil
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into unescaped reference tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// arrayIndex parses an array index token; "-" is only allowed when allowEnd is set and maps to length.
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if token == "-" && allowEnd {
+		return length, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx < 0 {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	limit := length
+	if allowEnd {
+		limit++
+	}
+	if idx >= limit {
+		return 0, fmt.Errorf("array index %d out of range", idx)
+	}
+	return idx, nil
+}
+
+func pointerGet(root any, path []string) (any, error) {
+	current := root
+	for _, token := range path {
+		switch node := current.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path segment %q not found", token)
+			}
+			current = value
+		case []any:
+			idx, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			current = node[idx]
+		default:
+			return nil, fmt.Errorf("path segment %q does not address a container", token)
+		}
+	}
+	return current, nil
+}
+
+// pointerSet replaces the existing value at path (or the root for an empty path).
+func pointerSet(root any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := pointerGet(root, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]any:
+		node[last] = value
+	case []any:
+		idx, err := arrayIndex(last, len(node), false)
+		if err != nil {
+			return nil, err
+		}
+		node[idx] = value
+	default:
+		return nil, fmt.Errorf("path segment %q does not address a container", last)
+	}
+	return root, nil
+}
+
+func pointerAdd(root any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parentPath, last := path[:len(path)-1], path[len(path)-1]
+	parent, err := pointerGet(root, parentPath)
+	if err != nil {
+		return nil, err
+	}
+	switch node := parent.(type) {
+	case map[string]any:
+		node[last] = value
+		return root, nil
+	case []any:
+		idx, err := arrayIndex(last, len(node), true)
+		if err != nil {
+			return nil, err
+		}
+		grown := make([]any, 0, len(node)+1)
+		grown = append(grown, node[:idx]...)
+		grown = append(grown, value)
+		grown = append(grown, node[idx:]...)
+		return pointerSet(root, parentPath, grown)
+	default:
+		return nil, fmt.Errorf("path segment %q does not address a container", last)
+	}
+}
+
+// pointerRemove deletes the value at path and returns the new root together with the removed value.
+func pointerRemove(root any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, nil, errors.New("cannot remove the document root")
+	}
+	parentPath, last := path[:len(path)-1], path[len(path)-1]
+	parent, err := pointerGet(root, parentPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch node := parent.(type) {
+	case map[string]any:
+		value, ok := node[last]
+		if !ok {
+			return nil, nil, fmt.Errorf("path segment %q not found", last)
+		}
+		delete(node, last)
+		return root, value, nil
+	case []any:
+		idx, err := arrayIndex(last, len(node), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		value := node[idx]
+		shrunk := make([]any, 0, len(node)-1)
+		shrunk = append(shrunk, node[:idx]...)
+		shrunk = append(shrunk, node[idx+1:]...)
+		root, err = pointerSet(root, parentPath, shrunk)
+		return root, value, err
+	default:
+		return nil, nil, fmt.Errorf("path segment %q does not address a container", last)
+	}
+}
+
+func deepCopyJSON(v any) (any, error) {
+	var out any
+	if err := Copy(v, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
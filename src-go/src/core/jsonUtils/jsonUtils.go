@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
 )
 
 const (
@@ -18,7 +17,7 @@ const (
 	jsonPrefix = ""
 )
 
-// ReadFromFile reads a JSON file into a given interface.
+// ReadFromFile reads a JSON file into a given interface, verifying its embedded checksum if present.
 // It returns nil if the file does not exist or is empty, allowing the caller to handle initialization.
 func ReadFromFile(filePath string, v any) error {
 	data, err := os.ReadFile(filePath)
@@ -33,22 +32,39 @@ func ReadFromFile(filePath string, v any) error {
 		return nil // File is empty, treat as uninitialized.
 	}
 
-	return json.Unmarshal(data, v)
+	payload, err := VerifyChecksum(data)
+	if err != nil {
+		return fmt.Errorf("failed to verify %s: %w", filePath, err)
+	}
+	return json.Unmarshal(payload, v)
 }
 
-// WriteToFile marshals an interface to an indented JSON string and writes it to a file.
-// It automatically creates the destination directory if it does not exist.
-func WriteToFile(filePath string, v any) error {
-	dir := filepath.Dir(filePath)
-	if err := os.MkdirAll(dir, defaultDirPermissions); err != nil {
-		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+// ReadFromFileWithRecovery reads a JSON file like ReadFromFile, but falls back to the last good
+// backup copy if the file is damaged. A non-nil Recovery reports that the backup was used.
+// A missing file returns nil without touching v.
+func ReadFromFileWithRecovery(filePath string, v any) (*Recovery, error) {
+	payload, recovery, err := ReadVerifiedFileWithRecovery(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(payload, v); err != nil {
+		return recovery, err
 	}
+	return recovery, nil
+}
 
-	data, err := json.MarshalIndent(v, jsonPrefix, jsonIndent)
+// WriteToFile marshals an interface to an indented JSON string with an embedded checksum
+// and writes it atomically, keeping the previous good version as a backup.
+// It automatically creates the destination directory if it does not exist.
+func WriteToFile(filePath string, v any) error {
+	data, err := MarshalWithChecksum(v)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(filePath, data, defaultFilePermissions)
+	return WriteFileAtomic(filePath, data)
 }
 
 // CopyFile reads a JSON file from srcPath and writes its contents to dstPath.
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"strings"
 	"syscall"
 	"unsafe"
 )
@@ -121,6 +122,16 @@ type ShortcutEntry struct {
 	Codes     []int   `json:"codes"`
 	Label     string  `json:"label"`
 	TargetApp *string `json:"targetApp,omitempty"`
+	// Sequence optionally turns the shortcut into a leader-key sequence: chords pressed one
+	// after another, e.g. Ctrl+Space, then W. Codes mirrors the final chord.
+	Sequence [][]int `json:"sequence,omitempty"`
+	// SequenceTimeoutMs is the maximum time between two chords of Sequence (default 1000).
+	SequenceTimeoutMs int `json:"sequenceTimeoutMs,omitempty"`
+	// TriggerMode is "press" (default), "tap", "hold" or "double_tap". The same key combination
+	// can be used by several menus with different trigger modes.
+	TriggerMode string `json:"triggerMode,omitempty"`
+	// PageID, if set, opens the menu on this page.
+	PageID *int `json:"pageID,omitempty"`
 }
 
 type KBDLLHOOKSTRUCT struct {
@@ -345,3 +356,35 @@ func RobotGoKeyNameToVK(robotGoKey string) (int, bool) {
 	}
 	return 0, false
 }
+
+// ParseRobotGoShortcut converts a RobotGo shortcut string (e.g. "ctrl+shift+p") into VK codes,
+// modifiers first and the main key last. Modifiers map to their generic codes.
+func ParseRobotGoShortcut(keys string) ([]int, bool) {
+	if keys == "" {
+		return nil, false
+	}
+	var mods []int
+	main := -1
+	for _, part := range strings.Split(strings.ToLower(keys), "+") {
+		switch part {
+		case "ctrl":
+			mods = append(mods, VK_CONTROL)
+		case "alt":
+			mods = append(mods, VK_ALT)
+		case "shift":
+			mods = append(mods, VK_SHIFT)
+		case "cmd":
+			mods = append(mods, VK_LWIN)
+		default:
+			vk, ok := RobotGoKeyNameToVK(part)
+			if !ok {
+				return nil, false
+			}
+			main = vk
+		}
+	}
+	if main < 0 {
+		return nil, false
+	}
+	return append(mods, main), true
+}
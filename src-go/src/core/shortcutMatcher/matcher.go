@@ -0,0 +1,403 @@
+// Package shortcutMatcher decides which configured shortcut a stream of key events triggers.
+// It tracks its own set of held keys instead of querying the OS, so it has no platform
+// dependencies; the Windows keyboard hook only feeds it events and acts on its decisions.
+package shortcutMatcher
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Virtual-key codes of the modifiers the matcher needs to know about.
+const (
+	VKShift    = 0x10
+	VKControl  = 0x11
+	VKAlt      = 0x12
+	VKLShift   = 0xA0
+	VKRShift   = 0xA1
+	VKLControl = 0xA2
+	VKRControl = 0xA3
+	VKLAlt     = 0xA4
+	VKRAlt     = 0xA5
+	VKLWin     = 0x5B
+	VKRWin     = 0x5C
+)
+
+// genericModifier maps left/right modifier codes to their generic code.
+var genericModifier = map[int]int{
+	VKLShift: VKShift, VKRShift: VKShift,
+	VKLControl: VKControl, VKRControl: VKControl,
+	VKLAlt: VKAlt, VKRAlt: VKAlt,
+}
+
+// sidesOf maps a generic modifier code to its left and right codes.
+var sidesOf = map[int][2]int{
+	VKShift:   {VKLShift, VKRShift},
+	VKControl: {VKLControl, VKRControl},
+	VKAlt:     {VKLAlt, VKRAlt},
+}
+
+// GenericKey returns the generic code for a left/right modifier, or code unchanged.
+func GenericKey(code int) int {
+	if g, ok := genericModifier[code]; ok {
+		return g
+	}
+	return code
+}
+
+// Shortcut is a key combination: the last code is the main key, all others must be held.
+// Generic modifier codes (Shift, Ctrl, Alt) are satisfied by either side.
+type Shortcut struct {
+	Codes []int
+	// TargetApp restricts the shortcut to a focused app; empty means global.
+	TargetApp string
+	// Sequence, if it has more than one chord, makes this a leader-key shortcut whose chords
+	// are pressed one after another. Codes is ignored for matching in that case.
+	Sequence [][]int
+	// Timeout is the maximum time between two chords of Sequence; zero means DefaultSequenceTimeout.
+	Timeout time.Duration
+	// Trigger selects how the key press is interpreted; empty means TriggerPress.
+	// Sequences always use TriggerPress for their final chord.
+	Trigger TriggerMode
+}
+
+// finalChord returns the chord whose release releases the shortcut.
+func (s Shortcut) finalChord() []int {
+	if len(s.Sequence) > 0 {
+		return s.Sequence[len(s.Sequence)-1]
+	}
+	return s.Codes
+}
+
+func (s Shortcut) isSequence() bool {
+	return len(s.Sequence) > 1
+}
+
+// Decision tells the event source what a key event did.
+type Decision struct {
+	// Consume is set when the event must not reach other applications.
+	Consume bool
+	// Pressed is the ID of the shortcut that became active, if any.
+	Pressed string
+	// Released lists the IDs of shortcuts that stopped being active.
+	Released []string
+	// TogglePause is set when the pause toggle combination was pressed.
+	TogglePause bool
+	// Pending is set when the key press advanced a sequence that is not complete yet.
+	Pending *PendingSequence
+	// SequenceEnded is set when a pending sequence was completed (Pressed is set) or abandoned
+	// because of a mismatching key, its timeout or a pause.
+	SequenceEnded bool
+	// WakeAfter, if positive, asks the event source to call Tick after this delay
+	// to resolve a timing-based trigger or sequence timeout.
+	WakeAfter time.Duration
+}
+
+// Matcher is a state machine over key-down/key-up events. It is safe for concurrent use.
+type Matcher struct {
+	mu          sync.Mutex
+	shortcuts   map[string]Shortcut
+	pauseToggle []int
+	focusedApp  string
+	suspended   bool
+	held        map[int]bool
+	active      map[string]bool
+	pending     *pendingSequence
+	gesture     *gesture
+	timing      TriggerTiming
+	now         func() time.Time
+}
+
+// New returns a matcher with no shortcuts.
+func New() *Matcher {
+	return &Matcher{
+		shortcuts: map[string]Shortcut{},
+		held:      map[int]bool{},
+		active:    map[string]bool{},
+		timing:    DefaultTriggerTiming,
+		now:       time.Now,
+	}
+}
+
+// SetShortcuts replaces the shortcut set, keyed by shortcut ID. Active shortcuts are forgotten.
+func (m *Matcher) SetShortcuts(shortcuts map[string]Shortcut) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.shortcuts = make(map[string]Shortcut, len(shortcuts))
+	for id, s := range shortcuts {
+		s.Codes = slices.Clone(s.Codes)
+		s.Sequence = slices.Clone(s.Sequence)
+		m.shortcuts[id] = s
+	}
+	m.active = map[string]bool{}
+	m.pending = nil
+	m.gesture = nil
+}
+
+// SetPauseToggle sets the pause toggle combination (main key last). Unlike shortcuts, its
+// modifiers must match exactly: Ctrl, Alt, Shift and Win must be held if and only if listed.
+// Nil disables it.
+func (m *Matcher) SetPauseToggle(codes []int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pauseToggle = slices.Clone(codes)
+}
+
+// SetFocusedApp updates the app name used to match TargetApp shortcuts.
+func (m *Matcher) SetFocusedApp(app string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.focusedApp = app
+}
+
+// SetSuspended pauses shortcut matching. Held keys are still tracked and the pause toggle still works.
+func (m *Matcher) SetSuspended(suspended bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.suspended = suspended
+}
+
+// Reset forgets all held keys and active shortcuts, e.g. after the event source was reinstalled.
+func (m *Matcher) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.held = map[int]bool{}
+	m.active = map[string]bool{}
+	m.pending = nil
+	m.gesture = nil
+}
+
+// Resync drops held keys for which isDown reports false. Event sources call it when key-up
+// events may have been missed, e.g. while another desktop had the input focus.
+// Active shortcuts are left alone; they are released by the next matching key-up.
+func (m *Matcher) Resync(isDown func(key int) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key := range m.held {
+		if !isDown(key) {
+			delete(m.held, key)
+		}
+	}
+}
+
+// KeyDown processes a key press. Auto-repeated presses of an already held key are matched
+// again (so they keep being consumed) but do not report the shortcut as pressed a second time.
+//
+// While a sequence is pending, modifier presses only build up the next chord. A key that does
+// not continue any candidate sequence abandons it and is then matched as a fresh key press.
+func (m *Matcher) KeyDown(key int) Decision {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	repeat := m.held[key]
+	m.held[key] = true
+
+	if m.pauseToggleHeld(key) {
+		m.gesture = nil
+		return Decision{Consume: true, TogglePause: true, SequenceEnded: m.cancelPending()}
+	}
+	if m.suspended {
+		m.gesture = nil
+		return Decision{SequenceEnded: m.cancelPending()}
+	}
+
+	var d Decision
+	if m.pending != nil {
+		if isModifier(key) {
+			return d
+		}
+		if repeat {
+			// Holding down the previous chord must not abandon the sequence.
+			return Decision{Consume: true}
+		}
+		if m.advanceSequence(key, &d) {
+			return d
+		}
+	}
+	if repeat && (m.activeSequenceEndsWith(key) || m.gestureHolds(key)) {
+		return Decision{Consume: true}
+	}
+	if m.continueGesture(key, &d) {
+		return d
+	}
+
+	matches := m.bestMatches(key)
+	if len(matches) == 0 {
+		m.startSequence(key, &d)
+		return d
+	}
+	if id := matches[0]; m.shortcuts[id].Trigger.isPress() {
+		d.Consume = true
+		if !m.active[id] {
+			m.active[id] = true
+			d.Pressed = id
+		}
+		return d
+	}
+	m.startGesture(key, matches, &d)
+	return d
+}
+
+// KeyUp processes a key release. Active shortcuts that include the key and are no longer
+// fully held are reported as released. Key releases are never consumed.
+func (m *Matcher) KeyUp(key int) Decision {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.held, key)
+
+	var d Decision
+	m.releaseGesture(key, &d)
+	for _, id := range sortedIDs(m.active) {
+		codes := m.shortcuts[id].finalChord()
+		if !containsKey(codes, key) {
+			continue
+		}
+		if !m.modifiersHeld(codes) {
+			delete(m.active, id)
+			d.Released = append(d.Released, id)
+		}
+	}
+	return d
+}
+
+// bestMatches returns the shortcuts triggered by pressing key with the currently held keys,
+// best first. The first one wins unless trigger modes apply (see KeyDown).
+//
+// When several shortcuts match, the order is deterministic:
+//  1. A shortcut whose TargetApp equals the focused app beats shortcuts without a TargetApp.
+//  2. Among those, the shortcut with more codes (e.g. Ctrl+Shift+A over Ctrl+A) wins.
+//  3. Remaining ties go to the lowest numeric ID, then the lexically smallest ID.
+//
+// Shortcuts for another app never fire; if only those match, the key passes through.
+func (m *Matcher) bestMatches(key int) []string {
+	var forApp, global []string
+	for id, s := range m.shortcuts {
+		if s.isSequence() || !m.chordMatches(s.finalChord(), key) {
+			continue
+		}
+		switch s.TargetApp {
+		case "":
+			global = append(global, id)
+		case m.focusedApp:
+			forApp = append(forApp, id)
+		}
+	}
+	for _, candidates := range [][]string{forApp, global} {
+		if len(candidates) > 0 {
+			slices.SortFunc(candidates, m.comparePriority)
+			return candidates
+		}
+	}
+	return nil
+}
+
+func (m *Matcher) comparePriority(a, b string) int {
+	return ComparePriority(a, m.shortcuts[a], b, m.shortcuts[b])
+}
+
+// ComparePriority orders two shortcuts of the same scope that fire on one key press the way
+// the matcher breaks the tie: the longer final chord first, then CompareIDs.
+func ComparePriority(idA string, a Shortcut, idB string, b Shortcut) int {
+	if n := len(b.finalChord()) - len(a.finalChord()); n != 0 {
+		return n
+	}
+	return CompareIDs(idA, idB)
+}
+
+// CompareIDs orders shortcut IDs numerically where possible, falling back to string order.
+func CompareIDs(a, b string) int {
+	ai, errA := strconv.Atoi(a)
+	bi, errB := strconv.Atoi(b)
+	if errA == nil && errB == nil {
+		return cmp.Compare(ai, bi)
+	}
+	return strings.Compare(a, b)
+}
+
+// chordMatches reports whether pressing key completes chord with the currently held keys.
+func (m *Matcher) chordMatches(chord []int, key int) bool {
+	return len(chord) > 0 && chord[len(chord)-1] == key && m.modifiersHeld(chord[:len(chord)-1])
+}
+
+// isHeld reports whether code is held; a generic modifier is held if either side is.
+func (m *Matcher) isHeld(code int) bool {
+	if m.held[code] {
+		return true
+	}
+	if sides, ok := sidesOf[code]; ok {
+		return m.held[sides[0]] || m.held[sides[1]]
+	}
+	return false
+}
+
+// modifiersHeld reports whether every code in codes is held.
+func (m *Matcher) modifiersHeld(codes []int) bool {
+	for _, code := range codes {
+		if !m.isHeld(code) {
+			return false
+		}
+	}
+	return true
+}
+
+// pauseToggleHeld reports whether pressing key completes the pause toggle with exactly its modifiers.
+func (m *Matcher) pauseToggleHeld(key int) bool {
+	if len(m.pauseToggle) == 0 || m.pauseToggle[len(m.pauseToggle)-1] != key {
+		return false
+	}
+	wanted := m.pauseToggle[:len(m.pauseToggle)-1]
+	for _, mod := range []int{VKControl, VKAlt, VKShift, VKLWin} {
+		if m.isHeld(mod) != slices.ContainsFunc(wanted, func(c int) bool { return GenericKey(c) == mod }) {
+			return false
+		}
+	}
+	return true
+}
+
+func isModifier(key int) bool {
+	switch GenericKey(key) {
+	case VKShift, VKControl, VKAlt, VKLWin, VKRWin:
+		return true
+	}
+	return false
+}
+
+// containsKey reports whether key is part of codes, mapping a left/right key to its generic code.
+func containsKey(codes []int, key int) bool {
+	return slices.Contains(codes, key) || slices.Contains(codes, GenericKey(key))
+}
+
+func sortedIDs(set map[string]bool) []string {
+	ids := make([]string, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, CompareIDs)
+	return ids
+}
+
+// FiresOn reports whether shortcut fires when the key combination combo is pressed
+// (combo's last code pressed while its other codes are held). Generic modifiers in either
+// may stand for both sides, so this errs on the side of reporting a possible overlap.
+func FiresOn(shortcut, combo []int) bool {
+	if len(shortcut) == 0 || len(combo) == 0 {
+		return false
+	}
+	if GenericKey(shortcut[len(shortcut)-1]) != GenericKey(combo[len(combo)-1]) {
+		return false
+	}
+	held := combo[:len(combo)-1]
+	for _, mod := range shortcut[:len(shortcut)-1] {
+		if !slices.ContainsFunc(held, func(h int) bool {
+			return h == mod || GenericKey(h) == mod || h == GenericKey(mod)
+		}) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,405 @@
+package shortcutMatcher
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+const (
+	keyA = 0x41
+	keyC = 0x43
+	keyK = 0x4B
+	keyP = 0x50
+	keyX = 0x58
+)
+
+// fakeClock replaces the matcher's clock so timing-based behaviour is deterministic.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestMatcher(shortcuts map[string]Shortcut) (*Matcher, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	m := New()
+	m.now = clock.now
+	m.SetShortcuts(shortcuts)
+	return m, clock
+}
+
+// press holds keys in order and returns the decision for the last one.
+func press(m *Matcher, keys ...int) Decision {
+	var d Decision
+	for _, key := range keys {
+		d = m.KeyDown(key)
+	}
+	return d
+}
+
+// release lets go of keys in order and collects the released shortcut IDs.
+func release(m *Matcher, keys ...int) []string {
+	var released []string
+	for _, key := range keys {
+		released = append(released, m.KeyUp(key).Released...)
+	}
+	return released
+}
+
+func TestChord(t *testing.T) {
+	m, _ := newTestMatcher(map[string]Shortcut{
+		"1": {Codes: []int{VKControl, keyA}},
+	})
+
+	if d := m.KeyDown(keyA); d.Consume || d.Pressed != "" {
+		t.Fatalf("A without Ctrl: got %+v, want pass-through", d)
+	}
+	release(m, keyA)
+
+	if d := m.KeyDown(VKLControl); d.Consume {
+		t.Fatalf("Ctrl alone was consumed")
+	}
+	d := m.KeyDown(keyA)
+	if !d.Consume || d.Pressed != "1" {
+		t.Fatalf("LCtrl+A: got %+v, want shortcut 1 pressed", d)
+	}
+	if d := m.KeyDown(keyA); !d.Consume || d.Pressed != "" {
+		t.Fatalf("auto-repeat: got %+v, want consumed without a second press", d)
+	}
+	if got := release(m, keyA, VKLControl); !slices.Equal(got, []string{"1"}) {
+		t.Fatalf("released %v, want [1]", got)
+	}
+}
+
+func TestReleaseOnModifierUp(t *testing.T) {
+	m, _ := newTestMatcher(map[string]Shortcut{
+		"1": {Codes: []int{VKControl, keyA}},
+	})
+	press(m, VKRControl, keyA)
+	if got := release(m, VKRControl); !slices.Equal(got, []string{"1"}) {
+		t.Fatalf("released %v on modifier up, want [1]", got)
+	}
+	if got := release(m, keyA); len(got) != 0 {
+		t.Fatalf("released %v again on main key up", got)
+	}
+}
+
+func TestTieBreak(t *testing.T) {
+	m, _ := newTestMatcher(map[string]Shortcut{
+		"10": {Codes: []int{VKControl, keyA}},
+		"2":  {Codes: []int{VKControl, keyA}},
+		"3":  {Codes: []int{VKControl, VKShift, keyA}},
+		"5":  {Codes: []int{VKControl, keyA}, TargetApp: "code"},
+		"7":  {Codes: []int{VKControl, keyX}, TargetApp: "code"},
+	})
+
+	tests := []struct {
+		name string
+		app  string
+		keys []int
+		want string
+	}{
+		{"lowest numeric ID", "", []int{VKLControl, keyA}, "2"},
+		{"more codes win", "", []int{VKLControl, VKLShift, keyA}, "3"},
+		{"focused app wins", "code", []int{VKLControl, keyA}, "5"},
+		{"focused app beats more codes", "code", []int{VKLControl, VKLShift, keyA}, "5"},
+		{"other app passes through", "", []int{VKLControl, keyX}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m.Reset()
+			m.SetFocusedApp(tt.app)
+			d := press(m, tt.keys...)
+			if d.Pressed != tt.want || d.Consume != (tt.want != "") {
+				t.Fatalf("got %+v, want %q pressed", d, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareIDs(t *testing.T) {
+	ids := []string{"b", "10", "a", "2"}
+	slices.SortFunc(ids, CompareIDs)
+	if want := []string{"2", "10", "a", "b"}; !slices.Equal(ids, want) {
+		t.Fatalf("sorted %v, want %v", ids, want)
+	}
+}
+
+func TestComparePriority(t *testing.T) {
+	shortcuts := map[string]Shortcut{
+		"1": {Codes: []int{VKControl, keyA}},
+		"2": {Codes: []int{VKControl, VKShift, keyA}},
+		"3": {Codes: []int{keyA}, Sequence: [][]int{{VKControl, keyK}, {VKControl, VKAlt, VKShift, keyA}}},
+		"4": {Codes: []int{VKControl, keyA}},
+	}
+	ids := []string{"4", "1", "3", "2"}
+	slices.SortFunc(ids, func(a, b string) int { return ComparePriority(a, shortcuts[a], b, shortcuts[b]) })
+	// A sequence ranks by its final chord, not by Codes
+	if want := []string{"3", "2", "1", "4"}; !slices.Equal(ids, want) {
+		t.Fatalf("sorted %v, want %v", ids, want)
+	}
+}
+
+func TestSequence(t *testing.T) {
+	newSequenceMatcher := func() (*Matcher, *fakeClock) {
+		return newTestMatcher(map[string]Shortcut{
+			"1": {Sequence: [][]int{{VKControl, keyK}, {VKControl, keyC}}},
+			"2": {Codes: []int{keyX}},
+		})
+	}
+
+	t.Run("completes", func(t *testing.T) {
+		m, clock := newSequenceMatcher()
+		d := press(m, VKLControl, keyK)
+		if !d.Consume || d.Pending == nil || d.Pending.Step != 1 || d.WakeAfter != DefaultSequenceTimeout {
+			t.Fatalf("first chord: got %+v, want pending step 1", d)
+		}
+		release(m, keyK)
+		clock.advance(DefaultSequenceTimeout / 2)
+		d = m.KeyDown(keyC)
+		if !d.Consume || d.Pressed != "1" || !d.SequenceEnded {
+			t.Fatalf("second chord: got %+v, want sequence 1 pressed", d)
+		}
+		if got := release(m, keyC); !slices.Equal(got, []string{"1"}) {
+			t.Fatalf("released %v, want [1]", got)
+		}
+	})
+
+	t.Run("times out", func(t *testing.T) {
+		m, clock := newSequenceMatcher()
+		press(m, VKLControl, keyK)
+		release(m, keyK)
+		clock.advance(DefaultSequenceTimeout)
+		if d := m.Tick(); !d.SequenceEnded {
+			t.Fatalf("tick after timeout: got %+v, want sequence ended", d)
+		}
+		if d := m.KeyDown(keyC); d.Consume || d.Pressed != "" {
+			t.Fatalf("late second chord: got %+v, want pass-through", d)
+		}
+	})
+
+	t.Run("times out without tick", func(t *testing.T) {
+		m, clock := newSequenceMatcher()
+		press(m, VKLControl, keyK)
+		release(m, keyK)
+		clock.advance(DefaultSequenceTimeout + time.Millisecond)
+		if d := m.KeyDown(keyC); d.Pressed != "" || !d.SequenceEnded {
+			t.Fatalf("late second chord: got %+v, want sequence ended", d)
+		}
+	})
+
+	t.Run("mismatch is matched fresh", func(t *testing.T) {
+		m, _ := newSequenceMatcher()
+		press(m, VKLControl, keyK)
+		release(m, keyK, VKLControl)
+		d := m.KeyDown(keyX)
+		if !d.SequenceEnded || d.Pressed != "2" {
+			t.Fatalf("mismatching key: got %+v, want sequence ended and shortcut 2 pressed", d)
+		}
+	})
+
+	t.Run("repeat keeps pending", func(t *testing.T) {
+		m, _ := newSequenceMatcher()
+		press(m, VKLControl, keyK)
+		if d := m.KeyDown(keyK); !d.Consume || d.SequenceEnded {
+			t.Fatalf("auto-repeat of first chord: got %+v, want consumed and still pending", d)
+		}
+		release(m, keyK)
+		if d := m.KeyDown(keyC); d.Pressed != "1" {
+			t.Fatalf("second chord after repeat: got %+v, want sequence 1 pressed", d)
+		}
+	})
+}
+
+func TestResync(t *testing.T) {
+	m, _ := newTestMatcher(map[string]Shortcut{
+		"1": {Codes: []int{VKControl, keyA}},
+	})
+
+	// The Ctrl key-up was missed, e.g. while a secure desktop had the focus.
+	m.KeyDown(VKLControl)
+	m.Resync(func(key int) bool { return false })
+	if d := m.KeyDown(keyA); d.Consume || d.Pressed != "" {
+		t.Fatalf("A after resync: got %+v, want pass-through", d)
+	}
+	release(m, keyA)
+
+	m.KeyDown(VKLControl)
+	m.Resync(func(key int) bool { return key == VKLControl })
+	if d := m.KeyDown(keyA); d.Pressed != "1" {
+		t.Fatalf("A with Ctrl still down: got %+v, want shortcut 1 pressed", d)
+	}
+}
+
+func TestPauseToggle(t *testing.T) {
+	m, _ := newTestMatcher(map[string]Shortcut{
+		"1": {Codes: []int{VKControl, keyA}},
+	})
+	m.SetPauseToggle([]int{VKControl, VKAlt, keyP})
+
+	if d := press(m, VKLControl, VKLAlt, keyP); !d.Consume || !d.TogglePause {
+		t.Fatalf("Ctrl+Alt+P: got %+v, want pause toggled", d)
+	}
+	release(m, keyP, VKLAlt, VKLControl)
+
+	if d := press(m, VKLControl, VKLAlt, VKLShift, keyP); d.TogglePause {
+		t.Fatalf("Ctrl+Alt+Shift+P toggled pause; modifiers must match exactly")
+	}
+	release(m, keyP, VKLShift, VKLAlt, VKLControl)
+
+	m.SetSuspended(true)
+	if d := press(m, VKLControl, keyA); d.Consume || d.Pressed != "" {
+		t.Fatalf("shortcut while suspended: got %+v, want pass-through", d)
+	}
+	release(m, keyA)
+	if d := press(m, VKLAlt, keyP); !d.TogglePause {
+		t.Fatalf("pause toggle while suspended: got %+v, want pause toggled", d)
+	}
+	release(m, keyP, VKLAlt, VKLControl)
+
+	m.SetSuspended(false)
+	if d := press(m, VKLControl, keyA); d.Pressed != "1" {
+		t.Fatalf("shortcut after resume: got %+v, want shortcut 1 pressed", d)
+	}
+}
+
+func TestPauseTogglePendingSequence(t *testing.T) {
+	m, _ := newTestMatcher(map[string]Shortcut{
+		"1": {Sequence: [][]int{{VKControl, keyK}, {VKControl, keyC}}},
+	})
+	m.SetPauseToggle([]int{VKControl, keyP})
+
+	press(m, VKLControl, keyK)
+	release(m, keyK)
+	if d := m.KeyDown(keyP); !d.TogglePause || !d.SequenceEnded {
+		t.Fatalf("pause toggle during sequence: got %+v, want pause toggled and sequence ended", d)
+	}
+}
+
+func TestTriggerModes(t *testing.T) {
+	timing := DefaultTriggerTiming
+	combo := []int{VKControl, keyA}
+	tapAndHold := map[string]Shortcut{
+		"tap":  {Codes: combo, Trigger: TriggerTap},
+		"hold": {Codes: combo, Trigger: TriggerHold},
+	}
+	tapAndDouble := map[string]Shortcut{
+		"tap":    {Codes: combo, Trigger: TriggerTap},
+		"double": {Codes: combo, Trigger: TriggerDoubleTap},
+	}
+
+	t.Run("tap", func(t *testing.T) {
+		m, clock := newTestMatcher(tapAndHold)
+		if d := press(m, VKLControl, keyA); !d.Consume || d.Pressed != "" || d.WakeAfter != timing.Hold {
+			t.Fatalf("key down: got %+v, want consumed and waiting for hold", d)
+		}
+		clock.advance(timing.TapMax / 2)
+		if d := m.KeyUp(keyA); d.Pressed != "tap" || !slices.Equal(d.Released, []string{"tap"}) {
+			t.Fatalf("short press: got %+v, want tap pressed and released", d)
+		}
+	})
+
+	t.Run("long press is neither tap nor hold", func(t *testing.T) {
+		m, clock := newTestMatcher(map[string]Shortcut{
+			"tap": {Codes: combo, Trigger: TriggerTap},
+		})
+		press(m, VKLControl, keyA)
+		clock.advance(timing.TapMax + time.Millisecond)
+		if d := m.KeyUp(keyA); d.Pressed != "" {
+			t.Fatalf("long press: got %+v, want nothing", d)
+		}
+	})
+
+	t.Run("hold", func(t *testing.T) {
+		m, clock := newTestMatcher(tapAndHold)
+		press(m, VKLControl, keyA)
+		clock.advance(timing.Hold / 4)
+		if d := m.Tick(); d.Pressed != "" || d.WakeAfter != timing.Hold*3/4 {
+			t.Fatalf("early tick: got %+v, want to wait for the rest of the hold", d)
+		}
+		if d := m.KeyDown(keyA); !d.Consume || d.Pressed != "" {
+			t.Fatalf("auto-repeat during hold: got %+v, want consumed", d)
+		}
+		clock.advance(timing.Hold * 3 / 4)
+		if d := m.Tick(); d.Pressed != "hold" {
+			t.Fatalf("tick at hold threshold: got %+v, want hold pressed", d)
+		}
+		if got := release(m, keyA); !slices.Equal(got, []string{"hold"}) {
+			t.Fatalf("released %v, want [hold]", got)
+		}
+	})
+
+	t.Run("hold released before tick", func(t *testing.T) {
+		m, clock := newTestMatcher(tapAndHold)
+		press(m, VKLControl, keyA)
+		clock.advance(timing.Hold)
+		d := m.KeyUp(keyA)
+		if d.Pressed != "hold" || !slices.Equal(d.Released, []string{"hold"}) {
+			t.Fatalf("late release: got %+v, want hold pressed and released", d)
+		}
+	})
+
+	t.Run("double tap", func(t *testing.T) {
+		m, clock := newTestMatcher(tapAndDouble)
+		press(m, VKLControl, keyA)
+		clock.advance(timing.TapMax / 2)
+		if d := m.KeyUp(keyA); d.Pressed != "" || d.WakeAfter != timing.DoubleTapWindow {
+			t.Fatalf("first tap: got %+v, want to wait for a second tap", d)
+		}
+		clock.advance(timing.DoubleTapWindow / 2)
+		if d := m.KeyDown(keyA); !d.Consume || d.Pressed != "double" || !slices.Equal(d.Released, []string{"double"}) {
+			t.Fatalf("second tap: got %+v, want double pressed and released", d)
+		}
+		if got := release(m, keyA); len(got) != 0 {
+			t.Fatalf("released %v again on key up", got)
+		}
+	})
+
+	t.Run("tap after double tap window", func(t *testing.T) {
+		m, clock := newTestMatcher(tapAndDouble)
+		press(m, VKLControl, keyA)
+		clock.advance(timing.TapMax / 2)
+		m.KeyUp(keyA)
+		clock.advance(timing.DoubleTapWindow / 2)
+		if d := m.Tick(); d.Pressed != "" || d.WakeAfter != timing.DoubleTapWindow/2 {
+			t.Fatalf("early tick: got %+v, want to keep waiting", d)
+		}
+		clock.advance(timing.DoubleTapWindow / 2)
+		if d := m.Tick(); d.Pressed != "tap" || !slices.Equal(d.Released, []string{"tap"}) {
+			t.Fatalf("tick after window: got %+v, want tap pressed and released", d)
+		}
+	})
+
+	t.Run("custom timing", func(t *testing.T) {
+		m, clock := newTestMatcher(tapAndHold)
+		m.SetTriggerTiming(TriggerTiming{Hold: time.Second})
+		press(m, VKLControl, keyA)
+		clock.advance(timing.Hold)
+		if d := m.Tick(); d.Pressed != "" {
+			t.Fatalf("tick at default hold: got %+v, want custom hold to apply", d)
+		}
+		clock.advance(time.Second - timing.Hold)
+		if d := m.Tick(); d.Pressed != "hold" {
+			t.Fatalf("tick at custom hold: got %+v, want hold pressed", d)
+		}
+	})
+}
+
+func TestFiresOn(t *testing.T) {
+	tests := []struct {
+		shortcut, combo []int
+		want            bool
+	}{
+		{[]int{VKControl, keyA}, []int{VKLControl, keyA}, true},
+		{[]int{VKLControl, keyA}, []int{VKControl, keyA}, true},
+		{[]int{VKControl, keyA}, []int{VKControl, VKShift, keyA}, true},
+		{[]int{VKControl, VKShift, keyA}, []int{VKControl, keyA}, false},
+		{[]int{VKControl, keyA}, []int{VKControl, keyX}, false},
+		{nil, []int{keyA}, false},
+	}
+	for _, tt := range tests {
+		if got := FiresOn(tt.shortcut, tt.combo); got != tt.want {
+			t.Errorf("FiresOn(%v, %v) = %v, want %v", tt.shortcut, tt.combo, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package shortcutMatcher
+
+import (
+	"slices"
+	"time"
+)
+
+// DefaultSequenceTimeout is the time allowed between two chords of a sequence without its own timeout.
+const DefaultSequenceTimeout = time.Second
+
+// PendingSequence describes a partially entered leader-key sequence.
+type PendingSequence struct {
+	// Step is the number of chords entered so far.
+	Step int
+	// Candidates are the IDs of the sequences that can still complete, in priority order.
+	Candidates []string
+	// Timeout is how long the matcher waits for the next chord.
+	Timeout time.Duration
+}
+
+type pendingSequence struct {
+	PendingSequence
+	deadline time.Time
+}
+
+// startSequence begins a sequence if key completes the first chord of any sequence in scope.
+func (m *Matcher) startSequence(key int, d *Decision) {
+	var forApp, global []string
+	for id, s := range m.shortcuts {
+		if !s.isSequence() || !m.chordMatches(s.Sequence[0], key) {
+			continue
+		}
+		switch s.TargetApp {
+		case "":
+			global = append(global, id)
+		case m.focusedApp:
+			forApp = append(forApp, id)
+		}
+	}
+	if len(forApp)+len(global) == 0 {
+		return
+	}
+	slices.SortFunc(forApp, CompareIDs)
+	slices.SortFunc(global, CompareIDs)
+	m.setPending(1, append(forApp, global...), d)
+}
+
+// advanceSequence feeds key to the pending sequence. It returns false if key does not continue
+// any candidate, in which case the sequence is abandoned and key still needs to be matched.
+func (m *Matcher) advanceSequence(key int, d *Decision) bool {
+	p := m.pending
+	if m.now().After(p.deadline) {
+		d.SequenceEnded = m.cancelPending()
+		return false
+	}
+
+	var next []string
+	for _, id := range p.Candidates {
+		if m.chordMatches(m.shortcuts[id].Sequence[p.Step], key) {
+			next = append(next, id)
+		}
+	}
+	if len(next) == 0 {
+		d.SequenceEnded = m.cancelPending()
+		return false
+	}
+
+	// A completed sequence wins over longer ones sharing its chords; the config manager
+	// reports those as conflicts. Among completed ones the most specific final chord wins.
+	var completed []string
+	for _, id := range next {
+		if len(m.shortcuts[id].Sequence) == p.Step+1 {
+			completed = append(completed, id)
+		}
+	}
+	if len(completed) > 0 {
+		slices.SortStableFunc(completed, func(a, b string) int {
+			return len(m.shortcuts[b].finalChord()) - len(m.shortcuts[a].finalChord())
+		})
+		id := completed[0]
+		m.pending = nil
+		d.Consume = true
+		d.SequenceEnded = true
+		if !m.active[id] {
+			m.active[id] = true
+			d.Pressed = id
+		}
+		return true
+	}
+	m.setPending(p.Step+1, next, d)
+	return true
+}
+
+// setPending records the sequence progress and reports it in d. The timeout is the longest
+// timeout among the candidates, since any of them may still be completed.
+func (m *Matcher) setPending(step int, candidates []string, d *Decision) {
+	timeout := time.Duration(0)
+	for _, id := range candidates {
+		t := m.shortcuts[id].Timeout
+		if t <= 0 {
+			t = DefaultSequenceTimeout
+		}
+		timeout = max(timeout, t)
+	}
+	m.pending = &pendingSequence{
+		PendingSequence: PendingSequence{Step: step, Candidates: candidates, Timeout: timeout},
+		deadline:        m.now().Add(timeout),
+	}
+	d.Consume = true
+	d.WakeAfter = timeout
+	pending := m.pending.PendingSequence
+	pending.Candidates = slices.Clone(candidates)
+	d.Pending = &pending
+}
+
+// expirePending abandons the pending sequence if its timeout has passed.
+func (m *Matcher) expirePending(d *Decision) {
+	if m.pending != nil && !m.now().Before(m.pending.deadline) {
+		m.pending = nil
+		d.SequenceEnded = true
+	}
+}
+
+// cancelPending abandons the pending sequence and reports whether there was one.
+func (m *Matcher) cancelPending() bool {
+	if m.pending == nil {
+		return false
+	}
+	m.pending = nil
+	return true
+}
+
+// activeSequenceEndsWith reports whether an active sequence's final chord has key as main key,
+// so that auto-repeats of that key are consumed like those of a plain shortcut.
+func (m *Matcher) activeSequenceEndsWith(key int) bool {
+	for id := range m.active {
+		s := m.shortcuts[id]
+		if s.isSequence() {
+			chord := s.finalChord()
+			if chord[len(chord)-1] == key {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+package shortcutMatcher
+
+import "time"
+
+// TriggerMode selects how a key press triggers a shortcut. Shortcuts with the same key combination
+// but different modes (other than TriggerPress) can coexist, e.g. tap for one menu and hold for another.
+type TriggerMode string
+
+const (
+	// TriggerPress fires on key-down and releases on key-up. This is the default.
+	TriggerPress TriggerMode = "press"
+	// TriggerTap fires on key-up if the key was held shorter than TriggerTiming.TapMax.
+	// If a double-tap shortcut shares the combination, it fires once the double-tap window has passed.
+	// Tap and double-tap shortcuts have nothing to hold: the Decision that presses them also releases them.
+	TriggerTap TriggerMode = "tap"
+	// TriggerHold fires once the key has been held for TriggerTiming.Hold and releases on key-up.
+	TriggerHold TriggerMode = "hold"
+	// TriggerDoubleTap fires on the second key-down if it follows a tap within TriggerTiming.DoubleTapWindow.
+	TriggerDoubleTap TriggerMode = "double_tap"
+)
+
+// TriggerModes lists the valid trigger modes.
+var TriggerModes = []TriggerMode{TriggerPress, TriggerTap, TriggerHold, TriggerDoubleTap}
+
+func (t TriggerMode) isPress() bool {
+	return t == "" || t == TriggerPress
+}
+
+// TriggerTiming holds the thresholds of the timing-based trigger modes.
+type TriggerTiming struct {
+	TapMax          time.Duration
+	Hold            time.Duration
+	DoubleTapWindow time.Duration
+}
+
+// DefaultTriggerTiming is used until SetTriggerTiming is called.
+var DefaultTriggerTiming = TriggerTiming{
+	TapMax:          200 * time.Millisecond,
+	Hold:            400 * time.Millisecond,
+	DoubleTapWindow: 300 * time.Millisecond,
+}
+
+// gesture tracks a key press on a combination whose best match uses a timing-based trigger mode.
+type gesture struct {
+	key int
+	// modes holds the best shortcut for each trigger mode among the equally specific matches.
+	modes      map[TriggerMode]string
+	down       bool
+	downAt     time.Time
+	releasedAt time.Time
+	// holdFired is set once the hold shortcut fired; it is then released like a press shortcut.
+	holdFired bool
+}
+
+// SetTriggerTiming sets the thresholds of the timing-based trigger modes. Zero fields keep their defaults.
+func (m *Matcher) SetTriggerTiming(timing TriggerTiming) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.timing = DefaultTriggerTiming
+	if timing.TapMax > 0 {
+		m.timing.TapMax = timing.TapMax
+	}
+	if timing.Hold > 0 {
+		m.timing.Hold = timing.Hold
+	}
+	if timing.DoubleTapWindow > 0 {
+		m.timing.DoubleTapWindow = timing.DoubleTapWindow
+	}
+}
+
+// Tick resolves time-based transitions: a hold threshold being reached, a tap whose double-tap
+// window has passed, or a sequence timing out. Event sources call it after Decision.WakeAfter;
+// extra calls are harmless.
+func (m *Matcher) Tick() Decision {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var d Decision
+	m.expirePending(&d)
+
+	g := m.gesture
+	if g == nil {
+		return d
+	}
+	now := m.now()
+	switch {
+	case g.down && !g.holdFired:
+		id, ok := g.modes[TriggerHold]
+		if !ok {
+			return d
+		}
+		if wait := g.downAt.Add(m.timing.Hold).Sub(now); wait > 0 {
+			d.WakeAfter = wait
+			return d
+		}
+		g.holdFired = true
+		m.active[id] = true
+		d.Pressed = id
+	case !g.down:
+		if wait := g.releasedAt.Add(m.timing.DoubleTapWindow).Sub(now); wait > 0 {
+			d.WakeAfter = wait
+			return d
+		}
+		m.gesture = nil
+		pulse(g.modes[TriggerTap], &d)
+	}
+	return d
+}
+
+// startGesture begins tracking a press of key. matches are the shortcuts matching it, best first;
+// those as specific as the best one compete by trigger mode, press-mode ones among them are ignored.
+func (m *Matcher) startGesture(key int, matches []string, d *Decision) {
+	g := &gesture{key: key, modes: map[TriggerMode]string{}, down: true, downAt: m.now()}
+	specificity := len(m.shortcuts[matches[0]].finalChord())
+	for _, id := range matches {
+		s := m.shortcuts[id]
+		if len(s.finalChord()) != specificity {
+			break
+		}
+		if _, taken := g.modes[s.Trigger]; !taken && !s.Trigger.isPress() {
+			g.modes[s.Trigger] = id
+		}
+	}
+	m.gesture = g
+	d.Consume = true
+	if _, ok := g.modes[TriggerHold]; ok {
+		d.WakeAfter = m.timing.Hold
+	}
+}
+
+// gestureHolds reports whether key is the held key of the current gesture, so its auto-repeats are consumed.
+func (m *Matcher) gestureHolds(key int) bool {
+	return m.gesture != nil && m.gesture.down && m.gesture.key == key
+}
+
+// continueGesture handles a key press while a gesture is in progress. A second press of the same
+// key within the double-tap window fires the double-tap shortcut; any other key abandons the gesture
+// (including a tap still waiting for its double-tap window) and is matched normally.
+func (m *Matcher) continueGesture(key int, d *Decision) bool {
+	g := m.gesture
+	if g == nil {
+		return false
+	}
+	m.gesture = nil
+	if g.down || g.key != key {
+		return false
+	}
+	id, ok := g.modes[TriggerDoubleTap]
+	if !ok || m.now().Sub(g.releasedAt) > m.timing.DoubleTapWindow || !m.chordMatches(m.shortcuts[id].finalChord(), key) {
+		return false
+	}
+	d.Consume = true
+	pulse(id, d)
+	return true
+}
+
+// releaseGesture handles the release of the gesture's key. A short press fires the tap shortcut,
+// or waits for a possible second tap if a double-tap shortcut shares the combination.
+func (m *Matcher) releaseGesture(key int, d *Decision) {
+	g := m.gesture
+	if g == nil || !g.down || g.key != key {
+		return
+	}
+	now := m.now()
+	if id, ok := g.modes[TriggerHold]; ok && !g.holdFired && now.Sub(g.downAt) >= m.timing.Hold {
+		// Released before Tick ran: the hold still counts, and ends right away.
+		m.gesture = nil
+		d.Pressed = id
+		d.Released = append(d.Released, id)
+		return
+	}
+	if g.holdFired || now.Sub(g.downAt) > m.timing.TapMax {
+		// A fired hold shortcut is released through m.active; a long press without hold does nothing.
+		m.gesture = nil
+		return
+	}
+	if _, ok := g.modes[TriggerDoubleTap]; ok {
+		g.down = false
+		g.releasedAt = now
+		d.WakeAfter = m.timing.DoubleTapWindow
+		return
+	}
+	m.gesture = nil
+	pulse(g.modes[TriggerTap], d)
+}
+
+// pulse reports id as pressed and released in the same decision, so listeners that pair
+// presses with releases see a complete press. An empty id reports nothing.
+func pulse(id string, d *Decision) {
+	if id == "" {
+		return
+	}
+	d.Pressed = id
+	d.Released = append(d.Released, id)
+}
@@ -1,5 +1,7 @@
 package core
 
+import "encoding/json"
+
 type AppInfo struct {
 	ExePath          string `json:"exePath"`                    // The resolved executable path
 	WorkingDirectory string `json:"workingDirectory,omitempty"` // Working directory from LNK
@@ -31,6 +33,7 @@ type ShowAnyWindowProperties struct {
 	IconPath        string `json:"icon_path"`
 	WindowHandle    int    `json:"window_handle"`
 	Instance        int    `json:"instance"`
+	ButtonActions
 }
 
 type ShowProgramWindowProperties struct {
@@ -39,18 +42,21 @@ type ShowProgramWindowProperties struct {
 	IconPath        string `json:"icon_path"`
 	WindowHandle    int    `json:"window_handle"`
 	Instance        int    `json:"instance"`
+	ButtonActions
 }
 
 type LaunchProgramProperties struct {
 	ButtonTextUpper string `json:"button_text_upper"` // AppName
 	ButtonTextLower string `json:"button_text_lower"` // " - Launch - "
 	IconPath        string `json:"icon_path"`
+	ButtonActions
 }
 
 type CallFunctionProperties struct {
 	ButtonTextUpper string `json:"button_text_upper"` // function name
 	ButtonTextLower string `json:"button_text_lower"` // empty string
 	IconPath        string `json:"icon_path"`
+	ButtonActions
 }
 
 type OpenSpecificPieMenuPage struct {
@@ -59,6 +65,7 @@ type OpenSpecificPieMenuPage struct {
 	IconPath        string `json:"icon_path"`
 	MenuID          int    `json:"menu_id"`
 	PageID          int    `json:"page_id"`
+	ButtonActions
 }
 
 type OpenResourceProperties struct {
@@ -66,6 +73,7 @@ type OpenResourceProperties struct {
 	ButtonTextLower string `json:"button_text_lower"` // empty string
 	IconPath        string `json:"icon_path"`
 	ResourcePath    string `json:"resource_path"`
+	ButtonActions
 }
 
 type KeyboardShortcut struct {
@@ -73,6 +81,72 @@ type KeyboardShortcut struct {
 	ButtonTextLower string `json:"button_text_lower"` // empty string
 	IconPath        string `json:"icon_path"`
 	Keys            string `json:"keys"`
+	ButtonActions
+}
+
+type RunCommandProperties struct {
+	ButtonTextUpper  string            `json:"button_text_upper"` // display name
+	ButtonTextLower  string            `json:"button_text_lower"` // empty string
+	IconPath         string            `json:"icon_path"`
+	Executable       string            `json:"executable"`
+	Args             []string          `json:"args"`
+	WorkingDirectory string            `json:"working_directory"`
+	Env              map[string]string `json:"env"`        // overrides on top of the worker's environment
+	TimeoutMs        int               `json:"timeout_ms"` // 0 means no timeout; only applies with wait_for_exit
+	WaitForExit      bool              `json:"wait_for_exit"`
+	CaptureOutput    bool              `json:"capture_output"` // implies wait_for_exit
+	ButtonActions
+}
+
+type MacroProperties struct {
+	ButtonTextUpper string      `json:"button_text_upper"` // display name
+	ButtonTextLower string      `json:"button_text_lower"` // empty string
+	IconPath        string      `json:"icon_path"`
+	Steps           []MacroStep `json:"steps"`
+	ButtonActions
+}
+
+type TypeTextProperties struct {
+	ButtonTextUpper string `json:"button_text_upper"` // display name
+	ButtonTextLower string `json:"button_text_lower"` // empty string
+	IconPath        string `json:"icon_path"`
+	Text            string `json:"text"`     // may contain template variables like {{date}}
+	Delivery        string `json:"delivery"` // TypeTextDeliveryType or TypeTextDeliveryPaste
+	ButtonActions
+}
+
+const (
+	// TypeTextDeliveryType simulates typing the text.
+	TypeTextDeliveryType = "type"
+	// TypeTextDeliveryPaste pastes the text through the clipboard and restores the clipboard afterwards.
+	TypeTextDeliveryPaste = "paste"
+)
+
+// MacroStep is one action of a macro: a button type from MacroStepTypes with its properties,
+// or a delay.
+type MacroStep struct {
+	Type       string          `json:"type"`
+	Properties json.RawMessage `json:"properties,omitempty"`
+	DelayMs    int             `json:"delay_ms,omitempty"` // only for delay steps
+	OnError    string          `json:"on_error,omitempty"` // "stop" (default) or "continue"
+}
+
+const (
+	MacroStepDelay = "delay"
+
+	MacroOnErrorStop     = "stop"
+	MacroOnErrorContinue = "continue"
+)
+
+// MacroStepTypes are the button types a macro step can run.
+var MacroStepTypes = []ButtonType{
+	ButtonTypeCallFunction,
+	ButtonTypeKeyboardShortcut,
+	ButtonTypeOpenResource,
+	ButtonTypeLaunchProgram,
+	ButtonTypeOpenPageInMenu,
+	ButtonTypeRunCommand,
+	ButtonTypeTypeText,
 }
 
 // ShortcutPressed_Message is a NATS message published when a shortcut is pressed or released.
@@ -88,6 +162,9 @@ const (
 	ButtonTypeOpenPageInMenu    ButtonType = "open_page_in_menu"
 	ButtonTypeOpenResource      ButtonType = "open_resource"
 	ButtonTypeKeyboardShortcut  ButtonType = "keyboard_shortcut"
+	ButtonTypeRunCommand        ButtonType = "run_command"
+	ButtonTypeMacro             ButtonType = "macro"
+	ButtonTypeTypeText          ButtonType = "type_text"
 	ButtonTypeDisabled          ButtonType = "disabled"
 )
 
@@ -97,4 +174,24 @@ type ShortcutPressed_Message struct {
 	MouseY           int  `json:"mouseY"`
 	OpenSpecificPage bool `json:"openSpecificPage"`
 	PageID           int  `json:"pageID"`
+	// Trigger is the trigger mode that fired. Tap and double-tap shortcuts are released right
+	// after they are pressed, so their release message does not mean a key went up.
+	Trigger string `json:"trigger,omitempty"`
+}
+
+// ShortcutSequencePending_Message is published while a leader-key sequence is partially entered
+// so the UI can show a hint, and once more with Pending false when it completed or was abandoned.
+type ShortcutSequencePending_Message struct {
+	Pending    bool                        `json:"pending"`
+	Completed  bool                        `json:"completed"`
+	Step       int                         `json:"step"`
+	TimeoutMs  int                         `json:"timeoutMs"`
+	Candidates []ShortcutSequenceCandidate `json:"candidates"`
+}
+
+// ShortcutSequenceCandidate is a sequence that can still complete, with the chord it expects next.
+type ShortcutSequenceCandidate struct {
+	ShortcutIndex int    `json:"shortcutIndex"`
+	Label         string `json:"label"`
+	NextChord     []int  `json:"nextChord"`
 }
@@ -10,6 +10,9 @@ const borderClassMap: Record<ButtonType | 'default', string> = {
     [ButtonType.OpenSpecificPieMenuPage]: 'border-[var(--color-accent-openpage)]',
     [ButtonType.OpenResource]: 'border-[var(--color-accent-resource)]',
     [ButtonType.KeyboardShortcut]: 'border-[var(--color-accent-shortcut)]',
+    [ButtonType.RunCommand]: 'border-[var(--color-accent-launch)]',
+    [ButtonType.Macro]: 'border-[var(--color-accent-shortcut)]',
+    [ButtonType.TypeText]: 'border-[var(--color-accent-shortcut)]',
     [ButtonType.Disabled]: 'border-neutral-400 dark:border-gray-700',
     default: 'border-neutral-400 dark:border-grey-600',
 };
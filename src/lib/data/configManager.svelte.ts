@@ -9,12 +9,15 @@ import {
     type CallFunctionProperties,
     type KeyboardShortcutProperties,
     type LaunchProgramProperties,
+    type MacroProperties,
     type MenuConfigData,
     type OpenResourceProperties,
     type OpenSpecificPieMenuPageProperties,
     type PagesInMenuMap,
+    type RunCommandProperties,
     type ShowAnyWindowProperties,
-    type ShowProgramWindowProperties
+    type ShowProgramWindowProperties,
+    type TypeTextProperties
 } from "$lib/data/types/pieButtonTypes.ts";
 import {publishMessage} from "$lib/natsAdapter.svelte.ts";
 import {getDefaultButton} from "$lib/data/types/pieButtonDefaults.ts";
@@ -270,12 +273,39 @@ function convertToButton(
             }
             return {button_type, properties: properties as KeyboardShortcutProperties};
 
+        case ButtonType.RunCommand:
+            if (!properties) {
+                logger.warn(createLogMessage("Properties missing"));
+                return getDefaultButton(ButtonType.Disabled);
+            }
+            return {button_type, properties: properties as RunCommandProperties};
+
+        case ButtonType.Macro:
+            if (!properties) {
+                logger.warn(createLogMessage("Properties missing"));
+                return getDefaultButton(ButtonType.Disabled);
+            }
+            return {button_type, properties: properties as MacroProperties};
+
+        case ButtonType.TypeText:
+            if (!properties) {
+                logger.warn(createLogMessage("Properties missing"));
+                return getDefaultButton(ButtonType.Disabled);
+            }
+            return {button_type, properties: properties as TypeTextProperties};
+
         case ButtonType.Disabled:
             return getDefaultButton(ButtonType.Disabled);
 
         default:
-            logger.warn(createLogMessage("Unknown or missing button type"));
-            return getDefaultButton(ButtonType.Disabled);
+            if (!button_type) {
+                logger.warn(createLogMessage("Missing button type"));
+                return getDefaultButton(ButtonType.Disabled);
+            }
+            // A type this frontend doesn't know yet (the backend validates it) is kept as is,
+            // so saving the config doesn't wipe the button
+            logger.warn(`Unknown button type '${button_type}' for button ${buttonId} on page ${pageId}, menu ${menuId}. Keeping it unchanged.`);
+            return {button_type, properties: properties ?? {}} as unknown as Button;
     }
 }
 
@@ -7,10 +7,13 @@ import {
     type DisabledProperties,
     type KeyboardShortcutProperties,
     type LaunchProgramProperties,
+    type MacroProperties,
     type OpenResourceProperties,
     type OpenSpecificPieMenuPageProperties,
+    type RunCommandProperties,
     type ShowAnyWindowProperties,
-    type ShowProgramWindowProperties
+    type ShowProgramWindowProperties,
+    type TypeTextProperties
 } from "$lib/data/types/pieButtonTypes.ts";
 
 const BUTTON_PROPERTIES_MAP = {
@@ -85,6 +88,40 @@ const BUTTON_PROPERTIES_MAP = {
             icon_path: "tabler_icons\\keyboard.svg",
             keys: "ctrl+c",
         } as KeyboardShortcutProperties,
+    },
+    [ButtonType.RunCommand]: {
+        button_type: ButtonType.RunCommand,
+        properties: {
+            button_text_upper: "Give your button a name ...",
+            button_text_lower: "",
+            icon_path: "",
+            executable: "",
+            args: [],
+            working_directory: "",
+            env: {},
+            timeout_ms: 30000,
+            wait_for_exit: false,
+            capture_output: false,
+        } as RunCommandProperties,
+    },
+    [ButtonType.Macro]: {
+        button_type: ButtonType.Macro,
+        properties: {
+            button_text_upper: "Give your button a name ...",
+            button_text_lower: "",
+            icon_path: "",
+            steps: [],
+        } as MacroProperties,
+    },
+    [ButtonType.TypeText]: {
+        button_type: ButtonType.TypeText,
+        properties: {
+            button_text_upper: "Give your button a name ...",
+            button_text_lower: "",
+            icon_path: "",
+            text: "",
+            delivery: "paste",
+        } as TypeTextProperties,
     }
 } as const;
 
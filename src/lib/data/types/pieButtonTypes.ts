@@ -17,6 +17,9 @@ export enum ButtonType {
     OpenResource = 'open_resource',
     Disabled = 'disabled',
     KeyboardShortcut = 'keyboard_shortcut',
+    RunCommand = 'run_command',
+    Macro = 'macro',
+    TypeText = 'type_text',
 }
 
 // Button Interfaces
@@ -77,6 +80,41 @@ export interface KeyboardShortcutProperties {
     keys: string; // keyboard shortcut string like "ctrl+c", "alt+tab", etc.
 }
 
+export interface RunCommandProperties {
+    button_text_upper: string; // display name
+    button_text_lower: string; // empty string
+    icon_path: string;
+    executable: string;
+    args: string[];
+    working_directory: string;
+    env: Record<string, string>; // overrides on top of the backend's environment
+    timeout_ms: number; // 0 means no timeout; only applies with wait_for_exit
+    wait_for_exit: boolean;
+    capture_output: boolean; // implies wait_for_exit
+}
+
+export interface MacroStep {
+    type: string; // a button type that can run in a macro, or 'delay'
+    properties?: Record<string, any>;
+    delay_ms?: number; // only for delay steps
+    on_error?: 'stop' | 'continue'; // 'stop' if omitted
+}
+
+export interface MacroProperties {
+    button_text_upper: string; // display name
+    button_text_lower: string; // empty string
+    icon_path: string;
+    steps: MacroStep[];
+}
+
+export interface TypeTextProperties {
+    button_text_upper: string; // display name
+    button_text_lower: string; // empty string
+    icon_path: string;
+    text: string; // may contain template variables like {{date}} or {{clipboard}}
+    delivery: 'type' | 'paste'; // 'paste' goes through the clipboard and restores it afterwards
+}
+
 export type Button =
     | { button_type: ButtonType.ShowProgramWindow; properties: ShowProgramWindowProperties }
     | { button_type: ButtonType.ShowAnyWindow; properties: ShowAnyWindowProperties }
@@ -85,7 +123,10 @@ export type Button =
     | { button_type: ButtonType.Disabled; properties: DisabledProperties }
     | { button_type: ButtonType.OpenSpecificPieMenuPage; properties: OpenSpecificPieMenuPageProperties }
     | { button_type: ButtonType.OpenResource; properties: OpenResourceProperties }
-    | { button_type: ButtonType.KeyboardShortcut; properties: KeyboardShortcutProperties };
+    | { button_type: ButtonType.KeyboardShortcut; properties: KeyboardShortcutProperties }
+    | { button_type: ButtonType.RunCommand; properties: RunCommandProperties }
+    | { button_type: ButtonType.Macro; properties: MacroProperties }
+    | { button_type: ButtonType.TypeText; properties: TypeTextProperties };
 
 export type ButtonPropertiesUnion =
     | ShowProgramWindowProperties
@@ -95,7 +136,10 @@ export type ButtonPropertiesUnion =
     | OpenSpecificPieMenuPageProperties
     | DisabledProperties
     | OpenResourceProperties
-    | KeyboardShortcutProperties;
+    | KeyboardShortcutProperties
+    | RunCommandProperties
+    | MacroProperties
+    | TypeTextProperties;
 
 // Represents the raw JSON structure: { "menuID": { "pageID": { "buttonID": ButtonData, ... }, ... }, ... }
 export type MenuConfigData = Record<string, Record<string, Record<string, ButtonData>>>;
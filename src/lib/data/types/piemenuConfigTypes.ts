@@ -15,9 +15,17 @@ export interface StarredFavorite {
     pageID: number;
 }
 
+export interface AppPageRule {
+    app: string; // focused app name, or a pattern depending on match
+    match?: 'exact' | 'glob' | 'regex'; // 'exact' if omitted; all modes ignore case
+    pageID: number;
+}
+
 export interface PieMenuConfig {
+    schemaVersion?: number; // stamped by the backend config manager; files from newer versions are refused
     buttons: MenuConfigData; // existing nested record structure
     shortcuts: ShortcutsMap; // keys stored as strings in file
     starred: StarredFavorite | null; // null if unset
     menuAliases?: Record<string, string>; // optional custom names for menus (menuID as string key)
+    appPageRules?: Record<string, AppPageRule[]>; // per menu (menuID as string key), first match picks the page
 }